@@ -3,23 +3,28 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/misc"
 )
 
-func createUserInDb(signupInfo UserInfo) (int64, bool) {
+type dbUserStore struct {
+	db *database.Conn
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+func NewDbStore(db *database.Conn) UserStore {
+	return &dbUserStore{db: db}
+}
 
-	// hashedPasswd, err := bcrypt.GenerateFromPassword([]byte(signupInfo.Password), costOfPwHash)
-	hashedPasswd, err := signupInfo.generatePasswordHash()
-	if err != nil {
-		return userIdNotFound, false
-	}
+func (s *dbUserStore) CreateUser(ctx context.Context, user UserInfo, passwordHash []byte) (int64, error) {
 
-	results, err := database.DbConn.ExecContext(ctx, `INSERT INTO user(
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	userId, err := s.db.InsertReturningId(ctx, `INSERT INTO user(
 															firstname,
 															lastname,
 															username,
@@ -28,80 +33,253 @@ func createUserInDb(signupInfo UserInfo) (int64, bool) {
 														VALUES (
 															?,?,?,?
 														)`,
-		signupInfo.Firstname,
-		signupInfo.Lastname,
-		signupInfo.Username,
-		hashedPasswd)
-	if err != nil {
-		return userIdNotFound, false
+		user.Firstname,
+		user.Lastname,
+		user.Username,
+		sql.NullString{String: string(passwordHash), Valid: len(passwordHash) > 0})
+	if misc.HasErrorCode(err, misc.ErrorCodeConflict) {
+		return userIdNotFound, misc.NewConflictError(fmt.Sprintf("Duplicate username %s", user.Username))
+	} else if err != nil {
+		return userIdNotFound, err
 	}
 
-	userId, _ := results.LastInsertId()
-	return userId, true
+	return userId, nil
 
 }
 
-func validateUserInDb(creds credentials) (int64, bool, bool) {
+func (s *dbUserStore) GetUserByUsername(ctx context.Context, username string) (*UserInfo, string, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	row := database.DbConn.QueryRowContext(ctx, `SELECT 
-														id,
-														password,
-														admin
-													FROM user
-													WHERE username = ?`,
-		creds.Username)
-
-	var userId int64
-	var passwdHash passwordHash
-	var isAdmin bool
-	err := row.Scan(&userId, &passwdHash, &isAdmin)
+	row := s.db.QueryRowContext(ctx, `SELECT 
+											id,
+											firstname,
+											lastname,
+											admin,
+											username,
+											password,
+											createdAt,
+											updatedAt
+										FROM user
+										WHERE username = ?`,
+		username)
+
+	var user UserInfo
+	var passwdHash sql.NullString
+	err := row.Scan(&user.ID,
+		&user.Firstname,
+		&user.Lastname,
+		&user.Admin,
+		&user.Username,
+		&passwdHash,
+		database.ScanTimestamp(&user.CreatedAt),
+		database.ScanTimestamp(&user.UpdatedAt))
 	if err == sql.ErrNoRows {
-		return userIdNotFound, false, false
+		return nil, "", nil
 	} else if err != nil {
-		return userIdNotFound, false, false
+		return nil, "", err
 	}
 
-	if err = passwdHash.validateCredentials(creds); err != nil {
-		return userIdNotFound, false, false
+	return &user, passwdHash.String, nil
+}
+
+func (s *dbUserStore) GetUser(ctx context.Context, userId int64) (*UserInfo, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT 
+											id,
+											firstname,
+											lastname,
+											admin,
+											username,
+											createdAt,
+											updatedAt
+										FROM user
+										WHERE id = ?`,
+		userId)
+
+	var user UserInfo
+	err := row.Scan(&user.ID,
+		&user.Firstname,
+		&user.Lastname,
+		&user.Admin,
+		&user.Username,
+		database.ScanTimestamp(&user.CreatedAt),
+		database.ScanTimestamp(&user.UpdatedAt))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
 
-	return userId, isAdmin, true
+	return &user, nil
 }
 
-func getUsersFromDb() ([]UserInfo, error) {
+func (s *dbUserStore) GetUsers(ctx context.Context) ([]UserInfo, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	rows, err := database.DbConn.QueryContext(ctx, `SELECT id, 
-														firstname, 
-														lastname, 
-														admin, 
-														username,
-														createdAt,
-														UpdatedAt 
-													FROM user`)
-	defer rows.Close()
+	rows, err := s.db.QueryContext(ctx, `SELECT id, 
+												firstname, 
+												lastname, 
+												admin, 
+												username,
+												createdAt,
+												UpdatedAt 
+											FROM user
+											ORDER BY id`)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	userInfoList := make([]UserInfo, 0)
 	for rows.Next() {
 
 		var user UserInfo
-		rows.Scan(&user.ID,
+		if err := rows.Scan(&user.ID,
 			&user.Firstname,
 			&user.Lastname,
 			&user.Admin,
 			&user.Username,
-			&user.CreatedAt,
-			&user.UpdatedAt)
+			database.ScanTimestamp(&user.CreatedAt),
+			database.ScanTimestamp(&user.UpdatedAt)); err != nil {
+			return nil, err
+		}
 		userInfoList = append(userInfoList, user)
 	}
 
-	return userInfoList, nil
+	return userInfoList, rows.Err()
+}
+
+type dbFailedLoginStore struct {
+	db *database.Conn
+}
+
+func NewDbFailedLoginStore(db *database.Conn) FailedLoginStore {
+	return &dbFailedLoginStore{db: db}
+}
+
+func (s *dbFailedLoginStore) Create(ctx context.Context, username string, ip string, attemptedAt time.Time) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	return s.db.InsertReturningId(ctx, `INSERT INTO failedLogin(
+														username,
+														ip,
+														attemptedAt
+													)
+													VALUES (
+														?,?,?
+													)`,
+		username,
+		ip,
+		attemptedAt.Unix())
+}
+
+func (s *dbFailedLoginStore) Delete(ctx context.Context, id int64) error {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM failedLogin WHERE id = ?`, id)
+	return err
+}
+
+func (s *dbFailedLoginStore) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	results, err := s.db.ExecContext(ctx, `DELETE FROM failedLogin WHERE attemptedAt < ?`, before.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return results.RowsAffected()
+}
+
+func (s *dbFailedLoginStore) List(ctx context.Context, filter FailedLoginFilter) ([]FailedLogin, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if filter.Username != "" {
+		conditions = append(conditions, "username = ?")
+		args = append(args, filter.Username)
+	}
+	if filter.Ip != "" {
+		conditions = append(conditions, "ip = ?")
+		args = append(args, filter.Ip)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "attemptedAt >= ?")
+		args = append(args, filter.Since.Unix())
+	}
+	if filter.Uncleared {
+		conditions = append(conditions, "cleared = ?")
+		args = append(args, false)
+	}
+
+	query := `SELECT id, username, ip, attemptedAt, cleared FROM failedLogin`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY attemptedAt DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failedLogins := make([]FailedLogin, 0)
+	for rows.Next() {
+
+		var failedLogin FailedLogin
+		var attemptedAt int64
+		if err := rows.Scan(&failedLogin.ID,
+			&failedLogin.Username,
+			&failedLogin.Ip,
+			&attemptedAt,
+			&failedLogin.Cleared); err != nil {
+			return nil, err
+		}
+		failedLogin.AttemptedAt = time.Unix(attemptedAt, 0).UTC().Format(misc.TimestampFormat)
+		failedLogins = append(failedLogins, failedLogin)
+	}
+
+	return failedLogins, rows.Err()
+}
+
+func (s *dbFailedLoginStore) Clear(ctx context.Context, username string) (*misc.Status, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	results, err := s.db.ExecContext(ctx, `UPDATE failedLogin
+														SET cleared = ?
+														WHERE username = ? AND cleared = ?`,
+		true,
+		username,
+		false)
+	status := &misc.Status{}
+	if err != nil {
+		status.SetStatus(0, 0)
+		return status, err
+	}
+
+	numRowsCleared, _ := results.RowsAffected()
+	status.SetStatus(numRowsCleared, 1)
+	return status, nil
 }
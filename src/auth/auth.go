@@ -8,19 +8,26 @@ import (
 	"strings"
 	"time"
 
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/tracing"
+
 	"github.com/dgrijalva/jwt-go"
 	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Create the JWT key used to create the signature
-const jwtKey = "12345-67890-09876-54321"
+// jwtKey signs the JWTs, it is set with jwtExpiration and costOfPwHash
+// from the configuration by SetupRoutes
+var jwtKey []byte
+var jwtExpiration time.Duration
+var costOfPwHash int
 
 const msgLoginFailed = "Login failed!"
 const msgLoginSuccessful = "You are successfully logged in!"
 
 const userIdNotFound int64 = -1
-const costOfPwHash = 8
 
 type UserInfo struct {
 	ID        int64  `json:"_id"`
@@ -32,19 +39,48 @@ type UserInfo struct {
 	credentials
 }
 
+// signupInfo is the body of a signup, which has no say on the admin flag,
+// the id or the dates of the user
+type signupInfo struct {
+	Firstname string `json:"firstname" validate:"max=50"`
+	Lastname  string `json:"lastname" validate:"max=50"`
+	credentials
+}
+
+// FailedLogin is a login attempt with a wrong username or password.
+// Cleared is set once the user logs in or an admin unlocks the username.
+type FailedLogin struct {
+	ID          int64  `json:"_id"`
+	Username    string `json:"username"`
+	Ip          string `json:"ip"`
+	AttemptedAt string `json:"attemptedAt"`
+	Cleared     bool   `json:"cleared"`
+}
+
+// FailedLoginFilter selects the failed logins listed, the zero value selects all
+type FailedLoginFilter struct {
+	Username string
+	Ip       string
+	Since    time.Time
+	// Uncleared leaves out the failed logins that have been cleared
+	Uncleared bool
+	// Limit caps the number of failed logins, 0 lists all of them
+	Limit int
+}
+
 type checkJwtStatus struct {
 	Status    string `json:"status"`
 	IsSuccess bool   `json:"success"`
 	ErrorMsg  string `json:"err"`
 }
 
-func (ui UserInfo) generatePasswordHash() ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(ui.Password), costOfPwHash)
+func (si signupInfo) generatePasswordHash() ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(si.Password), costOfPwHash)
 }
 
 type credentials struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,max=15"`
+	Password string `json:"password" validate:"required"`
 }
 
 type passwordHash string
@@ -72,7 +108,7 @@ type claims struct {
 
 func (claims *claims) generateTokenStringForUser() (string, error) {
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := time.Now().Add(jwtExpiration)
 	// Create the JWT claims, which includes the username and expiry time
 	claims.StandardClaims = jwt.StandardClaims{
 		// In JWT, the expiry time is expressed as unix milliseconds
@@ -80,7 +116,7 @@ func (claims *claims) generateTokenStringForUser() (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(jwtKey))
+	tokenString, err := token.SignedString(jwtKey)
 	return tokenString, err
 }
 
@@ -119,7 +155,7 @@ func validateToken(jwtToken string) (claims, bool) {
 	claims := &claims{}
 
 	token, err := jwt.ParseWithClaims(jwtToken, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtKey), nil
+		return jwtKey, nil
 	})
 	if err != nil {
 		return *claims, false
@@ -135,20 +171,26 @@ func validateToken(jwtToken string) (claims, bool) {
 func VerifyUser(next httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
+		ctx, span := tracing.Start(r.Context(), "auth.VerifyUser")
+		defer span.End()
+		r = r.WithContext(ctx)
+
 		token, err := GetJwtTokenFromRequest(r)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			misc.WriteError(w, r, misc.NewUnauthorizedError(err.Error()))
 			return
 		}
 
 		claims, ok := validateToken(token)
+		span.SetAttributes(attribute.Bool("auth.valid", ok))
 		if !ok {
-			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			misc.WriteError(w, r, misc.NewUnauthorizedError("JWT invalid!"))
 			return
 		}
 
 		// store the claims in the request as a context obj
-		r = r.WithContext(context.WithValue(r.Context(), "claims", claims))
+		ctx = context.WithValue(ctx, "claims", claims)
+		r = r.WithContext(logging.WithUserId(ctx, GetClaimsFromRequest(r.WithContext(ctx)).UserId))
 		next(w, r, ps)
 	}
 }
@@ -156,15 +198,25 @@ func VerifyUser(next httprouter.Handle) httprouter.Handle {
 func VerifyAdmin(next httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
+		ctx, span := tracing.Start(r.Context(), "auth.VerifyAdmin")
+		defer span.End()
+		r = r.WithContext(ctx)
+
 		claims := GetClaimsFromRequest(r)
 		if !claims.Admin {
-			http.Error(w, "You are not authorized to perform this operation!", http.StatusUnauthorized)
+			misc.WriteError(w, r, misc.NewForbiddenError("You are not authorized to perform this operation!"))
 			return
 		}
 		next(w, r, ps)
 	}
 }
 
+// GetUserId returns the id of the user verified by VerifyUser, empty for
+// anonymous requests
+func GetUserId(r *http.Request) string {
+	return GetClaimsFromRequest(r).UserId
+}
+
 func GetClaimsFromRequest(r *http.Request) claims {
 
 	claims, _ := r.Context().Value("claims").(claims)
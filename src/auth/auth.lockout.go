@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/misc"
+)
+
+// lockout keeps the failed logins in store and locks the usernames and IP
+// addresses which fail too often, by the settings of the configuration
+type lockout struct {
+	store       FailedLoginStore
+	threshold   int
+	ipThreshold int
+	window      time.Duration
+	duration    time.Duration
+
+	// the last time the expired failed logins were deleted
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+}
+
+// a failed login answers after failedLoginDelay, doubled for every
+// earlier failure of the username within lockout_window
+const failedLoginDelay = 250 * time.Millisecond
+const maxFailedLoginDelay = 4 * time.Second
+
+// maximum length of failedLogin.username
+const maxFailedLoginUsernameLength = 50
+
+// the expired failed logins are deleted at most once per pruneInterval
+const pruneInterval = time.Minute
+
+func newLockout(config config.Config, store FailedLoginStore) *lockout {
+
+	return &lockout{
+		store:       store,
+		threshold:   config.LockoutThreshold,
+		ipThreshold: config.LockoutIpThreshold,
+		window:      config.LockoutWindow,
+		duration:    config.LockoutDuration,
+	}
+}
+
+// getLockedUntil returns until when the failed logins lock, the zero time if
+// they don't. They lock when the last threshold of them happened within
+// lockout_window, for lockout_duration after the most recent one.
+func (l *lockout) getLockedUntil(failedLogins []FailedLogin, threshold int) time.Time {
+
+	if threshold == 0 || len(failedLogins) < threshold {
+		return time.Time{}
+	}
+
+	latest := getAttemptedAt(failedLogins[0])
+	if latest.Sub(getAttemptedAt(failedLogins[threshold-1])) > l.window {
+		return time.Time{}
+	}
+	return latest.Add(l.duration)
+}
+
+func getAttemptedAt(failedLogin FailedLogin) time.Time {
+
+	attemptedAt, _ := time.ParseInLocation(misc.TimestampFormat, failedLogin.AttemptedAt, time.UTC)
+	return attemptedAt
+}
+
+// listRecentFailedLogins returns the failed logins that can still lock, the
+// most recent first, leaving out the one of the login attempt
+func (l *lockout) listRecentFailedLogins(ctx context.Context, filter FailedLoginFilter, threshold int, now time.Time,
+	attemptId int64) ([]FailedLogin, error) {
+
+	if threshold == 0 {
+		return nil, nil
+	}
+
+	filter.Since = now.Add(-l.window - l.duration)
+	filter.Limit = threshold + 1
+	failedLogins, err := l.store.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, failedLogin := range failedLogins {
+		if failedLogin.ID == attemptId {
+			return append(failedLogins[:i], failedLogins[i+1:]...), nil
+		}
+	}
+	return failedLogins[:min(len(failedLogins), threshold)], nil
+}
+
+// recordLoginAttempt stores the login attempt as a failed login before its
+// password is checked, and returns its id. The attempts running at the same
+// time thus count each other, and no more than lockout_threshold of them get
+// their password checked. forgetLoginAttempt removes the attempts which don't fail.
+func (l *lockout) recordLoginAttempt(ctx context.Context, username string, ip string, now time.Time) (int64, error) {
+
+	l.pruneFailedLogins(ctx, now)
+	return l.store.Create(ctx, getFailedLoginUsername(username), ip, now)
+}
+
+// pruneFailedLogins deletes the failed logins which can no longer lock,
+// older than lockout_window and lockout_duration together, so the failed
+// logins kept don't grow with every login attempt. It runs at most once per
+// pruneInterval, and a failure only leaves them for the next time.
+func (l *lockout) pruneFailedLogins(ctx context.Context, now time.Time) {
+
+	l.pruneMu.Lock()
+	if now.Sub(l.lastPrune) < pruneInterval {
+		l.pruneMu.Unlock()
+		return
+	}
+	l.lastPrune = now
+	l.pruneMu.Unlock()
+
+	numDeleted, err := l.store.DeleteBefore(ctx, now.Add(-l.window-l.duration))
+	if err != nil {
+		logging.FromContext(ctx).Warn("Error deleting the expired failed logins", "error", err)
+		return
+	}
+	if numDeleted > 0 {
+		logging.FromContext(ctx).Debug("Deleted the expired failed logins", "count", numDeleted)
+	}
+}
+
+func (l *lockout) forgetLoginAttempt(ctx context.Context, attemptId int64) error {
+	return l.store.Delete(ctx, attemptId)
+}
+
+// getLockout returns how long the logins of username from ip stay locked,
+// 0 if they are not, and how many times username failed within lockout_window,
+// counting the failed logins recorded before, or at the same time as, the
+// login attempt
+func (l *lockout) getLockout(ctx context.Context, username string, ip string, now time.Time, attemptId int64) (time.Duration, int, error) {
+
+	userFailedLogins, err := l.listRecentFailedLogins(ctx,
+		FailedLoginFilter{Username: getFailedLoginUsername(username), Uncleared: true}, l.threshold, now, attemptId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// a successful login doesn't clear the failures of its IP address, one
+	// known password must not allow guessing the others
+	ipFailedLogins, err := l.listRecentFailedLogins(ctx, FailedLoginFilter{Ip: ip}, l.ipThreshold, now, attemptId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lockedUntil := l.getLockedUntil(userFailedLogins, l.threshold)
+	if ipLockedUntil := l.getLockedUntil(ipFailedLogins, l.ipThreshold); ipLockedUntil.After(lockedUntil) {
+		lockedUntil = ipLockedUntil
+	}
+
+	recentFailures := 0
+	for _, failedLogin := range userFailedLogins {
+		if now.Sub(getAttemptedAt(failedLogin)) <= l.window {
+			recentFailures++
+		}
+	}
+
+	if lockedUntil.After(now) {
+		return lockedUntil.Sub(now), recentFailures, nil
+	}
+	return 0, recentFailures, nil
+}
+
+func getFailedLoginUsername(username string) string {
+
+	if len(username) > maxFailedLoginUsernameLength {
+		return username[:maxFailedLoginUsernameLength]
+	}
+	return username
+}
+
+// delayFailedLogin holds the response to a failed login back, longer for
+// every earlier failure of the username. The failed login is already
+// recorded by recordLoginAttempt.
+func (l *lockout) delayFailedLogin(ctx context.Context, username string, ip string, earlierFailures int) {
+
+	if l.threshold > 0 && earlierFailures+1 == l.threshold {
+		logging.FromContext(ctx).Warn("Username locked after failed logins",
+			"username", username, "ip", ip, "failures", l.threshold, "lockedFor", l.duration.String())
+	}
+
+	delay := failedLoginDelay
+	for i := 0; i < earlierFailures && delay < maxFailedLoginDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxFailedLoginDelay {
+		delay = maxFailedLoginDelay
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func (l *lockout) clearFailedLogins(ctx context.Context, username string) error {
+
+	_, err := l.store.Clear(ctx, getFailedLoginUsername(username))
+	return err
+}
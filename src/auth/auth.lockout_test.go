@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+type testFailedLogin struct {
+	username, ip string
+	age          time.Duration
+}
+
+// repeatFailedLogin returns n failed logins of username from ip, step apart
+// from age on
+func repeatFailedLogin(n int, username, ip string, age, step time.Duration) []testFailedLogin {
+
+	failedLogins := make([]testFailedLogin, n)
+	for i := range failedLogins {
+		failedLogins[i] = testFailedLogin{username, ip, age + time.Duration(i)*step}
+	}
+	return failedLogins
+}
+
+// createFailedLogins stores the failed logins the oldest first, as they happen
+func createFailedLogins(t *testing.T, store FailedLoginStore, failedLogins []testFailedLogin, now time.Time) {
+
+	t.Helper()
+
+	failedLogins = slices.Clone(failedLogins)
+	slices.SortStableFunc(failedLogins, func(a, b testFailedLogin) int { return cmp.Compare(b.age, a.age) })
+	for _, failedLogin := range failedLogins {
+		if _, err := store.Create(context.Background(), failedLogin.username, failedLogin.ip, now.Add(-failedLogin.age)); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+}
+
+func TestGetLockout(t *testing.T) {
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	// lockout_threshold 5 and lockout_ip_threshold 20 within a 15m
+	// lockout_window lock for a 15m lockout_duration
+	for _, test := range []struct {
+		name            string
+		failedLogins    []testFailedLogin
+		cleared         bool
+		wantLockedFor   time.Duration
+		wantRecentFails int
+	}{
+		{"none", nil, false, 0, 0},
+		{"below threshold", repeatFailedLogin(4, "jane", "10.0.0.1", time.Minute, time.Minute), false, 0, 4},
+		{"threshold", repeatFailedLogin(5, "jane", "10.0.0.1", time.Minute, time.Minute), false, 14 * time.Minute, 5},
+		{"threshold from other ips", repeatFailedLogin(5, "jane", "10.0.0.2", time.Minute, time.Minute), false, 14 * time.Minute, 5},
+		{"other username", repeatFailedLogin(5, "john", "10.0.0.2", time.Minute, time.Minute), false, 0, 0},
+		{"cleared", repeatFailedLogin(5, "jane", "10.0.0.1", time.Minute, time.Minute), true, 0, 0},
+		{"longer than the window", []testFailedLogin{
+			{"jane", "10.0.0.1", time.Minute}, {"jane", "10.0.0.1", 6 * time.Minute},
+			{"jane", "10.0.0.1", 11 * time.Minute}, {"jane", "10.0.0.1", 16 * time.Minute},
+			{"jane", "10.0.0.1", 21 * time.Minute},
+		}, false, 0, 3},
+		{"lockout over", repeatFailedLogin(5, "jane", "10.0.0.1", 20*time.Minute, time.Minute), false, 0, 0},
+		{"lockout ending", repeatFailedLogin(5, "jane", "10.0.0.1", 14*time.Minute, time.Minute), false, time.Minute, 2},
+		{"ip threshold", repeatFailedLogin(20, "john", "10.0.0.1", time.Second, 10*time.Second), true, 15*time.Minute - time.Second, 0},
+		{"below ip threshold", repeatFailedLogin(19, "john", "10.0.0.1", time.Second, 10*time.Second), true, 0, 0},
+	} {
+
+		ctx := context.Background()
+		store := NewMemoryFailedLoginStore()
+		l := newLockout(testConfig, store)
+		createFailedLogins(t, store, test.failedLogins, now)
+		if test.cleared {
+			if err := l.clearFailedLogins(ctx, "jane"); err != nil {
+				t.Fatalf("%s: clearFailedLogins: %v", test.name, err)
+			}
+			if err := l.clearFailedLogins(ctx, "john"); err != nil {
+				t.Fatalf("%s: clearFailedLogins: %v", test.name, err)
+			}
+		}
+
+		// the login attempt itself doesn't count
+		attemptId, err := l.recordLoginAttempt(ctx, "jane", "10.0.0.1", now)
+		if err != nil {
+			t.Fatalf("%s: recordLoginAttempt: %v", test.name, err)
+		}
+		lockedFor, recentFailures, err := l.getLockout(ctx, "jane", "10.0.0.1", now, attemptId)
+		if err != nil {
+			t.Fatalf("%s: getLockout: %v", test.name, err)
+		}
+		if lockedFor != test.wantLockedFor || recentFailures != test.wantRecentFails {
+			t.Errorf("%s: got locked for %v after %d failures, want %v after %d", test.name,
+				lockedFor, recentFailures, test.wantLockedFor, test.wantRecentFails)
+		}
+	}
+}
+
+func TestGetLockoutDisabled(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	config := testConfig
+	config.LockoutThreshold = 0
+	config.LockoutIpThreshold = 0
+	store := NewMemoryFailedLoginStore()
+	l := newLockout(config, store)
+
+	createFailedLogins(t, store, repeatFailedLogin(30, "jane", "10.0.0.1", time.Second, 10*time.Second), now)
+
+	if lockedFor, _, err := l.getLockout(ctx, "jane", "10.0.0.1", now, 0); err != nil || lockedFor != 0 {
+		t.Errorf("getLockout: got %v, %v, want 0", lockedFor, err)
+	}
+}
+
+func TestPruneFailedLogins(t *testing.T) {
+
+	ctx := context.Background()
+	store := NewMemoryFailedLoginStore()
+	l := newLockout(testConfig, store)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	// lockout_window and lockout_duration are 15m each
+	createFailedLogins(t, store, []testFailedLogin{
+		{"jane", "10.0.0.1", 31 * time.Minute}, {"jane", "10.0.0.1", 29 * time.Minute}, {"jane", "10.0.0.1", time.Minute},
+	}, now)
+
+	if _, err := l.recordLoginAttempt(ctx, "jane", "10.0.0.1", now); err != nil {
+		t.Fatalf("recordLoginAttempt: %v", err)
+	}
+	if failedLogins, _ := store.List(ctx, FailedLoginFilter{}); len(failedLogins) != 3 {
+		t.Errorf("after the first attempt: got %d failed logins, want 3", len(failedLogins))
+	}
+
+	// the next prune is a pruneInterval later
+	if _, err := l.recordLoginAttempt(ctx, "jane", "10.0.0.1", now.Add(30*time.Second)); err != nil {
+		t.Fatalf("recordLoginAttempt: %v", err)
+	}
+	if failedLogins, _ := store.List(ctx, FailedLoginFilter{}); len(failedLogins) != 4 {
+		t.Errorf("within pruneInterval: got %d failed logins, want 4", len(failedLogins))
+	}
+
+	if _, err := l.recordLoginAttempt(ctx, "jane", "10.0.0.1", now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("recordLoginAttempt: %v", err)
+	}
+	if failedLogins, _ := store.List(ctx, FailedLoginFilter{}); len(failedLogins) != 4 {
+		t.Errorf("after pruneInterval: got %d failed logins, want 4", len(failedLogins))
+	}
+}
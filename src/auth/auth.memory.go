@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"confusion.com/bwoo/misc"
+)
+
+type memoryUser struct {
+	info         UserInfo
+	passwordHash string
+}
+
+type memoryUserStore struct {
+	mu     sync.RWMutex
+	nextId int64
+	users  map[int64]memoryUser
+}
+
+func NewMemoryStore() UserStore {
+	return &memoryUserStore{nextId: 1, users: make(map[int64]memoryUser)}
+}
+
+func (s *memoryUserStore) CreateUser(ctx context.Context, user UserInfo, passwordHash []byte) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.info.Username == user.Username {
+			return userIdNotFound, misc.NewConflictError(fmt.Sprintf("Duplicate username %s", user.Username))
+		}
+	}
+
+	now := time.Now().UTC().Format(misc.TimestampFormat)
+	newUser := memoryUser{
+		info: UserInfo{
+			ID:        s.nextId,
+			Firstname: user.Firstname,
+			Lastname:  user.Lastname,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		passwordHash: string(passwordHash),
+	}
+	newUser.info.Username = user.Username
+
+	s.users[newUser.info.ID] = newUser
+	s.nextId++
+	return newUser.info.ID, nil
+}
+
+// CreateMemoryAdmin creates an admin in a memory store, which CreateUser like
+// signing up never does; tests and demos seed their admins with it
+func CreateMemoryAdmin(ctx context.Context, store UserStore, user UserInfo, passwordHash []byte) (int64, error) {
+
+	s, ok := store.(*memoryUserStore)
+	if !ok {
+		return userIdNotFound, fmt.Errorf("%T is not a memory store", store)
+	}
+
+	userId, err := s.CreateUser(ctx, user, passwordHash)
+	if err != nil {
+		return userIdNotFound, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin := s.users[userId]
+	admin.info.Admin = true
+	s.users[userId] = admin
+	return userId, nil
+}
+
+func (s *memoryUserStore) GetUserByUsername(ctx context.Context, username string) (*UserInfo, string, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.info.Username == username {
+			info := user.info
+			return &info, user.passwordHash, nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+func (s *memoryUserStore) GetUser(ctx context.Context, userId int64) (*UserInfo, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userId]
+	if !ok {
+		return nil, nil
+	}
+
+	info := user.info
+	return &info, nil
+}
+
+func (s *memoryUserStore) GetUsers(ctx context.Context) ([]UserInfo, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	userInfoList := make([]UserInfo, 0, len(s.users))
+	for _, user := range s.users {
+		userInfoList = append(userInfoList, user.info)
+	}
+
+	sort.Slice(userInfoList, func(i, j int) bool { return userInfoList[i].ID < userInfoList[j].ID })
+	return userInfoList, nil
+}
+
+type memoryFailedLoginStore struct {
+	mu           sync.RWMutex
+	nextId       int64
+	failedLogins []FailedLogin
+}
+
+func NewMemoryFailedLoginStore() FailedLoginStore {
+	return &memoryFailedLoginStore{nextId: 1}
+}
+
+func (s *memoryFailedLoginStore) Create(ctx context.Context, username string, ip string, attemptedAt time.Time) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextId
+	s.failedLogins = append(s.failedLogins, FailedLogin{
+		ID:          id,
+		Username:    username,
+		Ip:          ip,
+		AttemptedAt: attemptedAt.UTC().Format(misc.TimestampFormat),
+	})
+	s.nextId++
+	return id, nil
+}
+
+func (s *memoryFailedLoginStore) Delete(ctx context.Context, id int64) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.failedLogins {
+		if s.failedLogins[i].ID == id {
+			s.failedLogins = append(s.failedLogins[:i], s.failedLogins[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryFailedLoginStore) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	beforeStr := before.UTC().Format(misc.TimestampFormat)
+	kept := make([]FailedLogin, 0, len(s.failedLogins))
+	for _, failedLogin := range s.failedLogins {
+		if failedLogin.AttemptedAt >= beforeStr {
+			kept = append(kept, failedLogin)
+		}
+	}
+
+	numDeleted := int64(len(s.failedLogins) - len(kept))
+	s.failedLogins = kept
+	return numDeleted, nil
+}
+
+func (s *memoryFailedLoginStore) List(ctx context.Context, filter FailedLoginFilter) ([]FailedLogin, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	since := filter.Since.UTC().Format(misc.TimestampFormat)
+	failedLogins := make([]FailedLogin, 0)
+	for i := len(s.failedLogins) - 1; i >= 0; i-- {
+
+		failedLogin := s.failedLogins[i]
+		if (filter.Username != "" && failedLogin.Username != filter.Username) ||
+			(filter.Ip != "" && failedLogin.Ip != filter.Ip) ||
+			(!filter.Since.IsZero() && failedLogin.AttemptedAt < since) ||
+			(filter.Uncleared && failedLogin.Cleared) {
+			continue
+		}
+
+		failedLogins = append(failedLogins, failedLogin)
+		if filter.Limit > 0 && len(failedLogins) == filter.Limit {
+			break
+		}
+	}
+
+	return failedLogins, nil
+}
+
+func (s *memoryFailedLoginStore) Clear(ctx context.Context, username string) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var numCleared int64
+	for i := range s.failedLogins {
+		if s.failedLogins[i].Username == username && !s.failedLogins[i].Cleared {
+			s.failedLogins[i].Cleared = true
+			numCleared++
+		}
+	}
+
+	status := &misc.Status{}
+	status.SetStatus(numCleared, 1)
+	return status, nil
+}
@@ -1,126 +1,208 @@
 package auth
 
 import (
-	"encoding/json"
-	"io"
+	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"confusion.com/bwoo/config"
 	"confusion.com/bwoo/cors"
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/metrics"
 	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/ratelimit"
+	"confusion.com/bwoo/validation"
 
 	"github.com/julienschmidt/httprouter"
 )
 
-func SetupRoutes(router *httprouter.Router) {
+// handlers serve the users of store and lock out the failed logins
+type handlers struct {
+	store   UserStore
+	lockout *lockout
+}
+
+func SetupRoutes(router *misc.Router, config config.Config, store UserStore, failedLogins FailedLoginStore,
+	limiters *ratelimit.Limiters) {
+
+	// a wrong validate tag fails here rather than in the handlers
+	validation.MustRegister(signupInfo{})
+
+	h := &handlers{store: store, lockout: newLockout(config, failedLogins)}
+	jwtKey = []byte(config.JwtKey)
+	jwtExpiration = config.JwtExpiration
+	costOfPwHash = config.PasswordHashCost
 
 	// auth methods
-	router.POST("/users/login", cors.Cors(login))
-	router.POST("/users/signup", cors.Cors(signup))
-	router.GET("/users", cors.Cors(VerifyUser(VerifyAdmin(getUsers))))
+	router.POST("/users/login", cors.Cors(limiters.RateLimit("login", nil, h.login)))
+	router.POST("/users/signup", cors.Cors(limiters.RateLimit("signup", nil, h.signup)))
+	router.GET("/users", cors.Cors(VerifyUser(VerifyAdmin(h.getUsers))))
 	router.GET("/users/checkJWTtoken", cors.Cors(checkJwtToken))
-}
-
-func writeCheckJwtErrorStatus(w http.ResponseWriter, statusMsg, errMsg string) {
 
-	w.Header().Set("Content-Type", "application/json")
-	jwtStatus := checkJwtStatus{IsSuccess: false, Status: statusMsg, ErrorMsg: errMsg}
-	statusJson, _ := misc.GetJsonFromJsonObjs(jwtStatus)
-	w.Write(statusJson)
-	w.WriteHeader(http.StatusUnauthorized)
+	// lockout methods
+	router.GET("/failedLogins", cors.Cors(VerifyUser(VerifyAdmin(h.getFailedLogins))))
+	router.DELETE("/lockouts/:username", cors.Cors(VerifyUser(VerifyAdmin(h.deleteLockout))))
 }
 
 func checkJwtToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	jwtStr, err := GetJwtTokenFromRequest(r)
 	if err != nil {
-		writeCheckJwtErrorStatus(w, "JWT invalid!", "JWT invalid!")
+		misc.WriteError(w, r, misc.NewUnauthorizedError("JWT invalid!"))
 		return
 	}
 
 	if _, ok := validateToken(jwtStr); !ok {
-		writeCheckJwtErrorStatus(w, "JWT invalid!", "JWT invalid!")
+		misc.WriteError(w, r, misc.NewUnauthorizedError("JWT invalid!"))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	jwtStatus := checkJwtStatus{IsSuccess: true, Status: "JWT valid!", ErrorMsg: "JWT valid!"}
 	statusJson, _ := misc.GetJsonFromJsonObjs(jwtStatus)
-	w.Write(statusJson)
 	w.WriteHeader(http.StatusOK)
+	w.Write(statusJson)
 }
 
-func getCredentialsFromBody(body io.ReadCloser) (credentials, error) {
+// getCredentialsFromBody decodes the credentials of a login, which are not
+// validated: wrong credentials are a failed login
+func getCredentialsFromBody(w http.ResponseWriter, r *http.Request) (credentials, error) {
 
 	var creds credentials
-	err := json.NewDecoder(body).Decode(&creds)
-	if err != nil {
+	if err := validation.DecodeJson(w, r, &creds); err != nil {
 		return credentials{}, err
 	}
 
 	return creds, nil
 }
 
-func getUserInfoInfoFromBody(body io.ReadCloser) (UserInfo, error) {
+// getSignupInfoFromBody decodes the body of a signup, refusing any field
+// besides the names, the username and the password
+func getSignupInfoFromBody(w http.ResponseWriter, r *http.Request) (signupInfo, error) {
+
+	var info signupInfo
+	if err := validation.DecodeAndValidate(w, r, &info, false); err != nil {
+		return signupInfo{}, err
+	}
+
+	return info, nil
+}
+
+func (h *handlers) createUser(ctx context.Context, info signupInfo) (int64, error) {
 
-	var signupInfo UserInfo
-	err := json.NewDecoder(body).Decode(&signupInfo)
+	hashedPasswd, err := info.generatePasswordHash()
 	if err != nil {
-		return UserInfo{}, err
+		// bcrypt refuses passwords longer than 72 bytes
+		return userIdNotFound, misc.NewValidationError(misc.FieldError{Field: "password", Message: err.Error()})
+	}
+
+	user := UserInfo{Firstname: info.Firstname, Lastname: info.Lastname}
+	user.Username = info.Username
+	return h.store.CreateUser(ctx, user, hashedPasswd)
+}
+
+func (h *handlers) validateUser(ctx context.Context, creds credentials) (int64, bool, bool) {
+
+	user, passwdHash, err := h.store.GetUserByUsername(ctx, creds.Username)
+	if err != nil || user == nil {
+		return userIdNotFound, false, false
+	}
+
+	if err = passwordHash(passwdHash).validateCredentials(creds); err != nil {
+		return userIdNotFound, false, false
 	}
 
-	return signupInfo, nil
+	return user.ID, user.Admin, true
 }
 
-func signup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) signup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	signupInfo, err := getUserInfoInfoFromBody(r.Body)
+	info, err := getSignupInfoFromBody(w, r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	_, ok := createUserInDb(signupInfo)
-	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
+	_, err = h.createUser(r.Context(), info)
+	if err != nil {
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	signupResult := signupResult{Status: "Registration Successful!", User: signupInfo.Username}
+	signupResult := signupResult{Status: "Registration Successful!", User: info.Username}
 	resultJson, _ := misc.GetJsonFromJsonObjs(signupResult)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(resultJson)
 }
 
-func login(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) login(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	creds, err := getCredentialsFromBody(r.Body)
+	creds, err := getCredentialsFromBody(w, r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	userId, isAdmin, isUserAuth := validateUserInDb(creds)
-	loginResult := GetLoginResult(userId, isAdmin, isUserAuth)
-	resultJson, _ := misc.GetJsonFromJsonObjs(loginResult)
+	// locked logins are refused before the password is checked, so guessing
+	// goes on failing even with the right password
+	ip := misc.GetClientIp(r)
+	now := time.Now()
+	attemptId, err := h.lockout.recordLoginAttempt(r.Context(), creds.Username, ip, now)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+	lockedFor, earlierFailures, err := h.lockout.getLockout(r.Context(), creds.Username, ip, now, attemptId)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+	if lockedFor > 0 {
+		// the locked logins don't make the lockout last longer
+		if err := h.lockout.forgetLoginAttempt(r.Context(), attemptId); err != nil {
+			misc.WriteError(w, r, err)
+			return
+		}
+		metrics.Logins.WithLabelValues("locked").Inc()
+		retryAfter := strconv.Itoa(int(math.Ceil(lockedFor.Seconds())))
+		w.Header().Set("Retry-After", retryAfter)
+		misc.WriteError(w, r, misc.NewTooManyRequestsError("Too many failed logins, retry in "+retryAfter+"s"))
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
+	userId, isAdmin, isUserAuth := h.validateUser(r.Context(), creds)
 	if !isUserAuth {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write(resultJson)
+		metrics.Logins.WithLabelValues("failure").Inc()
+		h.lockout.delayFailedLogin(r.Context(), creds.Username, ip, earlierFailures)
+		misc.WriteError(w, r, misc.NewUnauthorizedError(msgLoginFailed))
+		return
+	}
+
+	if err := h.lockout.forgetLoginAttempt(r.Context(), attemptId); err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+	if err := h.lockout.clearFailedLogins(r.Context(), creds.Username); err != nil {
+		misc.WriteError(w, r, err)
 		return
 	}
 
 	// return jwt token
+	metrics.Logins.WithLabelValues("success").Inc()
+	resultJson, _ := misc.GetJsonFromJsonObjs(GetLoginResult(userId, isAdmin, true))
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(resultJson)
 }
 
-func getUsers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) getUsers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	userInfo, err := getUsersFromDb()
+	userInfo, err := h.store.GetUsers(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
@@ -129,3 +211,50 @@ func getUsers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.WriteHeader(http.StatusOK)
 	w.Write(resultJson)
 }
+
+// maximum number of failed logins listed at once
+const maxFailedLoginsLimit = 1000
+
+func (h *handlers) getFailedLogins(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	query := r.URL.Query()
+	filter := FailedLoginFilter{Username: query.Get("username"), Ip: query.Get("ip"), Limit: 100}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxFailedLoginsLimit {
+			misc.WriteError(w, r, misc.NewBadRequestError(
+				fmt.Sprintf("Invalid limit %s, expected 1 to %d", limitStr, maxFailedLoginsLimit)))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	failedLogins, err := h.lockout.store.List(r.Context(), filter)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	resultJson, _ := misc.GetJsonFromJsonObjs(failedLogins)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultJson)
+}
+
+// deleteLockout unlocks a username by clearing its failed logins. The failed
+// logins of IP addresses are not cleared, they expire after lockout_duration.
+func (h *handlers) deleteLockout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	status, err := h.lockout.store.Clear(r.Context(), ps.ByName("username"))
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Username unlocked", "username", ps.ByName("username"),
+		"cleared", status.NumOfRowsAffected)
+
+	statusJson, _ := misc.GetJsonFromJsonObjs(status)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(statusJson)
+}
@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testConfig = config.Config{
+	JwtKey:             "test",
+	JwtExpiration:      time.Hour,
+	PasswordHashCost:   bcrypt.MinCost,
+	LockoutThreshold:   5,
+	LockoutIpThreshold: 20,
+	LockoutWindow:      15 * time.Minute,
+	LockoutDuration:    15 * time.Minute,
+}
+
+// newTestRouter serves the users of a memory store holding an admin, whose
+// password is "secret"
+func newTestRouter(t *testing.T) *misc.Router {
+
+	t.Helper()
+
+	store := NewMemoryStore()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	admin := UserInfo{Firstname: "Ada", Lastname: "Admin"}
+	admin.Username = "admin"
+	if _, err := CreateMemoryAdmin(context.Background(), store, admin, hash); err != nil {
+		t.Fatalf("CreateMemoryAdmin: %v", err)
+	}
+
+	router := misc.NewRouter()
+	SetupRoutes(router, testConfig, store, NewMemoryFailedLoginStore(), nil)
+	return router
+}
+
+func serve(router *misc.Router, method, path, body, token string) *httptest.ResponseRecorder {
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func login(t *testing.T, router *misc.Router, username, password string) string {
+
+	t.Helper()
+
+	w := serve(router, http.MethodPost, "/users/login", `{"username":"`+username+`","password":"`+password+`"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /users/login as %s: got status %d, want %d", username, w.Code, http.StatusOK)
+	}
+
+	var result loginResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("POST /users/login: %v", err)
+	}
+	return result.Token
+}
+
+func TestSignupLoginAndGetUsers(t *testing.T) {
+
+	router := newTestRouter(t)
+
+	w := serve(router, http.MethodPost, "/users/signup",
+		`{"username":"jane","password":"pass","firstname":"Jane","lastname":"Doe"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /users/signup: got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	janeToken := login(t, router, "jane", "pass")
+	if w := serve(router, http.MethodGet, "/users", "", janeToken); w.Code != http.StatusForbidden {
+		t.Errorf("GET /users as jane: got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = serve(router, http.MethodGet, "/users", "", login(t, router, "admin", "secret"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /users as admin: got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var users []UserInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil {
+		t.Fatalf("GET /users: %v", err)
+	}
+	if len(users) != 2 || users[0].Firstname != "Ada" || users[1].Firstname != "Jane" {
+		t.Errorf("GET /users: got %+v, want Ada and Jane", users)
+	}
+}
+
+func TestRoutersKeepTheirOwnStores(t *testing.T) {
+
+	body := `{"username":"jane","password":"pass","firstname":"Jane","lastname":"Doe"}`
+
+	// the same username signs up once on each router
+	for _, router := range []*misc.Router{newTestRouter(t), newTestRouter(t)} {
+		if w := serve(router, http.MethodPost, "/users/signup", body, ""); w.Code != http.StatusOK {
+			t.Errorf("POST /users/signup: got status %d, want %d", w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestAuthErrors(t *testing.T) {
+
+	router := newTestRouter(t)
+
+	for _, test := range []struct {
+		method, path, body, token string
+	}{
+		{http.MethodPost, "/users/login", `{"username":"admin","password":"wrong"}`, ""},
+		{http.MethodPost, "/users/login", `{"username":"nobody","password":"secret"}`, ""},
+		{http.MethodGet, "/users/checkJWTtoken", "", ""},
+		{http.MethodGet, "/users/checkJWTtoken", "", "forged"},
+		{http.MethodGet, "/users", "", "forged"},
+	} {
+
+		w := serve(router, test.method, test.path, test.body, test.token)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: got status %d, want %d", test.method, test.path, w.Code, http.StatusUnauthorized)
+			continue
+		}
+
+		var response struct{ Error misc.Error }
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s: %v", test.method, test.path, err)
+		}
+		if response.Error.Code != misc.ErrorCodeUnauthorized || response.Error.Message == "" {
+			t.Errorf("%s %s: got %+v, want an unauthorized error", test.method, test.path, response.Error)
+		}
+	}
+
+	if w := serve(router, http.MethodGet, "/users/checkJWTtoken", "", login(t, router, "admin", "secret")); w.Code != http.StatusOK {
+		t.Errorf("GET /users/checkJWTtoken: got status %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// the signup only takes the names, the username and the password, so nobody
+// signs up as an admin
+func TestSignupRefusesOtherFields(t *testing.T) {
+
+	router := newTestRouter(t)
+
+	for _, field := range []string{`"admin":true`, `"_id":7`, `"createdAt":"2020-01-01 00:00:00"`, `"updatedAt":"2020-01-01 00:00:00"`} {
+
+		body := `{"username":"jane","password":"pass","firstname":"Jane","lastname":"Doe",` + field + `}`
+		w := serve(router, http.MethodPost, "/users/signup", body, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /users/signup with %s: got status %d, want %d", field, w.Code, http.StatusBadRequest)
+			continue
+		}
+
+		var response struct{ Error misc.Error }
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("POST /users/signup with %s: %v", field, err)
+		}
+		if response.Error.Code != misc.ErrorCodeValidation {
+			t.Errorf("POST /users/signup with %s: got %+v, want a validation error", field, response.Error)
+		}
+	}
+
+	// jane was never created
+	if w := serve(router, http.MethodPost, "/users/login", `{"username":"jane","password":"pass"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("POST /users/login as jane: got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestCreateUserIsNeverAdmin(t *testing.T) {
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	user := UserInfo{Firstname: "Jane", Admin: true}
+	user.Username = "jane"
+	userId, err := store.CreateUser(ctx, user, []byte("hash"))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if got, _ := store.GetUser(ctx, userId); got == nil || got.Admin {
+		t.Errorf("CreateUser: got %+v, want a user who is not an admin", got)
+	}
+
+	user.Username = "ada"
+	adminId, err := CreateMemoryAdmin(ctx, store, user, []byte("hash"))
+	if err != nil {
+		t.Fatalf("CreateMemoryAdmin: %v", err)
+	}
+	if got, _ := store.GetUser(ctx, adminId); got == nil || !got.Admin {
+		t.Errorf("CreateMemoryAdmin: got %+v, want an admin", got)
+	}
+}
+
+// the spans of the middlewares nest in the server span of the request, in the trace of the client
+func TestVerifyUserSpans(t *testing.T) {
+
+	router := newTestRouter(t)
+	token := login(t, router, "admin", "secret")
+
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(previous)
+	if _, err := tracing.Setup(config.Config{}); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+
+	const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	tracing.Trace(router).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /users: got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID().String() != traceId {
+			t.Errorf("%s: got trace %s, want %s", span.Name, span.SpanContext.TraceID(), traceId)
+		}
+		spans[span.Name] = span
+	}
+
+	for _, test := range []struct {
+		name, parent string
+	}{
+		{"cors.Cors", "GET /users"},
+		{"auth.VerifyUser", "cors.Cors"},
+		{"auth.VerifyAdmin", "auth.VerifyUser"},
+	} {
+
+		span, ok := spans[test.name]
+		if !ok {
+			t.Errorf("%s: got no span, want one", test.name)
+			continue
+		}
+		if span.Parent.SpanID() != spans[test.parent].SpanContext.SpanID() {
+			t.Errorf("%s: got parent %s, want %s", test.name, span.Parent.SpanID(), test.parent)
+		}
+	}
+	if valid := spans["auth.VerifyUser"].Attributes; !slices.Contains(valid, attribute.Bool("auth.valid", true)) {
+		t.Errorf("auth.VerifyUser: got attributes %v, want auth.valid true", valid)
+	}
+}
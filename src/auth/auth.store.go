@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"confusion.com/bwoo/misc"
+)
+
+// UserStore is the persistence layer used by the auth handlers. Neither
+// store creates admins, see CreateMemoryAdmin for the tests and demos.
+type UserStore interface {
+	// CreateUser stores user with an already hashed password and returns the new user id.
+	// A nil passwordHash creates a user who cannot log in with a password.
+	CreateUser(ctx context.Context, user UserInfo, passwordHash []byte) (int64, error)
+	// GetUserByUsername returns the user and its password hash, or nil if not found
+	GetUserByUsername(ctx context.Context, username string) (*UserInfo, string, error)
+	GetUser(ctx context.Context, userId int64) (*UserInfo, error)
+	GetUsers(ctx context.Context) ([]UserInfo, error)
+}
+
+// FailedLoginStore keeps the failed logins the lockout is decided on
+type FailedLoginStore interface {
+	// Create stores a failed login and returns its id
+	Create(ctx context.Context, username string, ip string, attemptedAt time.Time) (int64, error)
+	// Delete removes a failed login, e.g. a login attempt which turned out not to fail
+	Delete(ctx context.Context, id int64) error
+	// DeleteBefore removes the failed logins attempted before a time and returns how many
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	// List returns the failed logins selected by filter, the most recent first
+	List(ctx context.Context, filter FailedLoginFilter) ([]FailedLogin, error)
+	// Clear marks the failed logins of username as cleared
+	Clear(ctx context.Context, username string) (*misc.Status, error)
+}
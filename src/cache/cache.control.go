@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// controlWriter sets the Cache-Control header of a response as its status is written
+type controlWriter struct {
+	http.ResponseWriter
+	policy  string
+	written bool
+}
+
+func (w *controlWriter) WriteHeader(status int) {
+
+	if !w.written {
+		w.written = true
+		if status < http.StatusBadRequest {
+			w.Header().Set("Cache-Control", w.policy)
+		} else {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *controlWriter) Write(b []byte) (int, error) {
+
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Control tells the browsers and proxies how to cache the responses of next
+// with the Cache-Control policy, e.g. "public, max-age=60". The errors are
+// not to be stored. An empty policy leaves the responses as they are.
+func Control(policy string, next httprouter.Handle) httprouter.Handle {
+
+	if policy == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		next(&controlWriter{ResponseWriter: w, policy: policy}, r, ps)
+	}
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type fakeValue struct {
+	value string
+	// expiresAt is zero for the values which never expire
+	expiresAt time.Time
+}
+
+// fakeRedis is an in-process server speaking enough of the Redis protocol for
+// the redis backend: PING, AUTH, GET, SET with EX or PX, INCR, DEL and
+// FLUSHALL. It stands in for Redis in the tests.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu     sync.Mutex
+	values map[string]fakeValue
+}
+
+// startFakeRedis starts a fakeRedis on a free port of 127.0.0.1, asking the
+// clients for password unless it is empty
+func startFakeRedis(password string) (*fakeRedis, error) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	f := &fakeRedis{listener: listener, password: password, values: make(map[string]fakeValue)}
+	go f.serve()
+	return f, nil
+}
+
+func (f *fakeRedis) Addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) Close() error {
+	return f.listener.Close()
+}
+
+func (f *fakeRedis) serve() {
+
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.serveConn(conn)
+	}
+}
+
+func (f *fakeRedis) serveConn(conn net.Conn) {
+
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	authenticated := f.password == ""
+
+	for {
+		command, err := readReply(reader)
+		if err != nil {
+			return
+		}
+
+		args, err := getArgs(command)
+		if err != nil {
+			writeError(writer, err.Error())
+		} else if strings.ToUpper(args[0]) == "AUTH" {
+			authenticated = len(args) == 2 && args[1] == f.password
+			if authenticated {
+				writer.WriteString("+OK\r\n")
+			} else {
+				writeError(writer, "WRONGPASS invalid password")
+			}
+		} else if !authenticated {
+			writeError(writer, "NOAUTH Authentication required")
+		} else {
+			f.run(writer, args)
+		}
+
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// getArgs returns the arguments of a command, an array of bulk strings
+func getArgs(command interface{}) ([]string, error) {
+
+	values, ok := command.([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, errors.New("ERR expected an array of bulk strings")
+	}
+
+	args := make([]string, len(values))
+	for i, value := range values {
+		arg, ok := value.([]byte)
+		if !ok {
+			return nil, errors.New("ERR expected an array of bulk strings")
+		}
+		args[i] = string(arg)
+	}
+	return args, nil
+}
+
+func writeError(w *bufio.Writer, message string) {
+	w.WriteString("-" + message + "\r\n")
+}
+
+func writeBulkString(w *bufio.Writer, value string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(value), value)
+}
+
+// get returns the value at key unless it has expired, the lock being held
+func (f *fakeRedis) get(key string) (fakeValue, bool) {
+
+	value, ok := f.values[key]
+	if ok && !value.expiresAt.IsZero() && time.Now().After(value.expiresAt) {
+		delete(f.values, key)
+		return fakeValue{}, false
+	}
+	return value, ok
+}
+
+func (f *fakeRedis) run(w *bufio.Writer, args []string) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := strings.ToUpper(args[0])
+	switch {
+	case name == "PING":
+		w.WriteString("+PONG\r\n")
+
+	case name == "GET" && len(args) == 2:
+		if value, ok := f.get(args[1]); ok {
+			writeBulkString(w, value.value)
+		} else {
+			w.WriteString("$-1\r\n")
+		}
+
+	case name == "SET" && (len(args) == 3 || len(args) == 5):
+		value := fakeValue{value: args[2]}
+		if len(args) == 5 {
+			amount, err := strconv.ParseInt(args[4], 10, 64)
+			if err != nil || amount <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			switch strings.ToUpper(args[3]) {
+			case "EX":
+				value.expiresAt = time.Now().Add(time.Duration(amount) * time.Second)
+			case "PX":
+				value.expiresAt = time.Now().Add(time.Duration(amount) * time.Millisecond)
+			default:
+				writeError(w, "ERR syntax error")
+				return
+			}
+		}
+		f.values[args[1]] = value
+		w.WriteString("+OK\r\n")
+
+	case name == "INCR" && len(args) == 2:
+		value, _ := f.get(args[1])
+		counter := int64(0)
+		if value.value != "" {
+			var err error
+			if counter, err = strconv.ParseInt(value.value, 10, 64); err != nil {
+				writeError(w, "ERR value is not an integer or out of range")
+				return
+			}
+		}
+		counter++
+		f.values[args[1]] = fakeValue{value: strconv.FormatInt(counter, 10), expiresAt: value.expiresAt}
+		fmt.Fprintf(w, ":%d\r\n", counter)
+
+	case name == "DEL" && len(args) >= 2:
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := f.get(key); ok {
+				delete(f.values, key)
+				deleted++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", deleted)
+
+	case name == "FLUSHALL":
+		f.values = make(map[string]fakeValue)
+		w.WriteString("+OK\r\n")
+
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command or wrong number of arguments for '%s'", args[0]))
+	}
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"confusion.com/bwoo/config"
+)
+
+// KeyPrefix starts every key, so the cache can share a Redis database
+const KeyPrefix = "confusion:"
+
+// Backend stores the cached values. NewLru keeps them in the memory of the
+// server, NewRedis in a Redis server, shared by the instances of the server.
+type Backend interface {
+	// Get returns the value at key, false when there is none
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value at key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// GetCounter returns the counter at key, 0 until it is incremented
+	GetCounter(ctx context.Context, key string) (int64, error)
+	// Incr adds one to the counter at key and returns it. The counters never expire.
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// Setup returns the backend of cache_backend, nil when it is none
+func Setup(cfg config.Config) (Backend, error) {
+
+	switch cfg.CacheBackend {
+	case config.LruCache:
+		return NewLru(cfg.CacheSize), nil
+
+	case config.RedisCache:
+		return NewRedis(cfg.CacheRedisAddr, cfg.CacheRedisPasswd), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// lru drops the least recently used value when it holds size of them
+type lru struct {
+	mu   sync.Mutex
+	size int
+	// order holds the entries, the most recently used first
+	order   *list.List
+	entries map[string]*list.Element
+	// the counters are apart from the values, as dropping one would bring back
+	// the values cached with its former count
+	counters map[string]int64
+}
+
+func NewLru(size int) Backend {
+	return &lru{size: size, order: list.New(), entries: make(map[string]*list.Element), counters: make(map[string]int64)}
+}
+
+func (c *lru) Get(ctx context.Context, key string) ([]byte, bool, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (c *lru) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+func (c *lru) GetCounter(ctx context.Context, key string) (int64, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.counters[key], nil
+}
+
+func (c *lru) Incr(ctx context.Context, key string) (int64, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters[key]++
+	return c.counters[key], nil
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// the time given to a command when the context has no deadline
+	redisTimeout = time.Second
+	// the connections kept open between the commands
+	maxIdleRedisConns = 8
+)
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// redis is a client of a Redis server, or of anything speaking its protocol
+type redis struct {
+	addr     string
+	password string
+	idle     chan *redisConn
+}
+
+func NewRedis(addr, password string) Backend {
+	return &redis{addr: addr, password: password, idle: make(chan *redisConn, maxIdleRedisConns)}
+}
+
+func (c *redis) dial(ctx context.Context) (*redisConn, error) {
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	if c.password != "" {
+		if _, err := rc.do(ctx, "AUTH", c.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+func (rc *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	rc.conn.SetDeadline(deadline)
+
+	if err := writeCommand(rc.writer, args...); err != nil {
+		return nil, err
+	}
+	reply, err := readReply(rc.reader)
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// do runs a command on an idle connection, or a new one, and returns the reply
+func (c *redis) do(ctx context.Context, args ...string) (interface{}, error) {
+
+	var rc *redisConn
+	select {
+	case rc = <-c.idle:
+	default:
+		var err error
+		if rc, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := rc.do(ctx, args...)
+	// after an error the connection may be half way through a reply
+	if _, isReplyErr := err.(redisError); err != nil && !isReplyErr {
+		rc.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case c.idle <- rc:
+	default:
+		rc.conn.Close()
+	}
+	return reply, err
+}
+
+func (c *redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("Unexpected reply to GET: %v", reply)
+	}
+	return value, true, nil
+}
+
+// Set rounds ttl up to whole milliseconds, Redis refuses PX 0
+func (c *redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+
+	milliseconds := (ttl + time.Millisecond - 1).Milliseconds()
+	_, err := c.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(max(milliseconds, 1), 10))
+	return err
+}
+
+func (c *redis) GetCounter(ctx context.Context, key string) (int64, error) {
+
+	value, ok, err := c.Get(ctx, key)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+func (c *redis) Incr(ctx context.Context, key string) (int64, error) {
+
+	reply, err := c.do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+
+	counter, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("Unexpected reply to INCR: %v", reply)
+	}
+	return counter, nil
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestRedis returns a redis backend of a fakeRedis asking for password
+func newTestRedis(t *testing.T, password string) (Backend, *fakeRedis) {
+
+	t.Helper()
+	fake, err := startFakeRedis(password)
+	if err != nil {
+		t.Fatalf("startFakeRedis: %v", err)
+	}
+	t.Cleanup(func() { fake.Close() })
+	return NewRedis(fake.Addr(), password), fake
+}
+
+func TestRedisGetSet(t *testing.T) {
+
+	ctx := context.Background()
+	backend, _ := newTestRedis(t, "secret")
+
+	if _, ok, err := backend.Get(ctx, "dish:1"); err != nil || ok {
+		t.Fatalf("Get of a missing key: got ok %v, error %v", ok, err)
+	}
+
+	if err := backend.Set(ctx, "dish:1", []byte(`{"name":"Uthappizza"}`), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	value, ok, err := backend.Get(ctx, "dish:1")
+	if err != nil || !ok || string(value) != `{"name":"Uthappizza"}` {
+		t.Fatalf("Get: got %q, ok %v, error %v", value, ok, err)
+	}
+}
+
+func TestRedisExpiry(t *testing.T) {
+
+	ctx := context.Background()
+	backend, _ := newTestRedis(t, "")
+
+	if err := backend.Set(ctx, "dish:1", []byte("value"), 10*time.Millisecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok, err := backend.Get(ctx, "dish:1"); err != nil || ok {
+		t.Fatalf("Get of an expired key: got ok %v, error %v", ok, err)
+	}
+}
+
+func TestRedisSubMillisecondTtl(t *testing.T) {
+
+	ctx := context.Background()
+	backend, _ := newTestRedis(t, "")
+
+	// PX 0 would be refused
+	for _, ttl := range []time.Duration{time.Microsecond, time.Millisecond + time.Microsecond} {
+		if err := backend.Set(ctx, "dish:1", []byte("value"), ttl); err != nil {
+			t.Errorf("Set with a ttl of %s: %v", ttl, err)
+		}
+	}
+}
+
+func TestRedisCounter(t *testing.T) {
+
+	ctx := context.Background()
+	backend, _ := newTestRedis(t, "")
+
+	if counter, err := backend.GetCounter(ctx, "dish:version"); err != nil || counter != 0 {
+		t.Fatalf("GetCounter before Incr: got %d, error %v", counter, err)
+	}
+
+	for want := int64(1); want <= 3; want++ {
+		if counter, err := backend.Incr(ctx, "dish:version"); err != nil || counter != want {
+			t.Fatalf("Incr: got %d, error %v, want %d", counter, err, want)
+		}
+	}
+
+	if counter, err := backend.GetCounter(ctx, "dish:version"); err != nil || counter != 3 {
+		t.Fatalf("GetCounter: got %d, error %v, want 3", counter, err)
+	}
+}
+
+func TestRedisWrongPassword(t *testing.T) {
+
+	_, fake := newTestRedis(t, "secret")
+	backend := NewRedis(fake.Addr(), "wrong")
+
+	if _, _, err := backend.Get(context.Background(), "dish:1"); err == nil {
+		t.Fatalf("Get with a wrong password: got no error")
+	}
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The Redis serialization protocol (RESP2), as much of it as the redis backend
+// and the fake of the tests speak: the commands are arrays of bulk strings, the replies
+// simple strings, errors, integers, bulk strings or null.
+
+// redisError is an error reply
+type redisError string
+
+func (e redisError) Error() string {
+	return "Redis error: " + string(e)
+}
+
+// writeCommand writes the arguments of a command as an array of bulk strings
+func writeCommand(w *bufio.Writer, args ...string) error {
+
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return w.Flush()
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", errors.New("Invalid RESP line")
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// readReply reads a value: a string for a simple string, a redisError, an
+// int64, a []byte for a bulk string, nil for null, or a []interface{} for an array
+func readReply(r *bufio.Reader) (interface{}, error) {
+
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("Empty RESP line")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+
+	case '-':
+		return redisError(line[1:]), nil
+
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < -1 {
+			return nil, fmt.Errorf("Invalid RESP bulk string length %q", line[1:])
+		}
+		if length == -1 {
+			return nil, nil
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:length], nil
+
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < -1 {
+			return nil, fmt.Errorf("Invalid RESP array length %q", line[1:])
+		}
+		if length == -1 {
+			return nil, nil
+		}
+		values := make([]interface{}, length)
+		for i := range values {
+			if values[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("Invalid RESP type %q", line[0])
+}
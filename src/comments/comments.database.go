@@ -4,35 +4,47 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/logging"
 	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/resource"
 )
 
-func createCommentInDb(dishId int64, authorId int64, comment Comment) (*misc.Status, error) {
+type dbCommentStore struct {
+	db *database.Conn
+}
+
+func NewDbStore(db *database.Conn) CommentStore {
+	return &dbCommentStore{db: db}
+}
+
+func (s *dbCommentStore) Create(ctx context.Context, dishId int64, authorId int64, comment Comment) (*misc.Status, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	results, err := database.DbConn.ExecContext(ctx, `INSERT INTO comment (
-															dishId,
-															rating,
-															comment,
-															authorId
-														) 
-														VALUES (
-															?,?,?,?
-														)`,
-		dishId,
-		comment.Rating,
-		comment.Comment,
-		authorId)
+	// the date is left to its default, now, unless the comment has one
+	columns := "dishId, rating, comment, authorId"
+	values := "?,?,?,?"
+	args := []interface{}{dishId, comment.Rating, comment.Comment, authorId}
+	if comment.Date != nil {
+		columns += ", date"
+		values += ",?"
+		args = append(args, *comment.Date)
+	}
+
+	results, err := s.db.ExecContext(ctx, `INSERT INTO comment (`+columns+`) VALUES (`+values+`)`, args...)
 
+	// the author comes from the JWT, so a missing referenced row is the dish
 	status := &misc.Status{}
-	if err != nil {
+	if misc.HasErrorCode(err, misc.ErrorCodeInvalidReference) {
+		status.SetStatus(0, 0)
+		return status, misc.NewNotFoundError(fmt.Sprintf("Dish %d not found", dishId))
+	} else if err != nil {
 		status.SetStatus(0, 0)
 		return status, err
 	}
@@ -42,13 +54,13 @@ func createCommentInDb(dishId int64, authorId int64, comment Comment) (*misc.Sta
 	return status, nil
 }
 
-func deleteCommentsFromDb(dishId int64) (*misc.Status, error) {
+func (s *dbCommentStore) DeleteAll(ctx context.Context, dishId int64) (*misc.Status, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
 	status := &misc.Status{}
-	results, err := database.DbConn.ExecContext(ctx, `DELETE FROM comment WHERE dishId = ?`, dishId)
+	results, err := s.db.ExecContext(ctx, `DELETE FROM comment WHERE dishId = ?`, dishId)
 	if err != nil {
 		status.SetStatus(0, 0)
 		return status, err
@@ -59,22 +71,60 @@ func deleteCommentsFromDb(dishId int64) (*misc.Status, error) {
 	return status, nil
 }
 
-func deleteCommentFromDb(dishId, commentId, updatedByUserId int64) (*misc.Status, error) {
+// change runs apply on the connection, or when there is a precondition, in a
+// transaction which checks it on the current comment, locked until apply is done
+func (s *dbCommentStore) change(ctx context.Context, dishId, commentId int64, precondition resource.Precondition[Comment],
+	apply func(q database.Queryer) error) error {
+
+	if precondition == nil {
+		return apply(s.db)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.get(ctx, tx, dishId, commentId, s.db.Dialect.ForUpdate())
+	if err == nil {
+		err = precondition(current)
+	}
+	if err == nil {
+		err = apply(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *dbCommentStore) Delete(ctx context.Context, dishId, commentId, updatedByUserId int64,
+	precondition resource.Precondition[Comment]) (*misc.Status, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	results, err := database.DbConn.ExecContext(ctx, `DELETE FROM comment
-														WHERE dishid = ? AND id = ? and authorId = ?`,
-		dishId, commentId, updatedByUserId)
 	commentStatus := &misc.Status{}
+	err := s.change(ctx, dishId, commentId, precondition, func(q database.Queryer) error {
+
+		results, err := q.ExecContext(ctx, `DELETE FROM comment
+														WHERE dishid = ? AND id = ? and authorId = ?`,
+			dishId, commentId, updatedByUserId)
+		if err != nil {
+			return err
+		}
+
+		numRowsDeleted, _ := results.RowsAffected()
+		commentStatus.SetStatus(numRowsDeleted, 1)
+		return nil
+	})
 	if err != nil {
 		commentStatus.SetStatus(0, 0)
 		return commentStatus, err
 	}
 
-	numRowsDeleted, err := results.RowsAffected()
-	commentStatus.SetStatus(numRowsDeleted, 1)
 	return commentStatus, nil
 }
 
@@ -113,33 +163,53 @@ func buildUpdateSQLFromInput(dishId int64, commentId int64, comment Comment, upd
 	return sb.String(), args
 }
 
-func updateCommentFromDb(dishId int64, commentId int64, comment Comment, updatedByUserId int64) (*Comment, error) {
+func (s *dbCommentStore) Update(ctx context.Context, dishId int64, commentId int64, comment Comment, updatedByUserId int64,
+	precondition resource.Precondition[Comment]) (*Comment, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
 	updateSql, updateArgs := buildUpdateSQLFromInput(dishId, commentId, comment, updatedByUserId)
-	results, err := database.DbConn.ExecContext(ctx, updateSql, updateArgs...)
-	if err != nil {
-		log.Println("Error updating record ", dishId)
-		return nil, err
+	if updateSql == "" {
+		return nil, misc.NewBadRequestError("Nothing to update")
 	}
 
-	numRowsUpdated, _ := results.RowsAffected()
-	if numRowsUpdated == 0 {
-		return &Comment{}, fmt.Errorf("No rows updated")
+	var commentUpdated *Comment
+	err := s.change(ctx, dishId, commentId, precondition, func(q database.Queryer) error {
+
+		results, err := q.ExecContext(ctx, updateSql, updateArgs...)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error updating record", "dishId", dishId, "commentId", commentId, "error", err)
+			return err
+		}
+
+		numRowsUpdated, _ := results.RowsAffected()
+		if numRowsUpdated == 0 {
+			return misc.NewNotFoundError(fmt.Sprintf("Comment %d not found", commentId))
+		}
+
+		commentUpdated, err = s.get(ctx, q, dishId, commentId, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	commentUpdated, err := getCommentFromDb(dishId, commentId)
-	return commentUpdated, err
+	return commentUpdated, nil
 }
 
-func getCommentFromDb(dishId, commentId int64) (*Comment, error) {
+func (s *dbCommentStore) Get(ctx context.Context, dishId, commentId int64) (*Comment, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	row := database.DbConn.QueryRowContext(ctx, `SELECT
+	return s.get(ctx, s.db, dishId, commentId, "")
+}
+
+// get reads the comment, with the lock of forUpdate
+func (s *dbCommentStore) get(ctx context.Context, q database.Queryer, dishId, commentId int64, forUpdate string) (*Comment, error) {
+
+	row := q.QueryRowContext(ctx, `SELECT
 													c.Id,
 													c.rating,
 													c.comment,
@@ -150,7 +220,7 @@ func getCommentFromDb(dishId, commentId int64) (*Comment, error) {
 												FROM comment c, user u
 												WHERE c.authorId = u.id
 												AND c.dishId = ? 
-												AND c.id = ?`,
+												AND c.id = ?`+forUpdate,
 		dishId, commentId)
 
 	var comment Comment
@@ -162,7 +232,7 @@ func getCommentFromDb(dishId, commentId int64) (*Comment, error) {
 		&comment.Author.Firstname,
 		&comment.Author.Lastname,
 		&comment.Author.ID,
-		&comment.Date)
+		database.ScanNullTimestamp(&comment.Date))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
@@ -172,25 +242,42 @@ func getCommentFromDb(dishId, commentId int64) (*Comment, error) {
 	return &comment, nil
 }
 
-func getCommentsFromDb(dishId int64) ([]Comment, error) {
+func (s *dbCommentStore) List(ctx context.Context, dishId int64, params listing.Params) ([]Comment, listing.PageInfo, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	rows, err := database.DbConn.QueryContext(ctx, `SELECT 
+	from := ` FROM comment c, user u
+				WHERE c.authorId = u.id
+				AND c.dishId = ?`
+	conditions, args := params.Where()
+	for _, condition := range conditions {
+		from += ` AND ` + condition
+	}
+	args = append([]interface{}{dishId}, args...)
+
+	var info listing.PageInfo
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*)`+from, args...).Scan(&info.Total); err != nil {
+		return nil, info, err
+	}
+
+	// the count is of all the pages, the cursor only selects the rows of this one
+	if seek, seekArgs := params.Seek(); seek != "" {
+		from += ` AND ` + seek
+		args = append(args, seekArgs...)
+	}
+	page, pageArgs := params.Page()
+	rows, err := s.db.QueryContext(ctx, `SELECT 
 														c.id,
 														c.rating,
 														c.comment,
 														u.firstname,
 														u.lastname,
-														c.date
-													FROM comment c, user u
-													WHERE c.authorId = u.id
-													AND c.dishId = ?`, dishId)
-	defer rows.Close()
+														c.date`+from+params.OrderBy("c.id")+page, append(args, pageArgs...)...)
 	if err != nil {
-		return nil, err
+		return nil, info, err
 	}
+	defer rows.Close()
 
 	comments := make([]Comment, 0)
 	for rows.Next() {
@@ -198,15 +285,21 @@ func getCommentsFromDb(dishId int64) ([]Comment, error) {
 		var comment Comment
 		var author Author
 		comment.Author = &author
-		rows.Scan(&comment.ID,
+		if err := rows.Scan(&comment.ID,
 			&comment.Rating,
 			&comment.Comment,
 			&comment.Author.Firstname,
 			&comment.Author.Lastname,
-			&comment.Date)
+			database.ScanNullTimestamp(&comment.Date)); err != nil {
+			return nil, info, err
+		}
 
 		comments = append(comments, comment)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, info, err
+	}
 
-	return comments, nil
+	comments, info.More = listing.Trim(comments, params)
+	return comments, info, nil
 }
@@ -1,5 +1,7 @@
 package comments
 
+import "confusion.com/bwoo/listing"
+
 type Author struct {
 	ID        int64  `json:"_id"`
 	Firstname string `json:"firstname"`
@@ -8,8 +10,33 @@ type Author struct {
 
 type Comment struct {
 	ID      int64   `json:"_id"`
-	Rating  *int    `json:"rating"`
+	Rating  *int    `json:"rating" validate:"required,min=1,max=5"`
 	Comment *string `json:"comment"`
 	Author  *Author `json:"author"`
 	Date    *string `json:"date"`
 }
+
+// listFields are the fields the comments of a dish are filtered and sorted on
+var listFields = []listing.Field{
+	{Name: "_id", Column: "c.id", Type: listing.Number},
+	{Name: "rating", Column: "c.rating", Type: listing.Number},
+	{Name: "date", Column: "c.date", Type: listing.Timestamp},
+}
+
+// getFieldValue returns the value of one of the listFields of comment as the listing compares it
+func getFieldValue(comment *Comment, field listing.Field) interface{} {
+
+	switch field.Name {
+	case "_id":
+		return float64(comment.ID)
+	case "rating":
+		if comment.Rating != nil {
+			return float64(*comment.Rating)
+		}
+	case "date":
+		if comment.Date != nil {
+			return *comment.Date
+		}
+	}
+	return nil
+}
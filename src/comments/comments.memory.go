@@ -0,0 +1,221 @@
+package comments
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/resource"
+)
+
+type memoryComment struct {
+	id       int64
+	dishId   int64
+	authorId int64
+	rating   int
+	comment  *string
+	date     string
+}
+
+// memoryCommentStore resolves comment authors through the given auth.UserStore,
+// the same way the SQL store joins the user table
+type memoryCommentStore struct {
+	mu       sync.RWMutex
+	nextId   int64
+	comments map[int64]memoryComment
+	users    auth.UserStore
+}
+
+func NewMemoryStore(users auth.UserStore) CommentStore {
+	return &memoryCommentStore{nextId: 1, comments: make(map[int64]memoryComment), users: users}
+}
+
+func (s *memoryCommentStore) toComment(ctx context.Context, stored memoryComment) (*Comment, error) {
+
+	user, err := s.users.GetUser(ctx, stored.authorId)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	rating := stored.rating
+	date := stored.date
+	return &Comment{
+		ID:      stored.id,
+		Rating:  &rating,
+		Comment: misc.CopyString(stored.comment),
+		Author:  &Author{ID: user.ID, Firstname: user.Firstname, Lastname: user.Lastname},
+		Date:    &date,
+	}, nil
+}
+
+func (s *memoryCommentStore) Create(ctx context.Context, dishId int64, authorId int64, comment Comment) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &misc.Status{}
+	if comment.Rating == nil {
+		status.SetStatus(0, 0)
+		return status, misc.NewBadRequestError("Missing comment rating")
+	}
+
+	date := time.Now().UTC().Format(misc.TimestampFormat)
+	if comment.Date != nil {
+		date = *comment.Date
+	}
+
+	stored := memoryComment{
+		id:       s.nextId,
+		dishId:   dishId,
+		authorId: authorId,
+		rating:   *comment.Rating,
+		comment:  misc.CopyString(comment.Comment),
+		date:     date,
+	}
+	s.comments[stored.id] = stored
+	s.nextId++
+
+	status.SetStatus(1, 1)
+	return status, nil
+}
+
+func (s *memoryCommentStore) DeleteAll(ctx context.Context, dishId int64) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var numRowsDeleted int64
+	for id, stored := range s.comments {
+		if stored.dishId == dishId {
+			delete(s.comments, id)
+			numRowsDeleted++
+		}
+	}
+
+	status := &misc.Status{}
+	status.SetStatus(numRowsDeleted, 1)
+	return status, nil
+}
+
+// checkPrecondition checks precondition, if any, on the current comment, with s.mu locked
+func (s *memoryCommentStore) checkPrecondition(ctx context.Context, dishId, commentId int64,
+	precondition resource.Precondition[Comment]) error {
+
+	if precondition == nil {
+		return nil
+	}
+
+	stored, ok := s.comments[commentId]
+	if !ok || stored.dishId != dishId {
+		return precondition(nil)
+	}
+	current, err := s.toComment(ctx, stored)
+	if err != nil {
+		return err
+	}
+	return precondition(current)
+}
+
+func (s *memoryCommentStore) Delete(ctx context.Context, dishId, commentId, updatedByUserId int64,
+	precondition resource.Precondition[Comment]) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &misc.Status{}
+	if err := s.checkPrecondition(ctx, dishId, commentId, precondition); err != nil {
+		status.SetStatus(0, 0)
+		return status, err
+	}
+
+	stored, ok := s.comments[commentId]
+	if !ok || stored.dishId != dishId || stored.authorId != updatedByUserId {
+		status.SetStatus(0, 1)
+		return status, nil
+	}
+
+	delete(s.comments, commentId)
+	status.SetStatus(1, 1)
+	return status, nil
+}
+
+func (s *memoryCommentStore) Update(ctx context.Context, dishId int64, commentId int64, comment Comment, updatedByUserId int64,
+	precondition resource.Precondition[Comment]) (*Comment, error) {
+
+	s.mu.Lock()
+
+	// We only allow the user update the Rating or the Comment
+	if comment.Rating == nil && comment.Comment == nil {
+		s.mu.Unlock()
+		return nil, misc.NewBadRequestError("Nothing to update")
+	}
+
+	if err := s.checkPrecondition(ctx, dishId, commentId, precondition); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	stored, ok := s.comments[commentId]
+	if !ok || stored.dishId != dishId || stored.authorId != updatedByUserId {
+		s.mu.Unlock()
+		return nil, misc.NewNotFoundError(fmt.Sprintf("Comment %d not found", commentId))
+	}
+
+	if comment.Rating != nil {
+		stored.rating = *comment.Rating
+	}
+	if comment.Comment != nil {
+		stored.comment = misc.CopyString(comment.Comment)
+	}
+	stored.date = time.Now().UTC().Format(misc.TimestampFormat)
+	s.comments[commentId] = stored
+	s.mu.Unlock()
+
+	return s.toComment(ctx, stored)
+}
+
+func (s *memoryCommentStore) Get(ctx context.Context, dishId, commentId int64) (*Comment, error) {
+
+	s.mu.RLock()
+	stored, ok := s.comments[commentId]
+	s.mu.RUnlock()
+	if !ok || stored.dishId != dishId {
+		return nil, nil
+	}
+
+	return s.toComment(ctx, stored)
+}
+
+func (s *memoryCommentStore) List(ctx context.Context, dishId int64, params listing.Params) ([]Comment, listing.PageInfo, error) {
+
+	s.mu.RLock()
+	storedComments := make([]memoryComment, 0)
+	for _, stored := range s.comments {
+		if stored.dishId == dishId {
+			storedComments = append(storedComments, stored)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(storedComments, func(i, j int) bool { return storedComments[i].id < storedComments[j].id })
+
+	comments := make([]Comment, 0, len(storedComments))
+	for _, stored := range storedComments {
+		comment, err := s.toComment(ctx, stored)
+		if err != nil {
+			return nil, listing.PageInfo{}, err
+		}
+		// comments whose author no longer exists are dropped, like the SQL join does
+		if comment != nil {
+			comments = append(comments, *comment)
+		}
+	}
+
+	page, info := listing.Apply(comments, params, getFieldValue)
+	return page, info, nil
+}
@@ -1,190 +1,225 @@
 package comments
 
 import (
-	"encoding/json"
-	"io"
-	"log"
+	"context"
+	"fmt"
 	"net/http"
 
 	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/cache"
 	"confusion.com/bwoo/cors"
+	"confusion.com/bwoo/etag"
+	"confusion.com/bwoo/listing"
 	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/ratelimit"
+	"confusion.com/bwoo/resource"
+	"confusion.com/bwoo/validation"
 	"github.com/julienschmidt/httprouter"
 )
 
-func SetupRoutes(router *httprouter.Router) {
+// handlers serve the comments of store
+type handlers struct {
+	store CommentStore
+}
+
+// the comments change often, so the clients check them with their ETag every time
+const cacheControl = "no-cache"
+
+// SetupRoutes registers the comment routes, whose changes count against the
+// comments limit of limiters per user
+func SetupRoutes(router *misc.Router, store CommentStore, limiters *ratelimit.Limiters) {
+
+	// a wrong validate tag fails here rather than in the handlers
+	validation.MustRegister(Comment{})
+
+	h := &handlers{store: store}
 
 	// dish
-	router.GET("/dishes/:dishId/comments/:commentId", cors.CorsAllOrigin(getComment))
-	router.PUT("/dishes/:dishId/comments/:commentId", cors.Cors(auth.VerifyUser(putComment)))
-	router.POST("/dishes/:dishId/comments/:commentId", cors.Cors(auth.VerifyUser(postComment)))
-	router.DELETE("/dishes/:dishId/comments/:commentId", cors.Cors(auth.VerifyUser(deleteComment)))
+	router.GET("/dishes/:dishId/comments/:commentId", cors.CorsAllOrigin(cache.Control(cacheControl, h.getComment)))
+	router.PUT("/dishes/:dishId/comments/:commentId", cors.Cors(auth.VerifyUser(limiters.RateLimit("comments", auth.GetUserId, h.putComment))))
+	router.POST("/dishes/:dishId/comments/:commentId", cors.Cors(auth.VerifyUser(h.postComment)))
+	router.DELETE("/dishes/:dishId/comments/:commentId", cors.Cors(auth.VerifyUser(h.deleteComment)))
 
 	// dishes
-	router.GET("/dishes/:dishId/comments", cors.CorsAllOrigin(getComments))
-	router.PUT("/dishes/:dishId/comments", cors.Cors(auth.VerifyUser(putComments)))
-	router.POST("/dishes/:dishId/comments", cors.Cors(auth.VerifyUser(postComments)))
-	router.DELETE("/dishes/:dishId/comments", cors.Cors(auth.VerifyUser(auth.VerifyAdmin(deleteComments))))
+	router.GET("/dishes/:dishId/comments", cors.CorsAllOrigin(cache.Control(cacheControl, h.getComments)))
+	router.PUT("/dishes/:dishId/comments", cors.Cors(auth.VerifyUser(h.putComments)))
+	router.POST("/dishes/:dishId/comments", cors.Cors(auth.VerifyUser(limiters.RateLimit("comments", auth.GetUserId, h.postComments))))
+	router.DELETE("/dishes/:dishId/comments", cors.Cors(auth.VerifyUser(auth.VerifyAdmin(h.deleteComments))))
 }
 
-func getCommentFromBody(body io.ReadCloser) (Comment, error) {
+// getCommentFromBody decodes and validates the comment of the request body, only the
+// fields to change when partial
+func getCommentFromBody(w http.ResponseWriter, r *http.Request, partial bool) (Comment, error) {
 
 	var comment Comment
-	err := json.NewDecoder(body).Decode(&comment)
-	if err != nil {
-		return comment, err
+	if err := validation.DecodeAndValidate(w, r, &comment, partial); err != nil {
+		return Comment{}, err
 	}
+
 	return comment, nil
 }
 
 /****************************
 * Comment operations
 ****************************/
-func getComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) getComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
 	commentId := ps.ByName("commentId")
 	commentIdInt, err := misc.GetInt64FromString(commentId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid comment id "+commentId))
 		return
 	}
 
-	comment, err := getCommentFromDb(dishIdInt, commentIdInt)
+	comment, err := h.store.Get(r.Context(), dishIdInt, commentIdInt)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	var jsonComment []byte = make([]byte, 0)
 	if comment == nil {
-		jsonComment = misc.GetEmptyJsonByteArray()
-	} else {
-		jsonComment, err = misc.GetJsonFromJsonObjs(comment)
+		misc.WriteError(w, r, misc.NewNotFoundError(fmt.Sprintf("Comment %d not found", commentIdInt)))
+		return
 	}
 
+	jsonComment, err := misc.GetJsonFromJsonObjs(comment)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(jsonComment)
+	etag.WriteJson(w, r, jsonComment, etag.Compute(jsonComment))
 }
 
-func isCommentBelongsToUser(dishId, commentId, userId int64) bool {
+// verifyCommentBelongsToUser returns an error unless the comment exists and the user wrote it
+func (h *handlers) verifyCommentBelongsToUser(ctx context.Context, dishId, commentId, userId int64) error {
 
-	comment, err := getCommentFromDb(dishId, commentId)
+	comment, err := h.store.Get(ctx, dishId, commentId)
 	if err != nil {
-		return false
+		return err
 	}
 
 	if comment == nil {
-		return false
+		return misc.NewNotFoundError(fmt.Sprintf("Comment %d not found", commentId))
 	}
 
-	return comment.Author.ID == userId
+	if comment.Author == nil || comment.Author.ID != userId {
+		return misc.NewForbiddenError("You are not the author of this comment!")
+	}
+	return nil
 }
 
-func putComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+// ifMatch returns the precondition of the If-Match header of the request, nil when
+// it has none. It fails with precondition_failed unless the comment is the one the
+// header names.
+func ifMatch(r *http.Request) resource.Precondition[Comment] {
+
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+
+	return func(current *Comment) error {
+
+		if current == nil {
+			return etag.CheckIfMatch(r, "")
+		}
+		commentJson, err := misc.GetJsonFromJsonObjs(current)
+		if err != nil {
+			return err
+		}
+		return etag.CheckIfMatch(r, etag.Compute(commentJson))
+	}
+}
+
+func (h *handlers) putComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
 	commentId := ps.ByName("commentId")
 	commentIdInt, err := misc.GetInt64FromString(commentId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid comment id "+commentId))
 		return
 	}
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, _ := misc.GetInt64FromString(claims.UserId)
 
-	isCommentBelongsToUser := isCommentBelongsToUser(dishIdInt, commentIdInt, userId)
-	if !isCommentBelongsToUser {
-		w.WriteHeader(http.StatusUnauthorized)
+	if err := h.verifyCommentBelongsToUser(r.Context(), dishIdInt, commentIdInt, userId); err != nil {
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	comment, err := getCommentFromBody(r.Body)
+	comment, err := getCommentFromBody(w, r, true)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	updatedComment, err := updateCommentFromDb(dishIdInt, commentIdInt, comment, userId)
-	if err != nil && updatedComment == nil {
-		w.WriteHeader(http.StatusBadRequest)
+	updatedComment, err := h.store.Update(r.Context(), dishIdInt, commentIdInt, comment, userId, ifMatch(r))
+	if err != nil {
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	// if update was run but no rows updated (i.e. updatedDish.ID = 0),
-	// we will just return an empty json object
-	var updatedCommentJson []byte
-	if updatedComment.ID == 0 {
-		updatedCommentJson = misc.GetEmptyJsonByteArray()
-	} else {
-		updatedCommentJson, _ = misc.GetJsonFromJsonObjs(updatedComment)
-	}
+	updatedCommentJson, _ := misc.GetJsonFromJsonObjs(updatedComment)
 
+	// the ETag to send in If-Match with the next change
+	w.Header().Set("ETag", etag.Compute(updatedCommentJson))
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(updatedCommentJson))
 }
 
-func postComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) postComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	dishId := ps.ByName("dishId")
-	commentId := ps.ByName("commentId")
-	w.WriteHeader(http.StatusForbidden)
-	w.Write([]byte("POST operation not supported on /dishes/" + dishId + "/comments/" + commentId))
+	misc.WriteError(w, r, misc.NewMethodNotAllowedError(r))
 }
 
-func deleteComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) deleteComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
 	commentId := ps.ByName("commentId")
 	commentIdInt, err := misc.GetInt64FromString(commentId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid comment id "+commentId))
 		return
 	}
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, _ := misc.GetInt64FromString(claims.UserId)
 
-	isCommentBelongsToUser := isCommentBelongsToUser(dishIdInt, commentIdInt, userId)
-	if !isCommentBelongsToUser {
-		w.WriteHeader(http.StatusUnauthorized)
+	if err := h.verifyCommentBelongsToUser(r.Context(), dishIdInt, commentIdInt, userId); err != nil {
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	status, err := deleteCommentFromDb(dishIdInt, commentIdInt, userId)
+	status, err := h.store.Delete(r.Context(), dishIdInt, commentIdInt, userId, ifMatch(r))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
 	statusJson, err := misc.GetJsonFromJsonObjs(status)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
@@ -195,39 +230,44 @@ func deleteComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 /****************************
 * Comments operations
 ****************************/
-func getComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) getComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
-	comments, err := getCommentsFromDb(dishIdInt)
+	params, err := listing.Parse(r.URL.Query(), listFields)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	comments, info, err := h.store.List(r.Context(), dishIdInt, params)
+	if err != nil {
+		misc.WriteError(w, r, err)
 		return
 	}
 
 	commentsJson, err := misc.GetJsonFromJsonObjs(comments)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(commentsJson)
+	listing.WriteHeaders(w, r, params, comments, info, getFieldValue)
+	etag.WriteJson(w, r, commentsJson,
+		etag.Compute(commentsJson, []byte(w.Header().Get("X-Total-Count")), []byte(w.Header().Get("Link"))))
 }
 
-func putComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) putComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	dishId := ps.ByName("dishId")
-	w.WriteHeader(http.StatusForbidden)
-	w.Write([]byte("PUT operation not supported on /dishes/" + dishId + "/comments"))
+	misc.WriteError(w, r, misc.NewMethodNotAllowedError(r))
 }
 
-func postComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) postComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, err := misc.GetInt64FromString(claims.UserId)
@@ -235,47 +275,49 @@ func postComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
-	comment, err := getCommentFromBody(r.Body)
+	comment, err := getCommentFromBody(w, r, false)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	status, err := createCommentInDb(dishIdInt, userId, comment)
-	statusJson, _ := misc.GetJsonFromJsonObjs(status)
+	// a comment is dated when it is posted
+	comment.Date = nil
+
+	status, err := h.store.Create(r.Context(), dishIdInt, userId, comment)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(statusJson)
+		misc.WriteError(w, r, err)
 		return
 	}
 
+	statusJson, _ := misc.GetJsonFromJsonObjs(status)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(statusJson)
 }
 
-func deleteComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) deleteComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
-	status, err := deleteCommentsFromDb(dishIdInt)
+	status, err := h.store.DeleteAll(r.Context(), dishIdInt)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
 	statusJson, err := misc.GetJsonFromJsonObjs(status)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,216 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/misc"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testConfig = config.Config{
+	JwtKey:           "test",
+	JwtExpiration:    time.Hour,
+	PasswordHashCost: bcrypt.MinCost,
+}
+
+// newTestRouter serves the comments of a memory store holding one comment
+// of dish 1, written by a user named firstname whose password is "secret",
+// and the login of the users
+func newTestRouter(t *testing.T, firstname string) *misc.Router {
+
+	t.Helper()
+	ctx := context.Background()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	users := auth.NewMemoryStore()
+	author := auth.UserInfo{Firstname: firstname, Lastname: "Doe"}
+	author.Username = strings.ToLower(firstname)
+	userId, err := users.CreateUser(ctx, author, hash)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	store := NewMemoryStore(users)
+	rating := 5
+	text := "Tasty"
+	if _, err := store.Create(ctx, 1, userId, Comment{Rating: &rating, Comment: &text}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	router := misc.NewRouter()
+	SetupRoutes(router, store, nil)
+	auth.SetupRoutes(router, testConfig, users, auth.NewMemoryFailedLoginStore(), nil)
+	return router
+}
+
+func serve(router *misc.Router, method, path string) *httptest.ResponseRecorder {
+
+	return serveWithHeaders(router, method, path, "", nil)
+}
+
+func serveWithHeaders(router *misc.Router, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func login(t *testing.T, router *misc.Router, username string) string {
+
+	t.Helper()
+
+	w := serveWithHeaders(router, http.MethodPost, "/users/login", `{"username":"`+username+`","password":"secret"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /users/login as %s: got status %d, want %d", username, w.Code, http.StatusOK)
+	}
+
+	var result struct{ Token string }
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("POST /users/login: %v", err)
+	}
+	return result.Token
+}
+
+func TestGetComment(t *testing.T) {
+
+	// each router keeps its own store
+	jane := newTestRouter(t, "Jane")
+	john := newTestRouter(t, "John")
+
+	for _, test := range []struct {
+		router    *misc.Router
+		firstname string
+	}{{jane, "Jane"}, {john, "John"}} {
+
+		w := serve(test.router, http.MethodGet, "/dishes/1/comments/1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /dishes/1/comments/1: got status %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var comment Comment
+		if err := json.Unmarshal(w.Body.Bytes(), &comment); err != nil {
+			t.Fatalf("GET /dishes/1/comments/1: %v", err)
+		}
+		if comment.Author == nil || comment.Author.Firstname != test.firstname {
+			t.Errorf("GET /dishes/1/comments/1: got author %+v, want %s", comment.Author, test.firstname)
+		}
+	}
+}
+
+func TestGetCommentErrors(t *testing.T) {
+
+	router := newTestRouter(t, "Jane")
+
+	for _, test := range []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/dishes/1/comments/2", http.StatusNotFound, misc.ErrorCodeNotFound},
+		{"/dishes/2/comments/1", http.StatusNotFound, misc.ErrorCodeNotFound},
+		{"/dishes/1/comments/x", http.StatusBadRequest, misc.ErrorCodeBadRequest},
+	} {
+
+		w := serve(router, http.MethodGet, test.path)
+		if w.Code != test.status {
+			t.Errorf("GET %s: got status %d, want %d", test.path, w.Code, test.status)
+			continue
+		}
+
+		var response struct{ Error misc.Error }
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("GET %s: %v", test.path, err)
+		}
+		if response.Error.Code != test.code {
+			t.Errorf("GET %s: got code %q, want %q", test.path, response.Error.Code, test.code)
+		}
+	}
+}
+
+func TestCommentETags(t *testing.T) {
+
+	router := newTestRouter(t, "Jane")
+	bearer := "Bearer " + login(t, router, "jane")
+	path := "/dishes/1/comments/1"
+
+	// expect serves the request and checks its status, returning its ETag
+	expect := func(method, body string, headers map[string]string, status int) string {
+
+		t.Helper()
+
+		w := serveWithHeaders(router, method, path, body, headers)
+		if w.Code != status {
+			t.Fatalf("%s %s with %v: got status %d, want %d", method, path, headers, w.Code, status)
+		}
+		if status == http.StatusPreconditionFailed {
+			var response struct{ Error misc.Error }
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error.Code != misc.ErrorCodePreconditionFailed {
+				t.Errorf("%s %s with %v: got %s, want a precondition_failed error", method, path, headers, w.Body.String())
+			}
+		}
+		return w.Header().Get("ETag")
+	}
+
+	first := expect(http.MethodGet, "", nil, http.StatusOK)
+	if first == "" {
+		t.Fatalf("GET %s: got no ETag", path)
+	}
+	if got := expect(http.MethodGet, "", map[string]string{"If-None-Match": first}, http.StatusNotModified); got != first {
+		t.Errorf("GET %s, not modified: got ETag %q, want %q", path, got, first)
+	}
+
+	// a change made from a stale copy fails
+	expect(http.MethodPut, `{"comment":"Salty"}`, map[string]string{"Authorization": bearer, "If-Match": `"stale"`},
+		http.StatusPreconditionFailed)
+	second := expect(http.MethodPut, `{"comment":"Salty"}`, map[string]string{"Authorization": bearer, "If-Match": first},
+		http.StatusOK)
+	if second == "" || second == first {
+		t.Errorf("PUT %s: got ETag %q, want a new one", path, second)
+	}
+
+	// the ETag of PUT is the one of GET
+	if got := expect(http.MethodGet, "", map[string]string{"If-None-Match": first}, http.StatusOK); got != second {
+		t.Errorf("GET %s after PUT: got ETag %q, want %q", path, got, second)
+	}
+
+	expect(http.MethodDelete, "", map[string]string{"Authorization": bearer, "If-Match": first}, http.StatusPreconditionFailed)
+	expect(http.MethodDelete, "", map[string]string{"Authorization": bearer, "If-Match": second}, http.StatusOK)
+	expect(http.MethodGet, "", nil, http.StatusNotFound)
+}
+
+func TestCommentsETag(t *testing.T) {
+
+	router := newTestRouter(t, "Jane")
+	path := "/dishes/1/comments"
+
+	w := serve(router, http.MethodGet, path)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET %s: got status %d and ETag %q, want %d and an ETag", path, w.Code, etag, http.StatusOK)
+	}
+
+	if w := serveWithHeaders(router, http.MethodGet, path, "", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("GET %s with its ETag: got status %d and %d bytes, want %d and none", path, w.Code, w.Body.Len(), http.StatusNotModified)
+	}
+
+	// another page is another representation
+	if w := serveWithHeaders(router, http.MethodGet, path+"?limit=1&offset=1", "", map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+		t.Errorf("GET %s?offset=1 with the ETag of %s: got status %d, want %d", path, path, w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,24 @@
+package comments
+
+import (
+	"context"
+
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/resource"
+)
+
+// CommentStore is the persistence layer used by the comment handlers.
+type CommentStore interface {
+	Get(ctx context.Context, dishId, commentId int64) (*Comment, error)
+	// List returns the page of the comments of the dish selected by params and where it lies among the comments matching its filters
+	List(ctx context.Context, dishId int64, params listing.Params) ([]Comment, listing.PageInfo, error)
+	// Create dates the comment now, unless comment.Date is set
+	Create(ctx context.Context, dishId int64, authorId int64, comment Comment) (*misc.Status, error)
+	// Update and Delete only touch the comment if it was written by updatedByUserId,
+	// and if precondition, when not nil, accepts it
+	Update(ctx context.Context, dishId int64, commentId int64, comment Comment, updatedByUserId int64,
+		precondition resource.Precondition[Comment]) (*Comment, error)
+	Delete(ctx context.Context, dishId, commentId, updatedByUserId int64, precondition resource.Precondition[Comment]) (*misc.Status, error)
+	DeleteAll(ctx context.Context, dishId int64) (*misc.Status, error)
+}
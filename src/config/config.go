@@ -1,44 +1,223 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Supported values of db_driver
+const (
+	MySQLDriver    = "mysql"
+	SQLiteDriver   = "sqlite"
+	PostgresDriver = "postgres"
+	// MemoryDriver keeps all data in memory instead of connecting to a database
+	MemoryDriver = "memory"
+)
+
+// Supported values of cache_backend
+const (
+	LruCache   = "lru"
+	RedisCache = "redis"
+	NoCache    = "none"
+)
+
+// Config holds every setting of the server. A setting is named after its json
+// key, which is also the name of its command-line flag and, upper cased with
+// a CONFUSION_ prefix, of its environment variable. See Load.
+// Relative paths in the config file are relative to the directory of the
+// file, elsewhere to the working directory.
 type Config struct {
-	DbDriver             string `json:"db_driver"`
-	DbHost               string `json:"db_host"`
-	DbPort               string `json:"db_port"`
-	DbUser               string `json:"db_user"`
-	DbPasswd             string `json:"db_passwd"`
-	DbName               string `json:"db_name"`
-	BaseDir              string `json:"base_dir"`
-	PublicImagesDir      string `json:"public_images_dir"`
-	Oauth2FbClientID     string `json:"oauth2_fb_client_id"`
-	Oauth2FbClientSecret string `json:"oauth2_fb_client_secret"`
-	Oauth2FbRedirectUrl  string `json:"oauth2_fb_redirect_url"`
+	DbDriver             string        `json:"db_driver" usage:"database backend: mysql, postgres, sqlite or memory"`
+	DbHost               string        `json:"db_host" usage:"database host"`
+	DbPort               string        `json:"db_port" usage:"database port"`
+	DbUser               string        `json:"db_user" usage:"database user"`
+	DbPasswd             string        `json:"db_passwd" secret:"true" usage:"database password"`
+	DbName               string        `json:"db_name" usage:"database name, the file path for sqlite"`
+	DbSslMode            string        `json:"db_sslmode" usage:"sslmode of the postgres connection"`
+	DbAutoMigrate        bool          `json:"db_auto_migrate" usage:"apply pending migrations on start"`
+	SeedFile             string        `json:"seed_file" path:"true" usage:"db.json file imported by the seed command"`
+	BaseDir              string        `json:"base_dir" usage:"base directory of the sources"`
+	PublicImagesDir      string        `json:"public_images_dir" path:"true" usage:"directory of the uploaded images"`
+	Oauth2FbClientID     string        `json:"oauth2_fb_client_id" usage:"Facebook app id, empty disables the Facebook login"`
+	Oauth2FbClientSecret string        `json:"oauth2_fb_client_secret" secret:"true" usage:"Facebook app secret"`
+	Oauth2FbRedirectUrl  string        `json:"oauth2_fb_redirect_url" usage:"Facebook login callback url"`
+	ServerListenAddr     string        `json:"server_listen_addr" usage:"address of the http server"`
+	ServerListenSslAddr  string        `json:"server_listen_ssl_addr" usage:"address of the https server"`
+	HttpsRedirect        bool          `json:"https_redirect" usage:"run the http server to redirect to https"`
+	HttpOnly             bool          `json:"http_only" usage:"serve the API over plain http only, e.g. behind a TLS-terminating proxy"`
+	MetricsListenAddr    string        `json:"metrics_listen_addr" usage:"address of the plain http server of GET /metrics, apart from the API, empty disables it"`
+	TrustedProxies       string        `json:"trusted_proxies" usage:"comma separated IP addresses or CIDR ranges of the proxies whose X-Forwarded-For and X-Real-IP headers name the client"`
+	ShutdownTimeout      time.Duration `json:"shutdown_timeout" usage:"time given to running requests to finish on SIGINT or SIGTERM"`
+	CertPath             string        `json:"cert_path" path:"true" usage:"TLS certificate file"`
+	KeyPath              string        `json:"key_path" path:"true" usage:"TLS private key file"`
+	SelfSignedCert       bool          `json:"self_signed_cert" usage:"generate a self-signed certificate at cert_path and key_path if they do not exist"`
+	AcmeDomains          string        `json:"acme_domains" usage:"comma separated domains to get ACME certificates for, empty disables ACME"`
+	AcmeDirectoryUrl     string        `json:"acme_directory_url" usage:"directory url of the ACME server"`
+	AcmeEmail            string        `json:"acme_email" usage:"contact email of the ACME account"`
+	AcmeCacheDir         string        `json:"acme_cache_dir" path:"true" usage:"directory keeping the ACME account and certificates"`
+	AcmeCaCert           string        `json:"acme_ca_cert" path:"true" usage:"extra CA certificate trusted to reach the ACME server, e.g. of a test server"`
+	CertMinValidity      time.Duration `json:"cert_min_validity" usage:"/readyz fails when the certificate expires sooner than this"`
+	JwtKey               string        `json:"jwt_key" secret:"true" usage:"key signing the JSON web tokens"`
+	JwtExpiration        time.Duration `json:"jwt_expiration" usage:"lifetime of the JSON web tokens, e.g. 24h"`
+	PasswordHashCost     int           `json:"password_hash_cost" usage:"bcrypt cost of the password hashes"`
+	LockoutThreshold     int           `json:"lockout_threshold" usage:"failed logins of a username within lockout_window that lock it, 0 disables the lockout"`
+	LockoutIpThreshold   int           `json:"lockout_ip_threshold" usage:"failed logins from an IP address within lockout_window that lock it, 0 disables the lockout"`
+	LockoutWindow        time.Duration `json:"lockout_window" usage:"time within which the failed logins are counted"`
+	LockoutDuration      time.Duration `json:"lockout_duration" usage:"time a username or IP address stays locked after its last failed login"`
+	LogLevel             string        `json:"log_level" usage:"lowest level logged: debug, info, warn or error"`
+	LogFormat            string        `json:"log_format" usage:"format of the log lines: json or text"`
+	RateLimits           string        `json:"rate_limits" usage:"comma separated <name>=<requests>/<period>[:<burst>] limits, e.g. login=10/1m, see the Readme for the names"`
+	OtlpEndpoint         string        `json:"otlp_endpoint" usage:"OTLP/HTTP collector url the trace spans are sent to, e.g. http://localhost:4318, empty disables tracing"`
+	CacheBackend         string        `json:"cache_backend" usage:"cache of the dish, leader and promotion reads: lru, redis or none"`
+	CacheSize            int           `json:"cache_size" usage:"number of entries kept by the lru cache"`
+	CacheTtl             time.Duration `json:"cache_ttl" usage:"time an entry stays cached, unless a change through the server removes it sooner"`
+	CacheRedisAddr       string        `json:"cache_redis_addr" usage:"host:port of the Redis server of the redis cache"`
+	CacheRedisPasswd     string        `json:"cache_redis_passwd" secret:"true" usage:"password of the Redis server, empty if it has none"`
 }
 
-func ReadDbConfig(inputConfigFile string) Config {
-	configFile, err := os.Open(inputConfigFile)
-	defer configFile.Close()
-	if err != nil {
-		panic("Error opening config file")
+// defaultConfig holds the settings used when neither the config file,
+// the environment nor the flags set them
+func defaultConfig() Config {
+	return Config{
+		DbDriver:            MySQLDriver,
+		SeedFile:            "../../db.json",
+		ServerListenAddr:    "0.0.0.0:3000",
+		ServerListenSslAddr: "0.0.0.0:3443",
+		HttpsRedirect:       true,
+		MetricsListenAddr:   "127.0.0.1:9090",
+		ShutdownTimeout:     30 * time.Second,
+		CertPath:            "../../certs/www.confusion.com.crt",
+		KeyPath:             "../../certs/www.confusion.com.key",
+		SelfSignedCert:      true,
+		AcmeDirectoryUrl:    "https://acme-v02.api.letsencrypt.org/directory",
+		AcmeCacheDir:        "../../certs/acme",
+		CertMinValidity:     7 * 24 * time.Hour,
+		JwtExpiration:       24 * time.Hour,
+		PasswordHashCost:    8,
+		LockoutThreshold:    5,
+		LockoutIpThreshold:  20,
+		LockoutWindow:       15 * time.Minute,
+		LockoutDuration:     15 * time.Minute,
+		LogLevel:            "info",
+		LogFormat:           "json",
+		RateLimits:          "login=10/1m,signup=5/1m,comments=20/1m,search=60/1m",
+		CacheBackend:        LruCache,
+		CacheSize:           1000,
+		CacheTtl:            5 * time.Minute,
+		CacheRedisAddr:      "localhost:6379",
+	}
+}
+
+// RateLimit lets Requests requests through per Period, up to Burst at once
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// GetRateLimits parses rate_limits, e.g. "login=10/1m,comments=20/1m:40", into the limits by name.
+// Without a burst, the requests of a whole period may come at once.
+func (c *Config) GetRateLimits() (map[string]RateLimit, error) {
+
+	limits := make(map[string]RateLimit)
+	for _, entry := range strings.Split(c.RateLimits, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		requests, period, ok2 := strings.Cut(value, "/")
+		if !ok || !ok2 || name == "" {
+			return nil, fmt.Errorf("expected <name>=<requests>/<period>[:<burst>], got %q", entry)
+		}
+		period, burst, hasBurst := strings.Cut(period, ":")
+
+		var limit RateLimit
+		var err error
+		if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests < 1 {
+			return nil, fmt.Errorf("expected a positive number of requests in %q", entry)
+		}
+		if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+			return nil, fmt.Errorf("expected a positive period like 1m in %q", entry)
+		}
+		limit.Burst = limit.Requests
+		if hasBurst {
+			if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst < 1 {
+				return nil, fmt.Errorf("expected a positive burst in %q", entry)
+			}
+		}
+		if _, ok := limits[name]; ok {
+			return nil, fmt.Errorf("%s is limited twice", name)
+		}
+		limits[name] = limit
 	}
+	return limits, nil
+}
+
+// GetTrustedProxies parses trusted_proxies, e.g. "10.0.0.0/8,127.0.0.1", into
+// prefixes, an address being the prefix of its own bits
+func (c *Config) GetTrustedProxies() ([]netip.Prefix, error) {
 
-	decoder := json.NewDecoder(configFile)
-	var config Config
-	err = decoder.Decode(&config)
-	if err != nil {
-		panic("Error decoding config file")
+	prefixes := make([]netip.Prefix, 0)
+	for _, entry := range strings.Split(c.TrustedProxies, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("expected an IP address or a CIDR range like 10.0.0.0/8, got %q", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
 	}
+	return prefixes, nil
+}
+
+// GetAcmeDomains splits acme_domains
+func (c *Config) GetAcmeDomains() []string {
 
-	return config
+	domains := make([]string, 0)
+	for _, domain := range strings.Split(c.AcmeDomains, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
 }
 
 func (c *Config) GetConnString() string {
 
+	// for SQLite, db_name is the path of the database file. The transactions
+	// take the write lock when they begin, so what they read stays current.
+	if c.DbDriver == SQLiteDriver {
+		return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate",
+			c.DbName)
+	}
+
+	// db_sslmode is only used by PostgreSQL, see the sslmode parameter of libpq
+	if c.DbDriver == PostgresDriver {
+		sslMode := c.DbSslMode
+		if sslMode == "" {
+			sslMode = "prefer"
+		}
+		dbUrl := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.DbUser, c.DbPasswd),
+			Host:     c.DbHost + ":" + c.DbPort,
+			Path:     c.DbName,
+			RawQuery: "sslmode=" + url.QueryEscape(sslMode),
+		}
+		return dbUrl.String()
+	}
+
 	connString := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", c.DbUser,
 		c.DbPasswd,
 		c.DbHost,
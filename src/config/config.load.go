@@ -0,0 +1,347 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix prefixes the environment variable of every setting
+const envPrefix = "CONFUSION_"
+
+// configPathEnv names the config file, unless the -config flag is given
+const configPathEnv = "CONFUSION_CONFIG_PATH"
+
+// secretFileSuffix turns the name of a secret setting into the name of the
+// setting holding the path of a file to read it from, e.g. db_passwd_file
+const secretFileSuffix = "_file"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is a field of Config
+type setting struct {
+	name   string
+	secret bool
+	isPath bool
+	usage  string
+	value  reflect.Value
+}
+
+// layer is a source of settings: the config file, the environment or the flags
+type layer struct {
+	lookup func(name string) (string, bool)
+	// describe tells where a setting comes from, for the error messages
+	describe func(name string) string
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, the config file, the CONFUSION_* environment variables and
+// the flags in args, then validates it. It returns the arguments left after
+// the flags. A secret setting such as db_passwd can instead be read from the
+// file named by db_passwd_file, CONFUSION_DB_PASSWD_FILE or -db_passwd_file,
+// as Docker and Kubernetes secrets are mounted.
+func Load(args []string) (Config, []string, error) {
+
+	config := defaultConfig()
+
+	flags, flagLayer := newFlagLayer(&config)
+	configFilePath := flags.String("config", os.Getenv(configPathEnv),
+		"config file, defaults to $"+configPathEnv)
+	flags.Parse(args)
+
+	errs := make([]error, 0)
+	layers := make([]layer, 0, 3)
+	if *configFilePath != "" {
+		fileLayer, err := newFileLayer(&config, *configFilePath)
+		if fileLayer.lookup == nil {
+			return Config{}, nil, err
+		}
+		errs = append(errs, err)
+		layers = append(layers, fileLayer)
+	}
+	layers = append(layers, newEnvLayer(), flagLayer)
+
+	for _, layer := range layers {
+		errs = append(errs, config.apply(layer))
+	}
+	errs = append(errs, config.Validate())
+
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, nil, fmt.Errorf("Invalid configuration:\n%w", err)
+	}
+
+	return config, flags.Args(), nil
+}
+
+func (c *Config) getSettings() []setting {
+
+	configValue := reflect.ValueOf(c).Elem()
+	configType := configValue.Type()
+
+	settings := make([]setting, 0, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		settings = append(settings, setting{
+			name:   field.Tag.Get("json"),
+			secret: field.Tag.Get("secret") == "true",
+			isPath: field.Tag.Get("path") == "true",
+			usage:  field.Tag.Get("usage"),
+			value:  configValue.Field(i),
+		})
+	}
+	return settings
+}
+
+// apply sets every setting found in layer and returns all the values that
+// could not be parsed
+func (c *Config) apply(layer layer) error {
+
+	errs := make([]error, 0)
+	for _, setting := range c.getSettings() {
+
+		raw, isSet := layer.lookup(setting.name)
+
+		if setting.secret {
+			fileName := setting.name + secretFileSuffix
+			if path, isFileSet := layer.lookup(fileName); isFileSet {
+				if isSet {
+					errs = append(errs, fmt.Errorf("%s and %s are both set, set only one of them",
+						layer.describe(setting.name), layer.describe(fileName)))
+					continue
+				}
+
+				var err error
+				if raw, err = readSecretFile(path); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %v", layer.describe(fileName), err))
+					continue
+				}
+				isSet = true
+			}
+		}
+
+		if !isSet {
+			continue
+		}
+		if err := setting.set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", layer.describe(setting.name), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// set parses raw into the setting
+func (s setting) set(raw string) error {
+
+	switch {
+	case s.value.Type() == durationType:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected a duration like 24h or 90m, got %q", raw)
+		}
+		s.value.SetInt(int64(duration))
+
+	case s.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		s.value.SetBool(b)
+
+	case s.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		s.value.SetInt(int64(n))
+
+	default:
+		s.value.SetString(raw)
+	}
+
+	return nil
+}
+
+// readSecretFile returns the content of a secret file without the trailing
+// newline most editors and `echo` add
+func readSecretFile(path string) (string, error) {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// getSettingNames returns the names a layer may set, including the _file
+// variants of the secret settings
+func getSettingNames(settings []setting) map[string]bool {
+
+	names := make(map[string]bool)
+	for _, setting := range settings {
+		names[setting.name] = true
+		if setting.secret {
+			names[setting.name+secretFileSuffix] = true
+		}
+	}
+	return names
+}
+
+// getPathSettingNames returns the names of the settings holding a path,
+// including the secret files
+func getPathSettingNames(settings []setting) map[string]bool {
+
+	names := make(map[string]bool)
+	for _, setting := range settings {
+		if setting.isPath {
+			names[setting.name] = true
+		}
+		if setting.secret {
+			names[setting.name+secretFileSuffix] = true
+		}
+	}
+	return names
+}
+
+/****************************
+* Layers
+****************************/
+// newFileLayer reads the config file. It returns an empty layer if the file
+// cannot be read, and the layer along with the invalid entries otherwise.
+func newFileLayer(c *Config, path string) (layer, error) {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return layer{}, fmt.Errorf("Error reading config file: %v", err)
+	}
+
+	var rawValues map[string]json.RawMessage
+	if err := json.Unmarshal(content, &rawValues); err != nil {
+		return layer{}, fmt.Errorf("Error decoding config file %s: %v", path, err)
+	}
+
+	settings := c.getSettings()
+	knownNames := getSettingNames(settings)
+	pathNames := getPathSettingNames(settings)
+	configDir := filepath.Dir(path)
+	values := make(map[string]string)
+	errs := make([]error, 0)
+	for _, name := range getSortedKeys(rawValues) {
+
+		rawValue := rawValues[name]
+		if !knownNames[name] {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %s", path, name))
+			continue
+		}
+
+		// like in the environment, every value is kept as a string and parsed
+		// according to its setting, so "3306" and 3306 are both accepted
+		var str string
+		if err := json.Unmarshal(rawValue, &str); err == nil {
+			if pathNames[name] && str != "" && !filepath.IsAbs(str) {
+				str = filepath.Join(configDir, str)
+			}
+			values[name] = str
+			continue
+		}
+		var scalar interface{}
+		json.Unmarshal(rawValue, &scalar)
+		switch scalar.(type) {
+		case bool, float64:
+			values[name] = string(rawValue)
+		default:
+			errs = append(errs, fmt.Errorf("%s: %s: expected a string, a number or a boolean, got %s",
+				path, name, rawValue))
+		}
+	}
+	return layer{
+		lookup: func(name string) (string, bool) {
+			value, ok := values[name]
+			return value, ok
+		},
+		describe: func(name string) string {
+			return path + ": " + name
+		},
+	}, errors.Join(errs...)
+}
+
+func getSortedKeys(rawValues map[string]json.RawMessage) []string {
+
+	keys := make([]string, 0, len(rawValues))
+	for key := range rawValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func getEnvName(name string) string {
+	return envPrefix + strings.ToUpper(name)
+}
+
+func newEnvLayer() layer {
+	return layer{
+		lookup: func(name string) (string, bool) {
+			return os.LookupEnv(getEnvName(name))
+		},
+		describe: getEnvName,
+	}
+}
+
+// flagValue records the raw value of a flag, parsed later like the other layers
+type flagValue struct {
+	name   string
+	isBool bool
+	values map[string]string
+}
+
+func (f flagValue) String() string {
+	return ""
+}
+
+func (f flagValue) Set(raw string) error {
+	f.values[f.name] = raw
+	return nil
+}
+
+// IsBoolFlag allows -db_auto_migrate without a value
+func (f flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+func newFlagLayer(c *Config) (*flag.FlagSet, layer) {
+
+	flags := flag.NewFlagSet("main", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: main [flags] [migrate|seed|export ...]")
+		flags.PrintDefaults()
+	}
+
+	values := make(map[string]string)
+	for _, setting := range c.getSettings() {
+		isBool := setting.value.Kind() == reflect.Bool
+		flags.Var(flagValue{name: setting.name, isBool: isBool, values: values}, setting.name, setting.usage)
+		if setting.secret {
+			fileName := setting.name + secretFileSuffix
+			flags.Var(flagValue{name: fileName, values: values}, fileName, "file to read "+setting.name+" from")
+		}
+	}
+
+	return flags, layer{
+		lookup: func(name string) (string, bool) {
+			value, ok := values[name]
+			return value, ok
+		},
+		describe: func(name string) string {
+			return "-" + name
+		},
+	}
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJwtKey = "0123456789abcdef"
+
+// clearEnv unsets the CONFUSION_* environment variables for the test
+func clearEnv(t *testing.T) {
+
+	t.Helper()
+
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, envPrefix) {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+}
+
+// writeFile writes content to name in dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+// the file sets db_name and lockout_threshold, which the environment and the flags override in turn
+func TestLoadPrecedence(t *testing.T) {
+
+	for _, test := range []struct {
+		name          string
+		file          bool
+		env           map[string]string
+		flags         []string
+		wantDbName    string
+		wantThreshold int
+	}{
+		{"defaults", false, nil, nil, "", 5},
+		{"file", true, nil, nil, "file.db", 1},
+		{"environment over file", true, map[string]string{"CONFUSION_DB_NAME": "env.db", "CONFUSION_LOCKOUT_THRESHOLD": "2"},
+			nil, "env.db", 2},
+		{"flags over environment", true, map[string]string{"CONFUSION_DB_NAME": "env.db", "CONFUSION_LOCKOUT_THRESHOLD": "2"},
+			[]string{"-db_name", "flag.db", "-lockout_threshold=3"}, "flag.db", 3},
+		{"flags over file", true, nil, []string{"-lockout_threshold", "3"}, "file.db", 3},
+		{"environment without file", false, map[string]string{"CONFUSION_LOCKOUT_THRESHOLD": "2"}, nil, "", 2},
+	} {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			clearEnv(t)
+			args := []string{"-db_driver", MemoryDriver, "-public_images_dir", "images", "-jwt_key", testJwtKey}
+			if test.file {
+				path := writeFile(t, t.TempDir(), "config.json", `{"db_name": "file.db", "lockout_threshold": 1}`)
+				args = append(args, "-config", path)
+			}
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			config, _, err := Load(append(args, test.flags...))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if config.DbName != test.wantDbName || config.LockoutThreshold != test.wantThreshold {
+				t.Errorf("Load: got db_name %q and lockout_threshold %d, want %q and %d",
+					config.DbName, config.LockoutThreshold, test.wantDbName, test.wantThreshold)
+			}
+		})
+	}
+}
+
+func TestLoadConfigPathFromEnvironment(t *testing.T) {
+
+	clearEnv(t)
+	path := writeFile(t, t.TempDir(), "config.json",
+		`{"db_driver": "memory", "public_images_dir": "images", "jwt_key": "`+testJwtKey+`", "shutdown_timeout": "5s"}`)
+	t.Setenv(configPathEnv, path)
+
+	config, args, err := Load([]string{"seed", "db.json"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if config.ShutdownTimeout != 5*time.Second {
+		t.Errorf("Load: got shutdown_timeout %s, want 5s", config.ShutdownTimeout)
+	}
+	if !slices.Equal(args, []string{"seed", "db.json"}) {
+		t.Errorf("Load: got args %q, want the command", args)
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+
+	for _, test := range []struct {
+		name  string
+		file  string
+		env   map[string]string
+		flags []string
+		want  string
+	}{
+		{"file relative to the config file", `{"jwt_key_file": "secrets/jwt"}`, nil, nil, "config-dir-secret"},
+		{"environment", "", map[string]string{"CONFUSION_JWT_KEY_FILE": "SECRET"}, nil, "environment-secret"},
+		{"flag", "", nil, []string{"-jwt_key_file", "SECRET"}, "environment-secret"},
+		{"flag file over environment value", "", map[string]string{"CONFUSION_JWT_KEY": "env-value-secret"},
+			[]string{"-jwt_key_file", "SECRET"}, "environment-secret"},
+	} {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			clearEnv(t)
+			dir := t.TempDir()
+			os.Mkdir(filepath.Join(dir, "secrets"), 0o700)
+			// editors and echo end the file with a newline, which is not part of the secret
+			writeFile(t, dir, "secrets/jwt", "config-dir-secret\n")
+			secretPath := writeFile(t, t.TempDir(), "jwt", "environment-secret\r\n")
+
+			args := []string{"-db_driver", MemoryDriver, "-public_images_dir", "images"}
+			if test.file != "" {
+				args = append(args, "-config", writeFile(t, dir, "config.json", test.file))
+			}
+			for name, value := range test.env {
+				t.Setenv(name, strings.ReplaceAll(value, "SECRET", secretPath))
+			}
+			for _, flag := range test.flags {
+				args = append(args, strings.ReplaceAll(flag, "SECRET", secretPath))
+			}
+
+			config, _, err := Load(args)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if config.JwtKey != test.want {
+				t.Errorf("Load: got jwt_key %q, want %q", config.JwtKey, test.want)
+			}
+		})
+	}
+}
+
+// the paths of the config file are relative to its directory, the others to the working directory
+func TestLoadRelativePaths(t *testing.T) {
+
+	clearEnv(t)
+	dir := t.TempDir()
+	absolute := filepath.Join(t.TempDir(), "cert.pem")
+	path := writeFile(t, dir, "config.json", `{"db_driver": "memory", "jwt_key": "`+testJwtKey+`",
+		"public_images_dir": "public/images", "seed_file": "../db.json", "cert_path": "`+filepath.ToSlash(absolute)+`",
+		"key_path": "", "db_name": "relative.db"}`)
+
+	config, _, err := Load([]string{"-config", path, "-key_path", "certs/key.pem"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	for _, test := range []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"public_images_dir", config.PublicImagesDir, filepath.Join(dir, "public/images")},
+		{"seed_file", config.SeedFile, filepath.Join(filepath.Dir(dir), "db.json")},
+		{"cert_path", config.CertPath, absolute},
+		// a flag is relative to the working directory
+		{"key_path", config.KeyPath, "certs/key.pem"},
+		// db_name is not a path setting, even though sqlite reads it as one
+		{"db_name", config.DbName, "relative.db"},
+		{"acme_cache_dir", config.AcmeCacheDir, "../../certs/acme"},
+	} {
+
+		if test.got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+
+	for _, test := range []struct {
+		name      string
+		file      string
+		env       map[string]string
+		flags     []string
+		wantInErr []string
+	}{
+		{"missing settings", "", nil, []string{"-public_images_dir", ""},
+			[]string{"db_host: required when db_driver is mysql", "public_images_dir: required to store the uploaded images",
+				"jwt_key: required to sign the JSON web tokens"}},
+		{"unreadable config file", "", nil, []string{"-config", "MISSING"}, []string{"Error reading config file"}},
+		{"invalid json", `{"db_driver": `, nil, nil, []string{"Error decoding config file"}},
+		{"unknown setting", `{"db_driver": "memory", "db_pasword": "x"}`, nil, nil, []string{"unknown setting db_pasword"}},
+		{"not a scalar", `{"db_driver": ["memory"]}`, nil, nil,
+			[]string{"db_driver: expected a string, a number or a boolean"}},
+		{"invalid values", "", map[string]string{"CONFUSION_LOCKOUT_WINDOW": "15", "CONFUSION_HTTP_ONLY": "yes"},
+			[]string{"-password_hash_cost", "eight"}, []string{`CONFUSION_LOCKOUT_WINDOW: expected a duration like 24h or 90m, got "15"`,
+				`CONFUSION_HTTP_ONLY: expected true or false, got "yes"`, `-password_hash_cost: expected an integer, got "eight"`}},
+		{"secret and its file", "", map[string]string{"CONFUSION_JWT_KEY": testJwtKey, "CONFUSION_JWT_KEY_FILE": "jwt"}, nil,
+			[]string{"CONFUSION_JWT_KEY and CONFUSION_JWT_KEY_FILE are both set, set only one of them"}},
+		{"missing secret file", "", nil, []string{"-jwt_key_file", "MISSING"}, []string{"-jwt_key_file: open"}},
+		{"invalid settings", "", nil, []string{"-db_driver", "oracle", "-jwt_key", "short", "-shutdown_timeout", "0s",
+			"-server_listen_ssl_addr", "3443"}, []string{`db_driver: expected mysql, postgres, sqlite or memory, got "oracle"`,
+			"jwt_key: must be at least 16 characters long", "shutdown_timeout: must be positive, got 0s",
+			`server_listen_ssl_addr: expected host:port, e.g. 0.0.0.0:3000, got "3443"`}},
+	} {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			clearEnv(t)
+			dir := t.TempDir()
+			args := []string{}
+			if test.file != "" {
+				args = append(args, "-config", writeFile(t, dir, "config.json", test.file))
+			}
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			for _, flag := range test.flags {
+				args = append(args, strings.ReplaceAll(flag, "MISSING", filepath.Join(dir, "missing")))
+			}
+
+			_, _, err := Load(args)
+			if err == nil {
+				t.Fatalf("Load: got no error, want one")
+			}
+			for _, want := range test.wantInErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load: got %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
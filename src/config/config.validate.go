@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minJwtKeyLength = 16
+
+var postgresSslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+var logFormats = []string{"json", "text"}
+var cacheBackends = []string{LruCache, RedisCache, NoCache}
+
+// FieldError is a setting with an invalid or missing value
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) addError(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) require(field, value, reason string) bool {
+
+	if value == "" {
+		v.addError(field, "required %s", reason)
+		return false
+	}
+	return true
+}
+
+func (v *validator) checkListenAddr(field, addr string) {
+
+	if !v.require(field, addr, "to listen on") {
+		return
+	}
+	if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+		v.addError(field, "expected host:port, e.g. 0.0.0.0:3000, got %q", addr)
+	}
+}
+
+// Validate checks the settings together and returns a FieldError for every
+// problem found, joined with errors.Join
+func (c *Config) Validate() error {
+
+	v := &validator{}
+
+	switch c.DbDriver {
+	case MySQLDriver, PostgresDriver:
+		reason := "when db_driver is " + c.DbDriver
+		v.require("db_host", c.DbHost, reason)
+		v.require("db_user", c.DbUser, reason)
+		v.require("db_name", c.DbName, reason)
+		if v.require("db_port", c.DbPort, reason) {
+			if port, err := strconv.Atoi(c.DbPort); err != nil || port < 1 || port > 65535 {
+				v.addError("db_port", "expected a port number between 1 and 65535, got %q", c.DbPort)
+			}
+		}
+		if c.DbDriver == PostgresDriver && c.DbSslMode != "" && !contains(postgresSslModes, c.DbSslMode) {
+			v.addError("db_sslmode", "expected one of %v, got %q", postgresSslModes, c.DbSslMode)
+		}
+	case SQLiteDriver:
+		v.require("db_name", c.DbName, "when db_driver is sqlite, it is the path of the database file")
+	case MemoryDriver:
+	default:
+		v.addError("db_driver", "expected %s, %s, %s or %s, got %q",
+			MySQLDriver, PostgresDriver, SQLiteDriver, MemoryDriver, c.DbDriver)
+	}
+
+	v.require("public_images_dir", c.PublicImagesDir, "to store the uploaded images")
+
+	// the Facebook login is optional, but needs all three settings
+	if c.Oauth2FbClientID != "" || c.Oauth2FbClientSecret != "" || c.Oauth2FbRedirectUrl != "" {
+		reason := "for the Facebook login"
+		v.require("oauth2_fb_client_id", c.Oauth2FbClientID, reason)
+		v.require("oauth2_fb_client_secret", c.Oauth2FbClientSecret, reason)
+		if v.require("oauth2_fb_redirect_url", c.Oauth2FbRedirectUrl, reason) {
+			redirectUrl, err := url.Parse(c.Oauth2FbRedirectUrl)
+			if err != nil || !redirectUrl.IsAbs() || redirectUrl.Host == "" {
+				v.addError("oauth2_fb_redirect_url", "expected an absolute url, got %q", c.Oauth2FbRedirectUrl)
+			}
+		}
+	}
+
+	if c.HttpOnly || c.HttpsRedirect {
+		v.checkListenAddr("server_listen_addr", c.ServerListenAddr)
+	}
+	if c.MetricsListenAddr != "" {
+		v.checkListenAddr("metrics_listen_addr", c.MetricsListenAddr)
+	}
+	if c.ShutdownTimeout <= 0 {
+		v.addError("shutdown_timeout", "must be positive, got %s", c.ShutdownTimeout)
+	}
+	if !c.HttpOnly {
+		v.checkListenAddr("server_listen_ssl_addr", c.ServerListenSslAddr)
+		v.require("cert_path", c.CertPath, "to serve https, unless http_only is set")
+		v.require("key_path", c.KeyPath, "to serve https, unless http_only is set")
+	}
+
+	if c.CertMinValidity < 0 {
+		v.addError("cert_min_validity", "must not be negative, got %s", c.CertMinValidity)
+	}
+
+	if !c.HttpOnly && len(c.GetAcmeDomains()) > 0 {
+		reason := "when acme_domains is set"
+		if v.require("acme_directory_url", c.AcmeDirectoryUrl, reason) {
+			directoryUrl, err := url.Parse(c.AcmeDirectoryUrl)
+			if err != nil || directoryUrl.Scheme != "https" || directoryUrl.Host == "" {
+				v.addError("acme_directory_url", "expected an https url, got %q", c.AcmeDirectoryUrl)
+			}
+		}
+		v.require("acme_cache_dir", c.AcmeCacheDir, reason)
+	}
+
+	if v.require("jwt_key", c.JwtKey, "to sign the JSON web tokens") && len(c.JwtKey) < minJwtKeyLength {
+		v.addError("jwt_key", "must be at least %d characters long", minJwtKeyLength)
+	}
+	if c.JwtExpiration <= 0 {
+		v.addError("jwt_expiration", "must be positive, got %s", c.JwtExpiration)
+	}
+	if c.PasswordHashCost < bcrypt.MinCost || c.PasswordHashCost > bcrypt.MaxCost {
+		v.addError("password_hash_cost", "expected a bcrypt cost between %d and %d, got %d",
+			bcrypt.MinCost, bcrypt.MaxCost, c.PasswordHashCost)
+	}
+	if c.LockoutThreshold < 0 {
+		v.addError("lockout_threshold", "must not be negative, got %d", c.LockoutThreshold)
+	}
+	if c.LockoutIpThreshold < 0 {
+		v.addError("lockout_ip_threshold", "must not be negative, got %d", c.LockoutIpThreshold)
+	}
+	if c.LockoutWindow <= 0 {
+		v.addError("lockout_window", "must be positive, got %s", c.LockoutWindow)
+	}
+	if c.LockoutDuration <= 0 {
+		v.addError("lockout_duration", "must be positive, got %s", c.LockoutDuration)
+	}
+
+	if !contains(logLevels, c.LogLevel) {
+		v.addError("log_level", "expected one of %v, got %q", logLevels, c.LogLevel)
+	}
+	if !contains(logFormats, c.LogFormat) {
+		v.addError("log_format", "expected one of %v, got %q", logFormats, c.LogFormat)
+	}
+	if _, err := c.GetRateLimits(); err != nil {
+		v.addError("rate_limits", "%v", err)
+	}
+	if _, err := c.GetTrustedProxies(); err != nil {
+		v.addError("trusted_proxies", "%v", err)
+	}
+	if c.OtlpEndpoint != "" {
+		endpointUrl, err := url.Parse(c.OtlpEndpoint)
+		if err != nil || (endpointUrl.Scheme != "http" && endpointUrl.Scheme != "https") || endpointUrl.Host == "" {
+			v.addError("otlp_endpoint", "expected an http or https url, got %q", c.OtlpEndpoint)
+		}
+	}
+
+	switch c.CacheBackend {
+	case LruCache:
+		if c.CacheSize <= 0 {
+			v.addError("cache_size", "must be positive, got %d", c.CacheSize)
+		}
+	case RedisCache:
+		if _, port, err := net.SplitHostPort(c.CacheRedisAddr); err != nil || port == "" {
+			v.addError("cache_redis_addr", "expected host:port, got %q", c.CacheRedisAddr)
+		}
+	case NoCache:
+	default:
+		v.addError("cache_backend", "expected one of %v, got %q", cacheBackends, c.CacheBackend)
+	}
+	if c.CacheBackend != NoCache && c.CacheTtl < time.Millisecond {
+		v.addError("cache_ttl", "must be at least 1ms, got %s", c.CacheTtl)
+	}
+
+	return errors.Join(v.errs...)
+}
+
+func contains(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,18 +3,24 @@ package cors
 import (
 	"net/http"
 
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/tracing"
+
 	"github.com/julienschmidt/httprouter"
 )
 
 var allowedOrigins = make(map[string]bool)
 
+// the response headers the browsers let the scripts read, besides the simple ones
+const exposedHeaders = "ETag, Link, X-Total-Count, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After"
+
 func setupAllowedOrigins() {
 	allowedOrigins["http://localhost:3000"] = true
 	allowedOrigins["https://localhost:3443"] = true
 	allowedOrigins["http://localhost:4200"] = true
 }
 
-func SetupCors(router *httprouter.Router) {
+func SetupCors(router *misc.Router) {
 
 	setupAllowedOrigins()
 	setupDefaultHttpOptions(router)
@@ -31,11 +37,12 @@ func addAccessControlsToHeader(rHeader http.Header, wHeader http.Header) {
 		}
 	}
 	wHeader.Add("Access-Control-Allow-Credentials", "true")
-	wHeader.Add("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Accept, Origin, Cache-Control, X-Requested-With")
-	wHeader.Add("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	wHeader.Add("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Accept, Origin, Cache-Control, X-Requested-With, If-Match, If-None-Match")
+	wHeader.Add("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	wHeader.Add("Access-Control-Expose-Headers", exposedHeaders)
 }
 
-func setupDefaultHttpOptions(router *httprouter.Router) {
+func setupDefaultHttpOptions(router *misc.Router) {
 	router.GlobalOPTIONS = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		wHeader := w.Header()
@@ -51,6 +58,7 @@ func CorsAllOrigin(next httprouter.Handle) httprouter.Handle {
 
 		wHeader := w.Header()
 		wHeader.Add("Access-Control-Allow-Origin", "*")
+		wHeader.Add("Access-Control-Expose-Headers", exposedHeaders)
 		next(w, r, ps)
 	}
 }
@@ -58,9 +66,12 @@ func CorsAllOrigin(next httprouter.Handle) httprouter.Handle {
 func Cors(next httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
+		ctx, span := tracing.Start(r.Context(), "cors.Cors")
+		defer span.End()
+
 		wHeader := w.Header()
 		addAccessControlsToHeader(r.Header, wHeader)
 
-		next(w, r, ps)
+		next(w, r.WithContext(ctx), ps)
 	}
 }
@@ -1,28 +1,161 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"strings"
 	"time"
 
 	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/tracing"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const dbConfigFile = "../config.json"
+// Conn is a database handle which rewrites every query for its Dialect
+// before handing it to database/sql. Constraint violations reported by Exec
+// and InsertReturningId are turned into misc errors, see classifyError.
+// Every query is traced with a span.
+type Conn struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Tx is a transaction started with Conn.BeginTx
+type Tx struct {
+	*sql.Tx
+	dialect Dialect
+}
 
-var DbConn *sql.DB
+// Queryer is implemented by both *Conn and *Tx, so a query can run on either
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
 
-func SetupDatabase(config config.Config) {
+var DbConn *Conn
 
-	connString := config.GetConnString()
+// SetupDatabase opens DbConn for the db_driver of dbConfig
+func SetupDatabase(dbConfig config.Config) error {
 
-	var err error
-	DbConn, err = sql.Open(config.DbDriver, connString)
+	dialect, ok := GetDialect(dbConfig.DbDriver)
+	if !ok {
+		return fmt.Errorf("Unsupported db_driver %s", dbConfig.DbDriver)
+	}
+
+	connString := dbConfig.GetConnString()
+
+	db, err := sql.Open(dialect.DriverName(), connString)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	db.SetMaxOpenConns(4)
+	db.SetMaxIdleConns(4)
+	db.SetConnMaxLifetime(60 * time.Second)
+
+	DbConn = &Conn{DB: db, Dialect: dialect}
+	return nil
+}
+
+// startQuerySpan starts the span of a query, named after its first keyword, e.g. SELECT.
+// The span records the statement, never its arguments.
+func startQuerySpan(ctx context.Context, dialect Dialect, query string) (context.Context, trace.Span) {
+
+	operation := "SQL"
+	fields := strings.Fields(query)
+	if len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
 	}
+	return tracing.Start(ctx, operation,
+		semconv.DBSystemKey.String(dialect.Name()),
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(strings.Join(fields, " ")))
+}
+
+func endQuerySpan(span trace.Span, err error) {
+
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+
+	query = c.Dialect.Rebind(query)
+	ctx, span := startQuerySpan(ctx, c.Dialect, query)
+	result, err := c.DB.ExecContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return result, classifyError(c.Dialect, err)
+}
+
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+
+	query = c.Dialect.Rebind(query)
+	ctx, span := startQuerySpan(ctx, c.Dialect, query)
+	rows, err := c.DB.QueryContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+// QueryRowContext only measures the query, its error is read by Scan
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+
+	query = c.Dialect.Rebind(query)
+	ctx, span := startQuerySpan(ctx, c.Dialect, query)
+	row := c.DB.QueryRowContext(ctx, query, args...)
+	endQuerySpan(span, row.Err())
+	return row
+}
+
+// InsertReturningId runs an INSERT query and returns the id of the new row
+func (c *Conn) InsertReturningId(ctx context.Context, query string, args ...interface{}) (int64, error) {
+
+	query = c.Dialect.Rebind(query)
+	ctx, span := startQuerySpan(ctx, c.Dialect, query)
+	id, err := c.Dialect.InsertReturningId(ctx, c.DB, query, args...)
+	endQuerySpan(span, err)
+	return id, classifyError(c.Dialect, err)
+}
+
+func (c *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+
+	tx, err := c.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: c.Dialect}, nil
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+
+	query = tx.dialect.Rebind(query)
+	ctx, span := startQuerySpan(ctx, tx.dialect, query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return result, classifyError(tx.dialect, err)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+
+	query = tx.dialect.Rebind(query)
+	ctx, span := startQuerySpan(ctx, tx.dialect, query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 
-	DbConn.SetMaxOpenConns(4)
-	DbConn.SetMaxIdleConns(4)
-	DbConn.SetConnMaxLifetime(60 * time.Second)
+	query = tx.dialect.Rebind(query)
+	ctx, span := startQuerySpan(ctx, tx.dialect, query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	endQuerySpan(span, row.Err())
+	return row
 }
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"confusion.com/bwoo/config"
+)
+
+// Dialect hides the SQL differences between the supported databases.
+// Queries in the *.database.go files are written for MySQL, with ? placeholders,
+// and rewritten by Rebind before they are sent to the database.
+type Dialect interface {
+	// Name is the db_driver value selecting this dialect
+	Name() string
+	// DriverName is the name the database/sql driver is registered with
+	DriverName() string
+	// Rebind rewrites a MySQL style query for this database
+	Rebind(query string) string
+	// InsertReturningId runs an INSERT query and returns the id of the new row
+	InsertReturningId(ctx context.Context, db queryExecer, query string, args ...interface{}) (int64, error)
+	// ForUpdate is appended to a SELECT of a transaction to keep the rows it
+	// reads from changing until the end of the transaction
+	ForUpdate() string
+	// GetConstraintViolation tells which constraint a driver error reports as
+	// broken and on which column, if the driver says so
+	GetConstraintViolation(err error) (ConstraintViolation, string)
+}
+
+// queryExecer is implemented by both *sql.DB and *sql.Tx
+type queryExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func GetDialect(dbDriver string) (Dialect, bool) {
+
+	switch dbDriver {
+	case config.MySQLDriver:
+		return mysqlDialect{}, true
+	case config.SQLiteDriver:
+		return sqliteDialect{}, true
+	case config.PostgresDriver:
+		return postgresDialect{}, true
+	}
+	return nil, false
+}
+
+/****************************
+* MySQL
+****************************/
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return config.MySQLDriver
+}
+
+func (mysqlDialect) DriverName() string {
+	return "mysql"
+}
+
+func (mysqlDialect) Rebind(query string) string {
+	return query
+}
+
+func (mysqlDialect) ForUpdate() string {
+	return " FOR UPDATE"
+}
+
+func (mysqlDialect) GetConstraintViolation(err error) (ConstraintViolation, string) {
+	return getMysqlConstraintViolation(err)
+}
+
+func (mysqlDialect) InsertReturningId(ctx context.Context, db queryExecer, query string, args ...interface{}) (int64, error) {
+	return execReturningLastInsertId(ctx, db, query, args...)
+}
+
+/****************************
+* SQLite
+****************************/
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return config.SQLiteDriver
+}
+
+func (sqliteDialect) DriverName() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) Rebind(query string) string {
+	return query
+}
+
+// SQLite has no row locks, its transactions begin IMMEDIATE instead (see
+// Config.GetConnString), holding the lock of the whole database
+func (sqliteDialect) ForUpdate() string {
+	return ""
+}
+
+func (sqliteDialect) GetConstraintViolation(err error) (ConstraintViolation, string) {
+	return getSqliteConstraintViolation(err)
+}
+
+func (sqliteDialect) InsertReturningId(ctx context.Context, db queryExecer, query string, args ...interface{}) (int64, error) {
+	return execReturningLastInsertId(ctx, db, query, args...)
+}
+
+/****************************
+* PostgreSQL
+****************************/
+type postgresDialect struct{}
+
+// user is a reserved word in PostgreSQL, so the user table has to be quoted
+var userTableRegexp = regexp.MustCompile(`\buser\b`)
+
+func (postgresDialect) Name() string {
+	return config.PostgresDriver
+}
+
+func (postgresDialect) DriverName() string {
+	return "pgx"
+}
+
+// Rebind turns ? placeholders into $1, $2, ... and quotes the user table,
+// leaving the string literals, quoted identifiers and comments as they are
+func (postgresDialect) Rebind(query string) string {
+
+	var sb strings.Builder
+	paramIndex := 0
+	for len(query) > 0 {
+
+		code, quoted, rest := splitQuoted(query)
+		code = userTableRegexp.ReplaceAllString(code, `"user"`)
+		for _, c := range code {
+			if c == '?' {
+				paramIndex++
+				sb.WriteString("$" + strconv.Itoa(paramIndex))
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+		sb.WriteString(quoted)
+		query = rest
+	}
+	return sb.String()
+}
+
+// splitQuoted splits query into the SQL before its first string literal,
+// quoted identifier or comment, that literal, identifier or comment, and
+// the rest of the query. An unterminated one runs to the end of the query.
+func splitQuoted(query string) (string, string, string) {
+
+	start := strings.IndexAny(query, `'"-/`)
+	for start >= 0 {
+
+		var begin, end string
+		switch {
+		case query[start] == '\'' || query[start] == '"':
+			begin, end = query[start:start+1], query[start:start+1]
+		case strings.HasPrefix(query[start:], "--"):
+			begin, end = "--", "\n"
+		case strings.HasPrefix(query[start:], "/*"):
+			begin, end = "/*", "*/"
+		}
+
+		if begin != "" {
+			// a doubled quote within a literal is an escaped quote, which
+			// reads as the end of a literal followed by another one
+			length := strings.Index(query[start+len(begin):], end)
+			if length < 0 {
+				return query[:start], query[start:], ""
+			}
+			stop := start + len(begin) + length + len(end)
+			return query[:start], query[start:stop], query[stop:]
+		}
+
+		next := strings.IndexAny(query[start+1:], `'"-/`)
+		if next < 0 {
+			break
+		}
+		start += 1 + next
+	}
+	return query, "", ""
+}
+
+func (postgresDialect) ForUpdate() string {
+	return " FOR UPDATE"
+}
+
+func (postgresDialect) GetConstraintViolation(err error) (ConstraintViolation, string) {
+	return getPostgresConstraintViolation(err)
+}
+
+// PostgreSQL drivers don't support LastInsertId(), the id is read with RETURNING instead
+func (d postgresDialect) InsertReturningId(ctx context.Context, db queryExecer, query string, args ...interface{}) (int64, error) {
+
+	var id int64
+	err := db.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+func execReturningLastInsertId(ctx context.Context, db queryExecer, query string, args ...interface{}) (int64, error) {
+
+	results, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return results.LastInsertId()
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"confusion.com/bwoo/config"
+)
+
+// openSqlite opens a SQLite database in a temporary file holding a
+// item (id, name UNIQUE) table
+func openSqlite(t *testing.T) *sql.DB {
+
+	t.Helper()
+
+	connString := (&config.Config{DbDriver: config.SQLiteDriver, DbName: filepath.Join(t.TempDir(), "test.db")}).GetConnString()
+	db, err := sql.Open("sqlite", connString)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`CREATE TABLE item (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(10) NOT NULL UNIQUE,
+		parentId INTEGER REFERENCES item(id))`); err != nil {
+		t.Fatalf("CREATE TABLE: %v", err)
+	}
+	return db
+}
+
+func TestGetDialect(t *testing.T) {
+
+	for _, test := range []struct {
+		dbDriver   string
+		wantOk     bool
+		driverName string
+		forUpdate  string
+	}{
+		{config.MySQLDriver, true, "mysql", " FOR UPDATE"},
+		{config.SQLiteDriver, true, "sqlite", ""},
+		{config.PostgresDriver, true, "pgx", " FOR UPDATE"},
+		{"oracle", false, "", ""},
+	} {
+
+		dialect, ok := GetDialect(test.dbDriver)
+		if ok != test.wantOk {
+			t.Errorf("GetDialect(%q): got ok %v, want %v", test.dbDriver, ok, test.wantOk)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if dialect.Name() != test.dbDriver || dialect.DriverName() != test.driverName || dialect.ForUpdate() != test.forUpdate {
+			t.Errorf("GetDialect(%q): got %s, %s, %q, want %s, %s, %q", test.dbDriver,
+				dialect.Name(), dialect.DriverName(), dialect.ForUpdate(), test.dbDriver, test.driverName, test.forUpdate)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+
+	for _, test := range []struct {
+		dbDriver string
+		query    string
+		want     string
+	}{
+		{config.MySQLDriver, "SELECT * FROM user WHERE username = ? AND id > ?", "SELECT * FROM user WHERE username = ? AND id > ?"},
+		{config.SQLiteDriver, "SELECT * FROM user WHERE username = ? AND id > ?", "SELECT * FROM user WHERE username = ? AND id > ?"},
+		{config.PostgresDriver, "SELECT * FROM user WHERE username = ? AND id > ?", `SELECT * FROM "user" WHERE username = $1 AND id > $2`},
+		{config.PostgresDriver, "INSERT INTO dish (name, price) VALUES (?, ?)", "INSERT INTO dish (name, price) VALUES ($1, $2)"},
+		// only the user table is quoted, not the words containing user
+		{config.PostgresDriver, "SELECT userId FROM favorite JOIN user ON user.id = favorite.userId",
+			`SELECT userId FROM favorite JOIN "user" ON "user".id = favorite.userId`},
+		{config.PostgresDriver, "SELECT 1", "SELECT 1"},
+		// the literals, quoted identifiers and comments are left as they are
+		{config.PostgresDriver, "SELECT * FROM user WHERE username = 'user?' AND id > ?",
+			`SELECT * FROM "user" WHERE username = 'user?' AND id > $1`},
+		{config.PostgresDriver, "SELECT 'it''s the user?', ? FROM dish", "SELECT 'it''s the user?', $1 FROM dish"},
+		{config.PostgresDriver, `SELECT "user?" FROM user`, `SELECT "user?" FROM "user"`},
+		{config.PostgresDriver, "SELECT id -- the user?\nFROM user WHERE id = ?", "SELECT id -- the user?\nFROM \"user\" WHERE id = $1"},
+		{config.PostgresDriver, "SELECT id /* the user? */ FROM user WHERE id = ? / 2", `SELECT id /* the user? */ FROM "user" WHERE id = $1 / 2`},
+		{config.PostgresDriver, "SELECT 'user? ", "SELECT 'user? "},
+	} {
+
+		dialect, _ := GetDialect(test.dbDriver)
+		if got := dialect.Rebind(test.query); got != test.want {
+			t.Errorf("%s: Rebind(%q): got %q, want %q", test.dbDriver, test.query, got, test.want)
+		}
+	}
+}
+
+// the dialects read the id with LastInsertId or RETURNING, which SQLite both supports
+func TestInsertReturningId(t *testing.T) {
+
+	ctx := context.Background()
+	for _, dbDriver := range []string{config.MySQLDriver, config.SQLiteDriver, config.PostgresDriver} {
+
+		dialect, _ := GetDialect(dbDriver)
+		db := openSqlite(t)
+
+		for _, want := range []int64{1, 2} {
+			id, err := dialect.InsertReturningId(ctx, db, `INSERT INTO item (name) VALUES (?)`, fmt.Sprintf("%s%d", dbDriver, want))
+			if err != nil {
+				t.Fatalf("%s: InsertReturningId: %v", dbDriver, err)
+			}
+			if id != want {
+				t.Errorf("%s: InsertReturningId: got %d, want %d", dbDriver, id, want)
+			}
+		}
+
+		// within a transaction as well
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("%s: BeginTx: %v", dbDriver, err)
+		}
+		id, err := dialect.InsertReturningId(ctx, tx, `INSERT INTO item (name) VALUES (?)`, dbDriver+"3")
+		if err != nil {
+			t.Fatalf("%s: InsertReturningId in a transaction: %v", dbDriver, err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("%s: Commit: %v", dbDriver, err)
+		}
+		if id != 3 {
+			t.Errorf("%s: InsertReturningId in a transaction: got %d, want 3", dbDriver, id)
+		}
+
+		if _, err := dialect.InsertReturningId(ctx, db, `INSERT INTO item (name) VALUES (?)`, dbDriver+"3"); err == nil {
+			t.Errorf("%s: InsertReturningId of a duplicate: got no error, want one", dbDriver)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"confusion.com/bwoo/misc"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ConstraintViolation is the kind of constraint a driver error reports as broken
+type ConstraintViolation int
+
+const (
+	NoViolation ConstraintViolation = iota
+	UniqueViolation
+	ForeignKeyViolation
+	DataTooLongViolation
+)
+
+// MySQL error numbers, see https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+const (
+	mysqlErrDupEntry         = 1062
+	mysqlErrDataTooLong      = 1406
+	mysqlErrNoReferencedRow  = 1216
+	mysqlErrNoReferencedRow2 = 1452
+)
+
+// PostgreSQL SQLSTATE codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	postgresUniqueViolation           = "23505"
+	postgresForeignKeyViolation       = "23503"
+	postgresStringDataRightTruncation = "22001"
+)
+
+var mysqlDupEntryRegexp = regexp.MustCompile(`for key '(?:\w+\.)?(\w+)'`)
+var mysqlForeignKeyRegexp = regexp.MustCompile("FOREIGN KEY \\(`(\\w+)`\\)")
+var mysqlDataTooLongRegexp = regexp.MustCompile(`for column '(\w+)'`)
+var postgresKeyRegexp = regexp.MustCompile(`Key \(([^),]+)`)
+var sqliteConstraintRegexp = regexp.MustCompile(`constraint failed: \w+\.(\w+)`)
+
+func getMysqlConstraintViolation(err error) (ConstraintViolation, string) {
+
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return NoViolation, ""
+	}
+
+	switch mysqlErr.Number {
+	case mysqlErrDupEntry:
+		return UniqueViolation, getFirstSubmatch(mysqlDupEntryRegexp, mysqlErr.Message)
+	case mysqlErrNoReferencedRow, mysqlErrNoReferencedRow2:
+		return ForeignKeyViolation, getFirstSubmatch(mysqlForeignKeyRegexp, mysqlErr.Message)
+	case mysqlErrDataTooLong:
+		return DataTooLongViolation, getFirstSubmatch(mysqlDataTooLongRegexp, mysqlErr.Message)
+	}
+	return NoViolation, ""
+}
+
+// SQLite does not enforce the length of VARCHAR columns, so it never reports data too long
+func getSqliteConstraintViolation(err error) (ConstraintViolation, string) {
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return NoViolation, ""
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return UniqueViolation, getFirstSubmatch(sqliteConstraintRegexp, sqliteErr.Error())
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		// SQLite does not tell which foreign key failed
+		return ForeignKeyViolation, ""
+	}
+	return NoViolation, ""
+}
+
+func getPostgresConstraintViolation(err error) (ConstraintViolation, string) {
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return NoViolation, ""
+	}
+
+	column := strings.Trim(getFirstSubmatch(postgresKeyRegexp, pgErr.Detail), `"`)
+	switch pgErr.Code {
+	case postgresUniqueViolation:
+		return UniqueViolation, column
+	case postgresForeignKeyViolation:
+		return ForeignKeyViolation, column
+	case postgresStringDataRightTruncation:
+		return DataTooLongViolation, pgErr.ColumnName
+	}
+	return NoViolation, ""
+}
+
+func getFirstSubmatch(re *regexp.Regexp, s string) string {
+
+	match := re.FindStringSubmatch(s)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// classifyError turns the constraint violations reported by the driver into errors
+// the handlers can reply with: a duplicate key is a conflict, a reference to a
+// missing row is an invalid reference and a value too long for its column is a bad request.
+// Other errors are returned unchanged.
+func classifyError(dialect Dialect, err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	var code, message, fieldMessage string
+	violation, column := dialect.GetConstraintViolation(err)
+	switch violation {
+	case UniqueViolation:
+		code, message, fieldMessage = misc.ErrorCodeConflict, "Duplicate value", "Already exists"
+	case ForeignKeyViolation:
+		code, message, fieldMessage = misc.ErrorCodeInvalidReference, "Reference to a missing row", "Does not exist"
+	case DataTooLongViolation:
+		code, message, fieldMessage = misc.ErrorCodeBadRequest, "Value too long", "Too long"
+	default:
+		return err
+	}
+
+	// not every driver tells which column broke the constraint
+	if column == "" {
+		return misc.NewError(code, message)
+	}
+	return misc.NewError(code, message+" for "+column, misc.FieldError{Field: column, Message: fieldMessage})
+}
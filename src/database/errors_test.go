@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/misc"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// getSqliteErrors returns the errors SQLite reports when a unique and a
+// foreign key constraint break
+func getSqliteErrors(t *testing.T) (error, error) {
+
+	t.Helper()
+
+	db := openSqlite(t)
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `INSERT INTO item (name) VALUES ('salad')`); err != nil {
+		t.Fatalf("INSERT: %v", err)
+	}
+	_, uniqueErr := db.ExecContext(ctx, `INSERT INTO item (name) VALUES ('salad')`)
+	_, foreignKeyErr := db.ExecContext(ctx, `INSERT INTO item (name, parentId) VALUES ('soup', 99)`)
+	if uniqueErr == nil || foreignKeyErr == nil {
+		t.Fatalf("INSERT: got %v, %v, want constraint violations", uniqueErr, foreignKeyErr)
+	}
+	return uniqueErr, foreignKeyErr
+}
+
+func TestClassifyError(t *testing.T) {
+
+	sqliteUniqueErr, sqliteForeignKeyErr := getSqliteErrors(t)
+	otherErr := errors.New("connection refused")
+
+	for _, test := range []struct {
+		name     string
+		dbDriver string
+		err      error
+		wantCode string
+		field    string
+	}{
+		{"mysql duplicate", config.MySQLDriver,
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'salad' for key 'dish.name'"}, misc.ErrorCodeConflict, "name"},
+		{"mysql foreign key", config.MySQLDriver, &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: " +
+			"a foreign key constraint fails (`confusion`.`comment`, CONSTRAINT `comment_ibfk_1` FOREIGN KEY (`dishId`) REFERENCES `dish` (`id`))"},
+			misc.ErrorCodeInvalidReference, "dishId"},
+		{"mysql too long", config.MySQLDriver,
+			&mysql.MySQLError{Number: 1406, Message: "Data too long for column 'name' at row 1"}, misc.ErrorCodeBadRequest, "name"},
+		{"mysql wrapped", config.MySQLDriver,
+			fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'salad' for key 'name'"}), misc.ErrorCodeConflict, "name"},
+		{"mysql other", config.MySQLDriver, &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}, "", ""},
+		{"postgres duplicate", config.PostgresDriver,
+			&pgconn.PgError{Code: "23505", Detail: "Key (name)=(salad) already exists."}, misc.ErrorCodeConflict, "name"},
+		{"postgres foreign key", config.PostgresDriver,
+			&pgconn.PgError{Code: "23503", Detail: `Key ("dishId")=(99) is not present in table "dish".`}, misc.ErrorCodeInvalidReference, "dishId"},
+		{"postgres too long", config.PostgresDriver,
+			&pgconn.PgError{Code: "22001", Message: "value too long for type character varying(10)"}, misc.ErrorCodeBadRequest, ""},
+		{"postgres other", config.PostgresDriver, &pgconn.PgError{Code: "42601"}, "", ""},
+		{"sqlite unique", config.SQLiteDriver, sqliteUniqueErr, misc.ErrorCodeConflict, "name"},
+		// SQLite does not tell which foreign key failed
+		{"sqlite foreign key", config.SQLiteDriver, sqliteForeignKeyErr, misc.ErrorCodeInvalidReference, ""},
+		// the error of another driver is not a violation
+		{"sqlite mysql error", config.SQLiteDriver, &mysql.MySQLError{Number: 1062}, "", ""},
+		{"not a driver error", config.PostgresDriver, otherErr, "", ""},
+	} {
+
+		dialect, _ := GetDialect(test.dbDriver)
+		err := classifyError(dialect, test.err)
+		if test.wantCode == "" {
+			if err != test.err {
+				t.Errorf("%s: got %v, want the error unchanged", test.name, err)
+			}
+			continue
+		}
+
+		var miscErr *misc.Error
+		if !errors.As(err, &miscErr) || miscErr.Code != test.wantCode {
+			t.Errorf("%s: got %v, want a %s error", test.name, err, test.wantCode)
+			continue
+		}
+		if test.field == "" {
+			if len(miscErr.Details) != 0 {
+				t.Errorf("%s: got details %+v, want none", test.name, miscErr.Details)
+			}
+			continue
+		}
+		if len(miscErr.Details) != 1 || miscErr.Details[0].Field != test.field {
+			t.Errorf("%s: got details %+v, want one for %s", test.name, miscErr.Details, test.field)
+		}
+	}
+
+	if err := classifyError(mysqlDialect{}, nil); err != nil {
+		t.Errorf("nil: got %v, want nil", err)
+	}
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"confusion.com/bwoo/misc"
+)
+
+// the layouts the drivers return the timestamps in as text: MySQL as
+// misc.TimestampFormat, SQLite as CURRENT_TIMESTAMP stores them or as
+// database/sql writes a time.Time
+var timestampLayouts = []string{
+	misc.TimestampFormat,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+}
+
+// timestamp is a Scan destination reading a TIMESTAMP column as a string in
+// misc.TimestampFormat, in UTC. The drivers return the timestamps as a
+// time.Time (SQLite and PostgreSQL) or as text (MySQL), so the models would
+// get a different format for each db_driver otherwise.
+type timestamp struct {
+	set func(value *string)
+}
+
+// ScanTimestamp returns the Scan destination of a NOT NULL timestamp column
+func ScanTimestamp(dest *string) sql.Scanner {
+	return &timestamp{set: func(value *string) {
+		*dest = ""
+		if value != nil {
+			*dest = *value
+		}
+	}}
+}
+
+// ScanNullTimestamp returns the Scan destination of a timestamp column, a NULL sets *dest to nil
+func ScanNullTimestamp(dest **string) sql.Scanner {
+	return &timestamp{set: func(value *string) { *dest = value }}
+}
+
+func (t *timestamp) Scan(src interface{}) error {
+
+	var parsed time.Time
+	switch src := src.(type) {
+	case nil:
+		t.set(nil)
+		return nil
+	case time.Time:
+		parsed = src
+	case []byte:
+		var err error
+		if parsed, err = parseTimestamp(string(src)); err != nil {
+			return err
+		}
+	case string:
+		var err error
+		if parsed, err = parseTimestamp(src); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Cannot scan a %T into a timestamp", src)
+	}
+
+	value := parsed.UTC().Format(misc.TimestampFormat)
+	t.set(&value)
+	return nil
+}
+
+func parseTimestamp(text string) (time.Time, error) {
+
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.ParseInLocation(layout, text, time.UTC); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid timestamp %s", text)
+}
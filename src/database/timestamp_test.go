@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScanTimestamp(t *testing.T) {
+
+	for _, test := range []struct {
+		name string
+		src  interface{}
+		want string
+	}{
+		{"mysql", []byte("2026-10-16 22:12:35"), "2026-10-16 22:12:35"},
+		{"sqlite", time.Date(2026, 10, 16, 22, 12, 35, 0, time.UTC), "2026-10-16 22:12:35"},
+		{"postgres", time.Date(2026, 10, 17, 0, 12, 35, 500, time.FixedZone("CEST", 2*60*60)), "2026-10-16 22:12:35"},
+		{"sqlite text", "2026-10-16 22:12:35.5+00:00", "2026-10-16 22:12:35"},
+		{"rfc3339", "2026-10-16T22:12:35Z", "2026-10-16 22:12:35"},
+	} {
+
+		var value string
+		if err := ScanTimestamp(&value).Scan(test.src); err != nil {
+			t.Errorf("%s: Scan(%v): %v", test.name, test.src, err)
+			continue
+		}
+		if value != test.want {
+			t.Errorf("%s: Scan(%v): got %q, want %q", test.name, test.src, value, test.want)
+		}
+	}
+}
+
+func TestScanNullTimestamp(t *testing.T) {
+
+	value := new(string)
+	if err := ScanNullTimestamp(&value).Scan(nil); err != nil || value != nil {
+		t.Errorf("Scan(nil): got %v, %v, want nil", value, err)
+	}
+
+	if err := ScanNullTimestamp(&value).Scan("yesterday"); err == nil {
+		t.Errorf("Scan(yesterday): got %v, want an error", *value)
+	}
+}
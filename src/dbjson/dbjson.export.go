@@ -0,0 +1,108 @@
+package dbjson
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"confusion.com/bwoo/listing"
+)
+
+// ExportOptions picks the optional parts of an export
+type ExportOptions struct {
+	Users     bool
+	Favorites bool
+}
+
+// Export reads the whole database through the stores into a Document
+func Export(ctx context.Context, stores Stores, options ExportOptions) (Document, error) {
+
+	doc := Document{Feedback: make([]json.RawMessage, 0)}
+
+	dishList, _, err := stores.Dishes.List(ctx, listing.Params{})
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc.Dishes = make([]DishDocument, 0, len(dishList))
+	for _, dish := range dishList {
+
+		commentList, _, err := stores.Comments.List(ctx, dish.ID, listing.Params{})
+		if err != nil {
+			return Document{}, err
+		}
+
+		dishDoc := DishDocument{Dish: dish, Comments: make([]CommentDocument, 0, len(commentList))}
+		for _, comment := range commentList {
+			commentDoc := CommentDocument{Rating: comment.Rating, Comment: comment.Comment, Date: comment.Date}
+			if comment.Author != nil {
+				commentDoc.Author = strings.TrimSpace(comment.Author.Firstname + " " + comment.Author.Lastname)
+			}
+			dishDoc.Comments = append(dishDoc.Comments, commentDoc)
+		}
+		doc.Dishes = append(doc.Dishes, dishDoc)
+	}
+
+	if doc.Promotions, _, err = stores.Promotions.List(ctx, listing.Params{}); err != nil {
+		return Document{}, err
+	}
+
+	if doc.Leaders, _, err = stores.Leaders.List(ctx, listing.Params{}); err != nil {
+		return Document{}, err
+	}
+
+	if options.Users || options.Favorites {
+		if err := exportUsersAndFavorites(ctx, stores, options, &doc); err != nil {
+			return Document{}, err
+		}
+	}
+
+	return doc, nil
+}
+
+func exportUsersAndFavorites(ctx context.Context, stores Stores, options ExportOptions, doc *Document) error {
+
+	users, err := stores.Users.GetUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+
+		if options.Users {
+			doc.Users = append(doc.Users, UserDocument{Username: user.Username,
+				Firstname: user.Firstname, Lastname: user.Lastname, Admin: user.Admin})
+		}
+
+		if !options.Favorites {
+			continue
+		}
+
+		favDishes, err := stores.Favorites.List(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if len(favDishes) == 0 {
+			continue
+		}
+
+		favorite := FavoriteDocument{User: user.Username, Dishes: make([]string, 0, len(favDishes))}
+		for _, dish := range favDishes {
+			if dish.Name != nil {
+				favorite.Dishes = append(favorite.Dishes, *dish.Name)
+			}
+		}
+		doc.Favorites = append(doc.Favorites, favorite)
+	}
+
+	return nil
+}
+
+// WriteDocument writes doc to w, indented like db.json
+func WriteDocument(w io.Writer, doc Document) error {
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,127 @@
+package dbjson
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/favoriteDishes"
+)
+
+func str(s *string) string {
+
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
+
+// describe lists the content of doc without the ids and the dates of the
+// rows, which differ from one database to the next, the comment dates as
+// the stores keep them
+func describe(t *testing.T, doc Document) []string {
+
+	t.Helper()
+
+	lines := make([]string, 0)
+	for _, dish := range doc.Dishes {
+		lines = append(lines, fmt.Sprintf("dish %s %s %s %s %s", str(dish.Name), str(dish.Category), str(dish.Price),
+			str(dish.Featured), str(dish.Description)))
+		for _, comment := range dish.Comments {
+			date, err := getCommentDate(comment.Date)
+			if err != nil {
+				t.Fatalf("getCommentDate: %v", err)
+			}
+			lines = append(lines, fmt.Sprintf("comment %s by %s: %d %s at %s", str(dish.Name), comment.Author,
+				*comment.Rating, str(comment.Comment), str(date)))
+		}
+	}
+	for _, promotion := range doc.Promotions {
+		lines = append(lines, fmt.Sprintf("promotion %s %s", str(promotion.Name), str(promotion.Price)))
+	}
+	for _, leader := range doc.Leaders {
+		lines = append(lines, fmt.Sprintf("leader %s %s", str(leader.Name), str(leader.Designation)))
+	}
+	return lines
+}
+
+// db.json goes through the stores unchanged, and so does an export
+func TestExportImport(t *testing.T) {
+
+	ctx := context.Background()
+	doc := readDbJson(t)
+	stores := newTestStores()
+	if _, err := Import(ctx, doc, stores); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	exported, err := Export(ctx, stores, ExportOptions{})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if got, want := describe(t, exported), describe(t, doc); !slices.Equal(got, want) {
+		t.Errorf("Export(Import(db.json)): got %q, want %q", got, want)
+	}
+	if exported.Users != nil || exported.Favorites != nil {
+		t.Errorf("Export: got users %v and favorites %v, want none", exported.Users, exported.Favorites)
+	}
+
+	// an export imports into other stores as db.json does
+	otherStores := newTestStores()
+	if _, err := Import(ctx, exported, otherStores); err != nil {
+		t.Fatalf("Import(Export()): %v", err)
+	}
+	reexported, err := Export(ctx, otherStores, ExportOptions{})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if got, want := describe(t, reexported), describe(t, exported); !slices.Equal(got, want) {
+		t.Errorf("Export(Import(Export())): got %q, want %q", got, want)
+	}
+}
+
+// the users and the favorites are exported for reading only, Import leaves them out
+func TestExportUsersAndFavorites(t *testing.T) {
+
+	ctx := context.Background()
+	stores := newTestStores()
+	stores.Favorites = favoriteDishes.NewMemoryStore(stores.Dishes)
+	if _, err := Import(ctx, readDbJson(t), stores); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	admin := auth.UserInfo{Firstname: "Ada", Lastname: "Admin"}
+	admin.Username = "admin"
+	adminId, err := auth.CreateMemoryAdmin(ctx, stores.Users, admin, []byte("hash"))
+	if err != nil {
+		t.Fatalf("CreateMemoryAdmin: %v", err)
+	}
+	if _, err := stores.Favorites.CreateMany(ctx, adminId, []int64{1, 3}); err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+
+	exported, err := Export(ctx, stores, ExportOptions{Users: true, Favorites: true})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(exported.Users) != 6 || exported.Users[5] != (UserDocument{Username: "admin", Firstname: "Ada", Lastname: "Admin", Admin: true}) {
+		t.Errorf("Export: got users %+v, want the 5 authors and the admin", exported.Users)
+	}
+	if len(exported.Favorites) != 1 || exported.Favorites[0].User != "admin" || len(exported.Favorites[0].Dishes) != 2 {
+		t.Errorf("Export: got favorites %+v, want the 2 of the admin", exported.Favorites)
+	}
+
+	otherStores := newTestStores()
+	summary, err := Import(ctx, exported, otherStores)
+	if err != nil {
+		t.Fatalf("Import(Export()): %v", err)
+	}
+	if summary.Users != (Counts{Created: 5}) {
+		t.Errorf("Import(Export()): got users %+v, want the 5 comment authors", summary.Users)
+	}
+	if user, _, _ := otherStores.Users.GetUserByUsername(ctx, "admin"); user != nil {
+		t.Errorf("Import(Export()): got the admin %+v, want none", user)
+	}
+}
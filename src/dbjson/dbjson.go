@@ -0,0 +1,64 @@
+package dbjson
+
+import (
+	"encoding/json"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/comments"
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/favoriteDishes"
+	"confusion.com/bwoo/leaders"
+	"confusion.com/bwoo/promotions"
+)
+
+// Document is the layout of db.json. Users and Favorites are only filled
+// in by an export asking for them, and are export-only: Import leaves them
+// out, as the users come without their password hashes and admins are never
+// created from a file.
+type Document struct {
+	Dishes     []DishDocument         `json:"dishes"`
+	Promotions []promotions.Promotion `json:"promotions"`
+	Leaders    []leaders.Leader       `json:"leaders"`
+	Feedback   []json.RawMessage      `json:"feedback"`
+	Users      []UserDocument         `json:"users,omitempty"`
+	Favorites  []FavoriteDocument     `json:"favorites,omitempty"`
+}
+
+// DishDocument is a dish with its comments nested, the comment author
+// being the author's full name
+type DishDocument struct {
+	dishes.Dish
+	Comments []CommentDocument `json:"comments"`
+}
+
+type CommentDocument struct {
+	Rating  *int    `json:"rating"`
+	Comment *string `json:"comment"`
+	Author  string  `json:"author"`
+	Date    *string `json:"date"`
+}
+
+// UserDocument is a user without its password hash
+type UserDocument struct {
+	Username  string `json:"username"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Admin     bool   `json:"admin"`
+}
+
+// FavoriteDocument lists the favorite dishes of a user by dish name,
+// as ids differ from one database to the next
+type FavoriteDocument struct {
+	User   string   `json:"user"`
+	Dishes []string `json:"dishes"`
+}
+
+// Stores are the stores db.json is read from and written to
+type Stores struct {
+	Dishes     dishes.DishStore
+	Comments   comments.CommentStore
+	Leaders    leaders.LeaderStore
+	Promotions promotions.PromotionStore
+	Users      auth.UserStore
+	Favorites  favoriteDishes.FavoriteDishStore
+}
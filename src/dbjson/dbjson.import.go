@@ -0,0 +1,340 @@
+package dbjson
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/comments"
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/misc"
+)
+
+// maximum length of user.username
+const maxUsernameLength = 15
+
+type Counts struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+}
+
+// ImportSummary tells how many rows Import created and how many already existed
+type ImportSummary struct {
+	Dishes     Counts `json:"dishes"`
+	Comments   Counts `json:"comments"`
+	Promotions Counts `json:"promotions"`
+	Leaders    Counts `json:"leaders"`
+	Users      Counts `json:"users"`
+}
+
+func ReadDocumentFile(path string) (Document, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Document{}, err
+	}
+	defer file.Close()
+
+	var doc Document
+	err = json.NewDecoder(file).Decode(&doc)
+	if err != nil {
+		return Document{}, fmt.Errorf("Error decoding %s: %v", path, err)
+	}
+
+	return doc, nil
+}
+
+// Import creates the rows of doc through the stores. Rows that already exist
+// are skipped, so importing the same document twice is harmless:
+// dishes, promotions and leaders are matched by name, comments by dish,
+// author and text. Comment authors get a placeholder user without a password.
+// doc.Users and doc.Favorites are ignored, see Document.
+func Import(ctx context.Context, doc Document, stores Stores) (ImportSummary, error) {
+
+	imp := &importer{stores: stores, users: make(map[string]*auth.UserInfo)}
+
+	if err := imp.importDishes(ctx, doc.Dishes); err != nil {
+		return imp.summary, err
+	}
+
+	if err := imp.importPromotions(ctx, doc); err != nil {
+		return imp.summary, err
+	}
+
+	if err := imp.importLeaders(ctx, doc); err != nil {
+		return imp.summary, err
+	}
+
+	return imp.summary, nil
+}
+
+type importer struct {
+	stores  Stores
+	summary ImportSummary
+	// comment authors by username, so each one is looked up once
+	users map[string]*auth.UserInfo
+}
+
+// featuredOrDefault makes sure the featured flag is set, the stores expect one
+func featuredOrDefault(featured *string) *string {
+
+	if featured == nil {
+		notFeatured := "false"
+		return &notFeatured
+	}
+	return featured
+}
+
+func getDishIdsByName(ctx context.Context, dishStore dishes.DishStore) (map[string]int64, error) {
+
+	existingDishes, _, err := dishStore.List(ctx, listing.Params{})
+	if err != nil {
+		return nil, err
+	}
+
+	dishIds := make(map[string]int64)
+	for _, dish := range existingDishes {
+		dishIds[*dish.Name] = dish.ID
+	}
+	return dishIds, nil
+}
+
+func (imp *importer) importDishes(ctx context.Context, dishDocs []DishDocument) error {
+
+	dishIds, err := getDishIdsByName(ctx, imp.stores.Dishes)
+	if err != nil {
+		return err
+	}
+
+	for _, dishDoc := range dishDocs {
+
+		if dishDoc.Name == nil {
+			return fmt.Errorf("Dish without a name")
+		}
+
+		dishId, ok := dishIds[*dishDoc.Name]
+		if ok {
+			imp.summary.Dishes.Skipped++
+		} else {
+			dish := dishDoc.Dish
+			dish.Featured = featuredOrDefault(dish.Featured)
+			status, err := imp.stores.Dishes.Create(ctx, dish)
+			if err != nil {
+				return fmt.Errorf("Error creating dish %s: %v", *dish.Name, err)
+			}
+			imp.summary.Dishes.Created++
+
+			// so a dish named twice in the document is created once
+			dishId = status.ID
+			dishIds[*dishDoc.Name] = dishId
+		}
+
+		if err := imp.importComments(ctx, dishId, dishDoc.Comments); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isSameComment(existing comments.Comment, author *auth.UserInfo, commentDoc CommentDocument) bool {
+
+	if existing.Author == nil || existing.Author.Firstname != author.Firstname ||
+		existing.Author.Lastname != author.Lastname {
+		return false
+	}
+
+	if (existing.Comment == nil) != (commentDoc.Comment == nil) {
+		return false
+	}
+	return existing.Comment == nil || *existing.Comment == *commentDoc.Comment
+}
+
+func (imp *importer) importComments(ctx context.Context, dishId int64, commentDocs []CommentDocument) error {
+
+	existingComments, _, err := imp.stores.Comments.List(ctx, dishId, listing.Params{})
+	if err != nil {
+		return err
+	}
+
+	for _, commentDoc := range commentDocs {
+
+		author, err := imp.findOrCreatePlaceholderUser(ctx, commentDoc.Author)
+		if err != nil {
+			return err
+		}
+
+		alreadyImported := false
+		for _, existing := range existingComments {
+			if isSameComment(existing, author, commentDoc) {
+				alreadyImported = true
+				break
+			}
+		}
+		if alreadyImported {
+			imp.summary.Comments.Skipped++
+			continue
+		}
+
+		date, err := getCommentDate(commentDoc.Date)
+		if err != nil {
+			return fmt.Errorf("Error reading the date of the comment by %s: %v", commentDoc.Author, err)
+		}
+
+		comment := comments.Comment{Rating: commentDoc.Rating, Comment: commentDoc.Comment, Date: date}
+		if _, err := imp.stores.Comments.Create(ctx, dishId, author.ID, comment); err != nil {
+			return fmt.Errorf("Error creating comment by %s: %v", commentDoc.Author, err)
+		}
+		imp.summary.Comments.Created++
+	}
+
+	return nil
+}
+
+// getCommentDate turns the date of a comment document into the UTC timestamp
+// the stores keep. It is either RFC 3339, like "2012-10-16T17:57:28.556094Z"
+// in db.json, or a timestamp as Export writes it. A comment without a date
+// is dated when it is imported.
+func getCommentDate(date *string) (*string, error) {
+
+	if date == nil {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, *date)
+	if err != nil {
+		if parsed, err = time.ParseInLocation(misc.TimestampFormat, *date, time.UTC); err != nil {
+			return nil, fmt.Errorf("Invalid date %s", *date)
+		}
+	}
+
+	timestamp := parsed.UTC().Format(misc.TimestampFormat)
+	return &timestamp, nil
+}
+
+// getPlaceholderUsername turns a full name like "John Lemon" into "johnlemon"
+func getPlaceholderUsername(fullName string) string {
+
+	var sb strings.Builder
+	for _, c := range strings.ToLower(fullName) {
+		if c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)) && sb.Len() < maxUsernameLength {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+func (imp *importer) findOrCreatePlaceholderUser(ctx context.Context, fullName string) (*auth.UserInfo, error) {
+
+	username := getPlaceholderUsername(fullName)
+	if username == "" {
+		return nil, fmt.Errorf("Cannot derive a username from author %q", fullName)
+	}
+
+	if user, ok := imp.users[username]; ok {
+		return user, nil
+	}
+
+	user, _, err := imp.stores.Users.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		imp.users[username] = user
+		imp.summary.Users.Skipped++
+		return user, nil
+	}
+
+	var newUser auth.UserInfo
+	names := strings.SplitN(strings.TrimSpace(fullName), " ", 2)
+	newUser.Firstname = names[0]
+	if len(names) > 1 {
+		newUser.Lastname = names[1]
+	}
+	newUser.Username = username
+
+	// placeholder users have no password, so nobody can log in as them
+	if _, err := imp.stores.Users.CreateUser(ctx, newUser, nil); err != nil {
+		return nil, fmt.Errorf("Error creating user %s: %v", username, err)
+	}
+	imp.summary.Users.Created++
+
+	user, _, err = imp.stores.Users.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	imp.users[username] = user
+	return user, nil
+}
+
+func (imp *importer) importPromotions(ctx context.Context, doc Document) error {
+
+	existingPromotions, _, err := imp.stores.Promotions.List(ctx, listing.Params{})
+	if err != nil {
+		return err
+	}
+
+	existingNames := make(map[string]bool)
+	for _, promotion := range existingPromotions {
+		existingNames[*promotion.Name] = true
+	}
+
+	for _, promotion := range doc.Promotions {
+
+		if promotion.Name == nil {
+			return fmt.Errorf("Promotion without a name")
+		}
+		if existingNames[*promotion.Name] {
+			imp.summary.Promotions.Skipped++
+			continue
+		}
+
+		promotion.Featured = featuredOrDefault(promotion.Featured)
+		if _, err := imp.stores.Promotions.Create(ctx, promotion); err != nil {
+			return fmt.Errorf("Error creating promotion %s: %v", *promotion.Name, err)
+		}
+		existingNames[*promotion.Name] = true
+		imp.summary.Promotions.Created++
+	}
+
+	return nil
+}
+
+func (imp *importer) importLeaders(ctx context.Context, doc Document) error {
+
+	existingLeaders, _, err := imp.stores.Leaders.List(ctx, listing.Params{})
+	if err != nil {
+		return err
+	}
+
+	existingNames := make(map[string]bool)
+	for _, leader := range existingLeaders {
+		existingNames[*leader.Name] = true
+	}
+
+	for _, leader := range doc.Leaders {
+
+		if leader.Name == nil {
+			return fmt.Errorf("Leader without a name")
+		}
+		if existingNames[*leader.Name] {
+			imp.summary.Leaders.Skipped++
+			continue
+		}
+
+		leader.Featured = featuredOrDefault(leader.Featured)
+		if _, err := imp.stores.Leaders.Create(ctx, leader); err != nil {
+			return fmt.Errorf("Error creating leader %s: %v", *leader.Name, err)
+		}
+		existingNames[*leader.Name] = true
+		imp.summary.Leaders.Created++
+	}
+
+	return nil
+}
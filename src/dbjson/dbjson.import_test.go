@@ -0,0 +1,229 @@
+package dbjson
+
+import (
+	"context"
+	"testing"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/comments"
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/leaders"
+	"confusion.com/bwoo/promotions"
+)
+
+const dbJsonPath = "../../db.json"
+
+func newTestStores() Stores {
+
+	users := auth.NewMemoryStore()
+	return Stores{
+		Dishes:     dishes.NewMemoryStore(),
+		Comments:   comments.NewMemoryStore(users),
+		Leaders:    leaders.NewMemoryStore(),
+		Promotions: promotions.NewMemoryStore(),
+		Users:      users,
+	}
+}
+
+func readDbJson(t *testing.T) Document {
+
+	t.Helper()
+
+	doc, err := ReadDocumentFile(dbJsonPath)
+	if err != nil {
+		t.Fatalf("ReadDocumentFile: %v", err)
+	}
+	return doc
+}
+
+func countComments(doc Document) int {
+
+	count := 0
+	for _, dish := range doc.Dishes {
+		count += len(dish.Comments)
+	}
+	return count
+}
+
+// importing a document again skips every row
+func TestImportTwice(t *testing.T) {
+
+	ctx := context.Background()
+	doc := readDbJson(t)
+	stores := newTestStores()
+
+	numComments := countComments(doc)
+	for _, test := range []struct {
+		name string
+		want ImportSummary
+	}{
+		{"first import", ImportSummary{
+			Dishes:     Counts{Created: len(doc.Dishes)},
+			Comments:   Counts{Created: numComments},
+			Promotions: Counts{Created: len(doc.Promotions)},
+			Leaders:    Counts{Created: len(doc.Leaders)},
+			Users:      Counts{Created: 5},
+		}},
+		{"second import", ImportSummary{
+			Dishes:     Counts{Skipped: len(doc.Dishes)},
+			Comments:   Counts{Skipped: numComments},
+			Promotions: Counts{Skipped: len(doc.Promotions)},
+			Leaders:    Counts{Skipped: len(doc.Leaders)},
+			Users:      Counts{Skipped: 5},
+		}},
+	} {
+
+		summary, err := Import(ctx, doc, stores)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if summary != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.name, summary, test.want)
+		}
+	}
+}
+
+// every comment author gets a user without a password, which nobody logs in as
+func TestImportPlaceholderUsers(t *testing.T) {
+
+	ctx := context.Background()
+	stores := newTestStores()
+
+	// an existing user of the same username writes the comments
+	existing := auth.UserInfo{Firstname: "Ringo", Lastname: "Starry"}
+	existing.Username = "ringostarry"
+	existingId, err := stores.Users.CreateUser(ctx, existing, []byte("hash"))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	summary, err := Import(ctx, readDbJson(t), stores)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if summary.Users != (Counts{Created: 4, Skipped: 1}) {
+		t.Errorf("Import: got users %+v, want 4 created and 1 skipped", summary.Users)
+	}
+
+	for _, test := range []struct {
+		username            string
+		firstname, lastname string
+		wantHash            bool
+	}{
+		{"johnlemon", "John", "Lemon", false},
+		{"paulmcvites", "Paul", "McVites", false},
+		{"michaeljaikisha", "Michael", "Jaikishan", false},
+		{"25cent", "25", "Cent", false},
+		{"ringostarry", "Ringo", "Starry", true},
+	} {
+
+		user, hash, err := stores.Users.GetUserByUsername(ctx, test.username)
+		if err != nil || user == nil {
+			t.Errorf("%s: got %v, %v, want the user", test.username, user, err)
+			continue
+		}
+		if user.Firstname != test.firstname || user.Lastname != test.lastname || user.Admin || (hash != "") != test.wantHash {
+			t.Errorf("%s: got %+v with hash %q, want %s %s", test.username, user, hash, test.firstname, test.lastname)
+		}
+		if test.wantHash && user.ID != existingId {
+			t.Errorf("%s: got user %d, want the existing user %d", test.username, user.ID, existingId)
+		}
+	}
+
+	// a name without a letter or a digit has no username
+	doc := Document{Dishes: []DishDocument{readDbJson(t).Dishes[0]}}
+	doc.Dishes[0].Comments = []CommentDocument{{Rating: doc.Dishes[0].Comments[0].Rating, Author: "---"}}
+	if _, err := Import(ctx, doc, newTestStores()); err == nil {
+		t.Errorf("Import of author ---: got no error, want one")
+	}
+}
+
+// a comment is skipped when the dish has one of the same author and text
+func TestImportCommentDedup(t *testing.T) {
+
+	ctx := context.Background()
+	doc := readDbJson(t)
+	doc = Document{Dishes: doc.Dishes[:1]}
+	first := doc.Dishes[0].Comments[0]
+
+	rating := 1
+	otherText := "Another comment"
+	doc.Dishes[0].Comments = []CommentDocument{
+		first,
+		// another text of the same author
+		{Rating: &rating, Comment: &otherText, Author: first.Author},
+		// the same text of another author
+		{Rating: first.Rating, Comment: first.Comment, Author: "Jane Doe"},
+		// no text
+		{Rating: &rating, Author: first.Author},
+	}
+
+	stores := newTestStores()
+	if _, err := Import(ctx, Document{Dishes: []DishDocument{{Dish: doc.Dishes[0].Dish,
+		Comments: []CommentDocument{first}}}}, stores); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	summary, err := Import(ctx, doc, stores)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if summary.Comments != (Counts{Created: 3, Skipped: 1}) {
+		t.Errorf("Import: got comments %+v, want 3 created and 1 skipped", summary.Comments)
+	}
+	if summary, _ = Import(ctx, doc, stores); summary.Comments != (Counts{Skipped: 4}) {
+		t.Errorf("Import again: got comments %+v, want 4 skipped", summary.Comments)
+	}
+}
+
+func TestGetCommentDate(t *testing.T) {
+
+	for _, test := range []struct {
+		date    string
+		want    string
+		wantErr bool
+	}{
+		{"2012-10-16T17:57:28.556094Z", "2012-10-16 17:57:28", false},
+		{"2014-09-05T17:57:28Z", "2014-09-05 17:57:28", false},
+		// the timestamps are kept in UTC
+		{"2015-02-13T17:57:28+02:00", "2015-02-13 15:57:28", false},
+		// as Export writes them
+		{"2013-12-02 17:57:28", "2013-12-02 17:57:28", false},
+		{"16/10/2012", "", true},
+		{"2012-10-16", "", true},
+	} {
+
+		got, err := getCommentDate(&test.date)
+		if (err != nil) != test.wantErr {
+			t.Errorf("getCommentDate(%q): got error %v, want one: %v", test.date, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && *got != test.want {
+			t.Errorf("getCommentDate(%q): got %q, want %q", test.date, *got, test.want)
+		}
+	}
+
+	// a comment without a date is dated when it is created
+	if got, err := getCommentDate(nil); got != nil || err != nil {
+		t.Errorf("getCommentDate(nil): got %v, %v, want nil", got, err)
+	}
+}
+
+func TestGetPlaceholderUsername(t *testing.T) {
+
+	for _, test := range []struct {
+		fullName string
+		want     string
+	}{
+		{"John Lemon", "johnlemon"},
+		{"25 Cent", "25cent"},
+		{"Michael Jaikishan", "michaeljaikisha"},
+		{"Émile O'Zola", "mileozola"},
+		{"---", ""},
+	} {
+
+		if got := getPlaceholderUsername(test.fullName); got != test.want {
+			t.Errorf("getPlaceholderUsername(%q): got %q, want %q", test.fullName, got, test.want)
+		}
+	}
+}
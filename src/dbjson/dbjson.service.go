@@ -0,0 +1,63 @@
+package dbjson
+
+import (
+	"net/http"
+	"strconv"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/cors"
+	"confusion.com/bwoo/misc"
+	"github.com/julienschmidt/httprouter"
+)
+
+// handlers export the content of stores
+type handlers struct {
+	stores Stores
+}
+
+func SetupRoutes(router *misc.Router, stores Stores) {
+
+	h := &handlers{stores: stores}
+
+	// /export?users=true&favorites=true
+	router.GET("/export", cors.Cors(auth.VerifyUser(auth.VerifyAdmin(h.getExport))))
+}
+
+/****************************
+* Helper functions
+****************************/
+func getBoolQueryParam(r *http.Request, name string) (bool, error) {
+
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+/****************************
+* /export operations
+****************************/
+func (h *handlers) getExport(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var options ExportOptions
+	var err error
+	if options.Users, err = getBoolQueryParam(r, "users"); err != nil {
+		misc.WriteError(w, r, misc.NewBadRequestError("users must be true or false"))
+		return
+	}
+	if options.Favorites, err = getBoolQueryParam(r, "favorites"); err != nil {
+		misc.WriteError(w, r, misc.NewBadRequestError("favorites must be true or false"))
+		return
+	}
+
+	doc, err := Export(r.Context(), h.stores, options)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="db.json"`)
+	WriteDocument(w, doc)
+}
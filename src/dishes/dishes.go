@@ -2,18 +2,30 @@ package dishes
 
 import (
 	"confusion.com/bwoo/comments"
+	"confusion.com/bwoo/resource"
 )
 
 type Dish struct {
 	ID          int64              `json:"_id"`
-	Name        *string            `json:"name"`
-	Image       *string            `json:"image"`
-	Category    *string            `json:"category"`
-	Label       *string            `json:"label"`
-	Price       *string            `json:"price"`
-	Featured    *string            `json:"featured"`
-	Description *string            `json:"description"`
+	Name        *string            `json:"name" validate:"required,max=50"`
+	Image       *string            `json:"image" validate:"required,max=50"`
+	Category    *string            `json:"category" validate:"required,max=20"`
+	Label       *string            `json:"label" validate:"max=10"`
+	Price       *string            `json:"price" validate:"required,number"`
+	Featured    *string            `json:"featured" validate:"oneof=true false"`
+	Description *string            `json:"description" validate:"required"`
 	Comments    []comments.Comment `json:"comments"`
 	CreatedAt   *string            `json:"createdAt"`
 	UpdatedAt   *string            `json:"updatedAt"`
 }
+
+// Resource declares the dish table and its routes under /dishes
+var Resource = resource.New[Dish](resource.Definition{
+	Name:          "dish",
+	Path:          "/dishes",
+	Table:         "dish",
+	Columns:       []string{"name", "image", "category", "label", "price", "featured", "description"},
+	UniqueColumn:  "name",
+	NumberColumns: []string{"price"},
+	CacheControl:  "public, max-age=60",
+})
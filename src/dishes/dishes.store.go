@@ -0,0 +1,18 @@
+package dishes
+
+import (
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/resource"
+)
+
+// DishStore is the persistence layer used by the dish handlers, a
+// resource.Store of the dish table.
+type DishStore = resource.Store[Dish]
+
+func NewDbStore(db *database.Conn) DishStore {
+	return resource.NewDbStore(db, Resource)
+}
+
+func NewMemoryStore() DishStore {
+	return resource.NewMemoryStore(Resource)
+}
@@ -0,0 +1,77 @@
+package etag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"confusion.com/bwoo/misc"
+)
+
+// Compute returns the strong ETag of a representation made of parts: a hash
+// of their content, which changes with updatedAt or any other field
+func Compute(parts ...[]byte) string {
+
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write(part)
+		// so ["ab", "c"] and ["a", "bc"] differ
+		hash.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// getTags splits the value of an If-Match or If-None-Match header into its ETags
+func getTags(header string) []string {
+
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// WriteJson writes the JSON body with its ETag, or answers 304 Not Modified
+// without the body when If-None-Match lists the ETag
+func WriteJson(w http.ResponseWriter, r *http.Request, body []byte, etag string) {
+
+	w.Header().Set("ETag", etag)
+
+	// If-None-Match uses the weak comparison, which ignores the W/ prefix
+	for _, tag := range getTags(r.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// CheckIfMatch returns a precondition_failed error when the request has an
+// If-Match header which doesn't list etag, the ETag of the current
+// representation of the resource, "" when there is none. The changes made
+// without If-Match are let through.
+func CheckIfMatch(r *http.Request, etag string) error {
+
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	// If-Match uses the strong comparison, so a weak ETag never matches
+	for _, tag := range getTags(header) {
+		if etag != "" && (tag == "*" || tag == etag) {
+			return nil
+		}
+	}
+
+	if etag == "" {
+		return misc.NewPreconditionFailedError("The resource does not exist anymore")
+	}
+	return misc.NewPreconditionFailedError("The resource has changed, get it again before changing it")
+}
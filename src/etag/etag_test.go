@@ -0,0 +1,116 @@
+package etag
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"confusion.com/bwoo/misc"
+)
+
+func TestCompute(t *testing.T) {
+
+	tag := Compute([]byte(`{"name":"soup"}`))
+	if len(tag) != 34 || tag[0] != '"' || tag[33] != '"' {
+		t.Errorf(`Compute: got %s, want 32 hex digits within ""`, tag)
+	}
+
+	for _, test := range []struct {
+		name   string
+		a, b   [][]byte
+		sameAs bool
+	}{
+		{"same content", [][]byte{[]byte("soup")}, [][]byte{[]byte("soup")}, true},
+		{"other content", [][]byte{[]byte("soup")}, [][]byte{[]byte("salad")}, false},
+		{"parts split elsewhere", [][]byte{[]byte("ab"), []byte("c")}, [][]byte{[]byte("a"), []byte("bc")}, false},
+		{"empty part", [][]byte{[]byte("soup")}, [][]byte{[]byte("soup"), nil}, false},
+	} {
+
+		if same := Compute(test.a...) == Compute(test.b...); same != test.sameAs {
+			t.Errorf("%s: got same ETags %v, want %v", test.name, same, test.sameAs)
+		}
+	}
+}
+
+func TestWriteJson(t *testing.T) {
+
+	body := []byte(`{"name":"soup"}`)
+	tag := Compute(body)
+
+	for _, test := range []struct {
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"", http.StatusOK},
+		{tag, http.StatusNotModified},
+		{"W/" + tag, http.StatusNotModified},
+		{`"other", ` + tag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+		{tag[1:], http.StatusOK},
+	} {
+
+		r := httptest.NewRequest(http.MethodGet, "/dishes/1", nil)
+		if test.ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", test.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		WriteJson(w, r, body, tag)
+
+		if w.Code != test.wantStatus {
+			t.Errorf("If-None-Match %s: got status %d, want %d", test.ifNoneMatch, w.Code, test.wantStatus)
+		}
+		if got := w.Header().Get("ETag"); got != tag {
+			t.Errorf("If-None-Match %s: got ETag %s, want %s", test.ifNoneMatch, got, tag)
+		}
+		wantBody := string(body)
+		if test.wantStatus == http.StatusNotModified {
+			wantBody = ""
+		}
+		if w.Body.String() != wantBody {
+			t.Errorf("If-None-Match %s: got body %q, want %q", test.ifNoneMatch, w.Body.String(), wantBody)
+		}
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+
+	tag := Compute([]byte(`{"name":"soup"}`))
+
+	for _, test := range []struct {
+		name    string
+		ifMatch string
+		current string
+		wantErr bool
+	}{
+		{"no If-Match", "", tag, false},
+		{"no If-Match on a deleted row", "", "", false},
+		{"current", tag, tag, false},
+		{"among others", `"other", ` + tag, tag, false},
+		{"any", "*", tag, false},
+		{"stale", `"other"`, tag, true},
+		// If-Match uses the strong comparison
+		{"weak", "W/" + tag, tag, true},
+		{"deleted row", tag, "", true},
+		{"any deleted row", "*", "", true},
+	} {
+
+		r := httptest.NewRequest(http.MethodPut, "/dishes/1", nil)
+		if test.ifMatch != "" {
+			r.Header.Set("If-Match", test.ifMatch)
+		}
+
+		err := CheckIfMatch(r, test.current)
+		if !test.wantErr {
+			if err != nil {
+				t.Errorf("%s: got %v, want no error", test.name, err)
+			}
+			continue
+		}
+		var miscErr *misc.Error
+		if !errors.As(err, &miscErr) || miscErr.Code != misc.ErrorCodePreconditionFailed {
+			t.Errorf("%s: got %v, want a precondition_failed error", test.name, err)
+		}
+	}
+}
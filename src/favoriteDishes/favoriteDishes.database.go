@@ -3,27 +3,35 @@ package favoriteDishes
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
-	"confusion.com/bwoo/dishes"
-
 	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/dishes"
 	"confusion.com/bwoo/misc"
 )
 
-func getFavoriteDishFromDb(userId, dishId int64) (favoriteDishExist, error) {
+type dbFavoriteDishStore struct {
+	db *database.Conn
+}
+
+func NewDbStore(db *database.Conn) FavoriteDishStore {
+	return &dbFavoriteDishStore{db: db}
+}
+
+func (s *dbFavoriteDishStore) Get(ctx context.Context, userId, dishId int64) (*dishes.Dish, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	row := database.DbConn.QueryRowContext(ctx, `SELECT 
+	row := s.db.QueryRowContext(ctx, `SELECT 
 													d.id,
 													d.name,
 													d.image,
 													d.category,
 													d.label,
 													d.price,
-													CASE WHEN d.featured = 0 then 'false' ELSE 'true' END,
+													d.featured,
 													d.description,
 													d.createdAt,
 													d.updatedAt 
@@ -34,43 +42,42 @@ func getFavoriteDishFromDb(userId, dishId int64) (favoriteDishExist, error) {
 		userId,
 		dishId)
 
-	var favDishExist favoriteDishExist
 	var favDish dishes.Dish
+	var featured bool
 	err := row.Scan(&favDish.ID,
 		&favDish.Name,
 		&favDish.Image,
 		&favDish.Category,
 		&favDish.Label,
 		&favDish.Price,
-		&favDish.Featured,
+		&featured,
 		&favDish.Description,
-		&favDish.CreatedAt,
-		&favDish.UpdatedAt)
+		database.ScanNullTimestamp(&favDish.CreatedAt),
+		database.ScanNullTimestamp(&favDish.UpdatedAt))
 
 	if err == sql.ErrNoRows {
-		return favDishExist, nil
+		return nil, nil
 	} else if err != nil {
-		return favDishExist, err
+		return nil, err
 	}
 
-	favDishExist.IsExists = true
-	favDishExist.Favorites = &favDish
-	return favDishExist, nil
+	favDish.Featured = misc.GetStringFromBool(featured)
+	return &favDish, nil
 }
 
-func getFavoriteDishesFromDb(userId int64) (favoriteDishesResult, error) {
+func (s *dbFavoriteDishStore) List(ctx context.Context, userId int64) ([]dishes.Dish, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	rows, err := database.DbConn.QueryContext(ctx, `SELECT 
+	rows, err := s.db.QueryContext(ctx, `SELECT 
 														d.id,
 														d.name,
 														d.image,
 														d.category,
 														d.label,
 														d.price,
-														CASE WHEN d.featured = 0 then 'false' ELSE 'true' END,
+														d.featured,
 														d.description,
 														d.createdAt,
 														d.updatedAt 
@@ -78,36 +85,62 @@ func getFavoriteDishesFromDb(userId int64) (favoriteDishesResult, error) {
 														WHERE d.id = fd.dishId 
 														AND fd.userId = ? `,
 		userId)
-
-	defer rows.Close()
 	if err != nil {
-		return favoriteDishesResult{}, err
+		return nil, err
 	}
+	defer rows.Close()
 
 	favDishes := make([]dishes.Dish, 0)
 	for rows.Next() {
 
 		var dish dishes.Dish
-		rows.Scan(&dish.ID,
+		var featured bool
+		if err := rows.Scan(&dish.ID,
 			&dish.Name,
 			&dish.Image,
 			&dish.Category,
 			&dish.Label,
 			&dish.Price,
-			&dish.Featured,
+			&featured,
 			&dish.Description,
-			&dish.CreatedAt,
-			&dish.UpdatedAt)
+			database.ScanNullTimestamp(&dish.CreatedAt),
+			database.ScanNullTimestamp(&dish.UpdatedAt)); err != nil {
+			return nil, err
+		}
 
+		dish.Featured = misc.GetStringFromBool(featured)
 		favDishes = append(favDishes, dish)
 	}
 
-	var favDishesResult favoriteDishesResult
-	favDishesResult.Dishes = favDishes
-	return favDishesResult, nil
+	return favDishes, rows.Err()
+}
+
+func (s *dbFavoriteDishStore) CountByDish(ctx context.Context) (map[int64]int, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT dishId, COUNT(*) FROM favoriteDish GROUP BY dishId`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+
+		var dishId int64
+		var count int
+		if err := rows.Scan(&dishId, &count); err != nil {
+			return nil, err
+		}
+		counts[dishId] = count
+	}
+
+	return counts, rows.Err()
 }
 
-func getExecContextFunc(tx *sql.Tx) func(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+func (s *dbFavoriteDishStore) getExecContextFunc(tx *database.Tx) func(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 
 	if tx != nil {
 		return func(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
@@ -115,20 +148,20 @@ func getExecContextFunc(tx *sql.Tx) func(ctx context.Context, query string, args
 		}
 	} else {
 		return func(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-			return database.DbConn.ExecContext(ctx, query, args...)
+			return s.db.ExecContext(ctx, query, args...)
 		}
 	}
 }
 
-func createFavoriteDishInDb(userId, dishId int64) (*misc.Status, error) {
+func (s *dbFavoriteDishStore) Create(ctx context.Context, userId, dishId int64) (*misc.Status, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	return createFavoriteDishInDbInternal(nil, ctx, userId, dishId)
+	return s.createFavoriteDishInDbInternal(nil, ctx, userId, dishId)
 }
 
-func createFavoriteDishInDbInternal(tx *sql.Tx, ctx context.Context, userId, dishId int64) (*misc.Status, error) {
+func (s *dbFavoriteDishStore) createFavoriteDishInDbInternal(tx *database.Tx, ctx context.Context, userId, dishId int64) (*misc.Status, error) {
 
 	status := &misc.Status{NumOfRowsAffected: 0, IsOk: 0}
 	sqlInsert := `INSERT INTO favoriteDish(
@@ -139,9 +172,14 @@ func createFavoriteDishInDbInternal(tx *sql.Tx, ctx context.Context, userId, dis
 					?,?
 				)`
 
-	execContextFunc := getExecContextFunc(tx)
+	// the user comes from the JWT, so a missing referenced row is the dish
+	execContextFunc := s.getExecContextFunc(tx)
 	result, err := execContextFunc(ctx, sqlInsert, userId, dishId)
-	if err != nil {
+	if misc.HasErrorCode(err, misc.ErrorCodeInvalidReference) {
+		return status, misc.NewNotFoundError(fmt.Sprintf("Dish %d not found", dishId))
+	} else if misc.HasErrorCode(err, misc.ErrorCodeConflict) {
+		return status, misc.NewConflictError(fmt.Sprintf("Dish %d is already a favorite", dishId))
+	} else if err != nil {
 		return status, err
 	}
 
@@ -151,21 +189,21 @@ func createFavoriteDishInDbInternal(tx *sql.Tx, ctx context.Context, userId, dis
 	return status, nil
 }
 
-func createFavoriteDishesInDb(userId int64, favDishes favoriteDishes) (*misc.Status, error) {
+func (s *dbFavoriteDishStore) CreateMany(ctx context.Context, userId int64, dishIds []int64) (*misc.Status, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
 	// we use transaction so we can commit atomicly (i.e. all or none)
-	tx, err := database.DbConn.BeginTx(ctx, nil)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	status := &misc.Status{NumOfRowsAffected: 0, IsOk: 0}
-	for _, favDish := range favDishes {
+	for _, dishId := range dishIds {
 
-		result, err := createFavoriteDishInDbInternal(tx, ctx, userId, favDish.ID)
+		result, err := s.createFavoriteDishInDbInternal(tx, ctx, userId, dishId)
 		if err != nil {
 			tx.Rollback()
 			status.NumOfRowsAffected = 0
@@ -184,13 +222,13 @@ func createFavoriteDishesInDb(userId int64, favDishes favoriteDishes) (*misc.Sta
 	return status, nil
 }
 
-func deleteFavoriteDishesFromDb(userId int64) (*misc.Status, error) {
+func (s *dbFavoriteDishStore) DeleteAll(ctx context.Context, userId int64) (*misc.Status, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
 	status := &misc.Status{NumOfRowsAffected: 0, IsOk: 0}
-	results, err := database.DbConn.ExecContext(ctx, `DELETE FROM favoriteDish 
+	results, err := s.db.ExecContext(ctx, `DELETE FROM favoriteDish 
 														WHERE userId = ?`,
 		userId)
 
@@ -204,13 +242,13 @@ func deleteFavoriteDishesFromDb(userId int64) (*misc.Status, error) {
 	return status, nil
 }
 
-func deleteFavoriteDishFromDb(userId, dishId int64) (*misc.Status, error) {
+func (s *dbFavoriteDishStore) Delete(ctx context.Context, userId, dishId int64) (*misc.Status, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
 	status := &misc.Status{NumOfRowsAffected: 0, IsOk: 0}
-	results, err := database.DbConn.ExecContext(ctx, `DELETE FROM favoriteDish 
+	results, err := s.db.ExecContext(ctx, `DELETE FROM favoriteDish 
 														WHERE userId = ?
 														AND dishId = ?`,
 		userId,
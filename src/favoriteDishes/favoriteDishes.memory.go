@@ -0,0 +1,173 @@
+package favoriteDishes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/misc"
+)
+
+type favoriteDishKey struct {
+	userId int64
+	dishId int64
+}
+
+// memoryFavoriteDishStore resolves favorite dishes through the given dishes.DishStore,
+// the same way the SQL store joins the dish table
+type memoryFavoriteDishStore struct {
+	mu        sync.RWMutex
+	favorites []favoriteDishKey
+	dishes    dishes.DishStore
+}
+
+func NewMemoryStore(dishStore dishes.DishStore) FavoriteDishStore {
+	return &memoryFavoriteDishStore{favorites: make([]favoriteDishKey, 0), dishes: dishStore}
+}
+
+func (s *memoryFavoriteDishStore) indexOf(key favoriteDishKey) int {
+
+	for i, favorite := range s.favorites {
+		if favorite == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *memoryFavoriteDishStore) Get(ctx context.Context, userId, dishId int64) (*dishes.Dish, error) {
+
+	s.mu.RLock()
+	index := s.indexOf(favoriteDishKey{userId: userId, dishId: dishId})
+	s.mu.RUnlock()
+	if index < 0 {
+		return nil, nil
+	}
+
+	return s.dishes.Get(ctx, dishId)
+}
+
+func (s *memoryFavoriteDishStore) List(ctx context.Context, userId int64) ([]dishes.Dish, error) {
+
+	s.mu.RLock()
+	dishIds := make([]int64, 0)
+	for _, favorite := range s.favorites {
+		if favorite.userId == userId {
+			dishIds = append(dishIds, favorite.dishId)
+		}
+	}
+	s.mu.RUnlock()
+
+	favDishes := make([]dishes.Dish, 0, len(dishIds))
+	for _, dishId := range dishIds {
+		dish, err := s.dishes.Get(ctx, dishId)
+		if err != nil {
+			return nil, err
+		}
+		// favorites of deleted dishes are dropped, like the SQL join does
+		if dish != nil {
+			favDishes = append(favDishes, *dish)
+		}
+	}
+
+	return favDishes, nil
+}
+
+func (s *memoryFavoriteDishStore) CountByDish(ctx context.Context) (map[int64]int, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[int64]int)
+	for _, favorite := range s.favorites {
+		counts[favorite.dishId]++
+	}
+	return counts, nil
+}
+
+// checkNewFavorite returns an error for the same cases the SQL store
+// fails on: an unknown dish (foreign key) or a duplicate favorite (unique key)
+func (s *memoryFavoriteDishStore) checkNewFavorite(ctx context.Context, key favoriteDishKey) error {
+
+	dish, err := s.dishes.Get(ctx, key.dishId)
+	if err != nil {
+		return err
+	}
+	if dish == nil {
+		return misc.NewNotFoundError(fmt.Sprintf("Dish %d not found", key.dishId))
+	}
+
+	if s.indexOf(key) >= 0 {
+		return misc.NewConflictError(fmt.Sprintf("Dish %d is already a favorite", key.dishId))
+	}
+	return nil
+}
+
+func (s *memoryFavoriteDishStore) Create(ctx context.Context, userId, dishId int64) (*misc.Status, error) {
+
+	return s.CreateMany(ctx, userId, []int64{dishId})
+}
+
+func (s *memoryFavoriteDishStore) CreateMany(ctx context.Context, userId int64, dishIds []int64) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &misc.Status{NumOfRowsAffected: 0, IsOk: 0}
+	newFavorites := make([]favoriteDishKey, 0, len(dishIds))
+	for _, dishId := range dishIds {
+
+		key := favoriteDishKey{userId: userId, dishId: dishId}
+		if err := s.checkNewFavorite(ctx, key); err != nil {
+			return status, err
+		}
+
+		for _, newFavorite := range newFavorites {
+			if newFavorite == key {
+				return status, misc.NewConflictError(fmt.Sprintf("Dish %d is already a favorite", dishId))
+			}
+		}
+		newFavorites = append(newFavorites, key)
+	}
+
+	s.favorites = append(s.favorites, newFavorites...)
+	status.NumOfRowsAffected = int64(len(newFavorites))
+	status.IsOk = 1
+	return status, nil
+}
+
+func (s *memoryFavoriteDishStore) DeleteAll(ctx context.Context, userId int64) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remaining := make([]favoriteDishKey, 0, len(s.favorites))
+	for _, favorite := range s.favorites {
+		if favorite.userId != userId {
+			remaining = append(remaining, favorite)
+		}
+	}
+
+	status := &misc.Status{}
+	status.SetStatus(int64(len(s.favorites)-len(remaining)), 1)
+	s.favorites = remaining
+	return status, nil
+}
+
+func (s *memoryFavoriteDishStore) Delete(ctx context.Context, userId, dishId int64) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &misc.Status{}
+	index := s.indexOf(favoriteDishKey{userId: userId, dishId: dishId})
+	if index < 0 {
+		status.SetStatus(0, 1)
+		return status, nil
+	}
+
+	s.favorites = append(s.favorites[:index], s.favorites[index+1:]...)
+	status.SetStatus(1, 1)
+	return status, nil
+}
@@ -1,52 +1,58 @@
 package favoriteDishes
 
 import (
-	"encoding/json"
-	"io"
 	"net/http"
 
 	"confusion.com/bwoo/auth"
 	"confusion.com/bwoo/cors"
 	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/validation"
 	"github.com/julienschmidt/httprouter"
 )
 
-func SetupRoutes(router *httprouter.Router) {
+// handlers serve the favorite dishes of store
+type handlers struct {
+	store FavoriteDishStore
+}
+
+func SetupRoutes(router *misc.Router, store FavoriteDishStore) {
+
+	h := &handlers{store: store}
 
 	// /favorites
-	router.GET("/favorites", cors.Cors(auth.VerifyUser(getFavoriteDishes)))
-	router.POST("/favorites", cors.Cors(auth.VerifyUser(postFavoriteDishes)))
-	router.DELETE("/favorites", cors.Cors(auth.VerifyUser(deleteFavoriteDishes)))
+	router.GET("/favorites", cors.Cors(auth.VerifyUser(h.getFavoriteDishes)))
+	router.POST("/favorites", cors.Cors(auth.VerifyUser(h.postFavoriteDishes)))
+	router.DELETE("/favorites", cors.Cors(auth.VerifyUser(h.deleteFavoriteDishes)))
 
 	// /favorites/:dishId
-	router.GET("/favorites/:dishId", cors.Cors(auth.VerifyUser(getFavoriteDish)))
-	router.POST("/favorites/:dishId", cors.Cors(auth.VerifyUser(postFavoriteDish)))
-	router.DELETE("/favorites/:dishId", cors.Cors(auth.VerifyUser(deleteFavoriteDish)))
+	router.GET("/favorites/:dishId", cors.Cors(auth.VerifyUser(h.getFavoriteDish)))
+	router.POST("/favorites/:dishId", cors.Cors(auth.VerifyUser(h.postFavoriteDish)))
+	router.DELETE("/favorites/:dishId", cors.Cors(auth.VerifyUser(h.deleteFavoriteDish)))
 }
 
 /****************************
 * Helper functions
 ****************************/
-func getFavoriteDishesFromBody(body io.ReadCloser) (favoriteDishes, error) {
+func getFavoriteDishesFromBody(w http.ResponseWriter, r *http.Request) (favoriteDishes, error) {
 
 	var favDishes favoriteDishes
-	err := json.NewDecoder(body).Decode(&favDishes)
-	if err != nil {
+	if err := validation.DecodeJson(w, r, &favDishes); err != nil {
 		return favoriteDishes{}, err
 	}
 
 	return favDishes, nil
 }
 
-func getFavoriteDishesAndReply(w http.ResponseWriter, userId int64) {
+func (h *handlers) getFavoriteDishesAndReply(w http.ResponseWriter, r *http.Request, userId int64) {
 
-	favDishes, err := getFavoriteDishesFromDb(userId)
+	favDishes, err := h.store.List(r.Context(), userId)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	favDishesJson, _ := misc.GetJsonFromJsonObjs(favDishes)
+	favDishesResult := favoriteDishesResult{Dishes: favDishes}
+	favDishesJson, _ := misc.GetJsonFromJsonObjs(favDishesResult)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(favDishesJson)
 }
@@ -54,112 +60,119 @@ func getFavoriteDishesAndReply(w http.ResponseWriter, userId int64) {
 /****************************
 * /favorites operations
 ****************************/
-func getFavoriteDishes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) getFavoriteDishes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, _ := misc.GetInt64FromString(claims.UserId)
 
-	getFavoriteDishesAndReply(w, userId)
+	h.getFavoriteDishesAndReply(w, r, userId)
 }
 
-func postFavoriteDishes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) postFavoriteDishes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	favDishes, err := getFavoriteDishesFromBody(r.Body)
+	favDishes, err := getFavoriteDishesFromBody(w, r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, err)
 		return
 	}
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, _ := misc.GetInt64FromString(claims.UserId)
 
-	_, err = createFavoriteDishesInDb(userId, favDishes)
+	dishIds := make([]int64, 0, len(favDishes))
+	for _, favDish := range favDishes {
+		dishIds = append(dishIds, favDish.ID)
+	}
+
+	_, err = h.store.CreateMany(r.Context(), userId, dishIds)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	getFavoriteDishesAndReply(w, userId)
+	h.getFavoriteDishesAndReply(w, r, userId)
 }
 
-func deleteFavoriteDishes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) deleteFavoriteDishes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, _ := misc.GetInt64FromString(claims.UserId)
 
-	_, err := deleteFavoriteDishesFromDb(userId)
+	_, err := h.store.DeleteAll(r.Context(), userId)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	getFavoriteDishesAndReply(w, userId)
+	h.getFavoriteDishesAndReply(w, r, userId)
 }
 
 /****************************
 * /favorites/:dishId operations
 ****************************/
-func getFavoriteDish(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) getFavoriteDish(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, _ := misc.GetInt64FromString(claims.UserId)
 
-	status, err := getFavoriteDishFromDb(userId, dishIdInt)
+	favDish, err := h.store.Get(r.Context(), userId, dishIdInt)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
+	status := favoriteDishExist{IsExists: favDish != nil, Favorites: favDish}
+
 	statusJson, _ := misc.GetJsonFromJsonObjs(status)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(statusJson)
 }
 
-func postFavoriteDish(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) postFavoriteDish(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, _ := misc.GetInt64FromString(claims.UserId)
 
-	_, err = createFavoriteDishInDb(userId, dishIdInt)
+	_, err = h.store.Create(r.Context(), userId, dishIdInt)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	getFavoriteDishesAndReply(w, userId)
+	h.getFavoriteDishesAndReply(w, r, userId)
 }
 
-func deleteFavoriteDish(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) deleteFavoriteDish(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	dishId := ps.ByName("dishId")
 	dishIdInt, err := misc.GetInt64FromString(dishId)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Invalid dish id "+dishId))
 		return
 	}
 
 	claims := auth.GetClaimsFromRequest(r)
 	userId, _ := misc.GetInt64FromString(claims.UserId)
 
-	_, err = deleteFavoriteDishFromDb(userId, dishIdInt)
+	_, err = h.store.Delete(r.Context(), userId, dishIdInt)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
-	getFavoriteDishesAndReply(w, userId)
+	h.getFavoriteDishesAndReply(w, r, userId)
 }
@@ -0,0 +1,22 @@
+package favoriteDishes
+
+import (
+	"context"
+
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/misc"
+)
+
+// FavoriteDishStore is the persistence layer used by the favorites handlers.
+type FavoriteDishStore interface {
+	// Get returns the dish if it is a favorite of the user, nil otherwise
+	Get(ctx context.Context, userId, dishId int64) (*dishes.Dish, error)
+	List(ctx context.Context, userId int64) ([]dishes.Dish, error)
+	// CountByDish returns the number of users having each dish as a favorite, the dishes nobody has are left out
+	CountByDish(ctx context.Context) (map[int64]int, error)
+	Create(ctx context.Context, userId, dishId int64) (*misc.Status, error)
+	// CreateMany adds all the dishes or none of them
+	CreateMany(ctx context.Context, userId int64, dishIds []int64) (*misc.Status, error)
+	Delete(ctx context.Context, userId, dishId int64) (*misc.Status, error)
+	DeleteAll(ctx context.Context, userId int64) (*misc.Status, error)
+}
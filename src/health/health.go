@@ -0,0 +1,21 @@
+package health
+
+// Statuses of a check and of the whole report
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+	// StatusSkipped is a check which does not apply to the configuration, e.g.
+	// the database check with db_driver memory. It does not fail the report.
+	StatusSkipped = "skipped"
+)
+
+type Check struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the body of /readyz, its status is fail when any check failed
+type Report struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
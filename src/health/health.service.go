@@ -0,0 +1,179 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/tlscert"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// how long the database has to answer the ping of /readyz
+const pingTimeout = 2 * time.Second
+
+// handlers check the database db, nil with db_driver memory, the image
+// directory and the certificates, nil with http_only
+type handlers struct {
+	db              *database.Conn
+	imageDirectory  string
+	certMinValidity time.Duration
+	certificates    *tlscert.Manager
+}
+
+// SetupRoutes registers /healthz, answering as long as the process serves
+// requests, and /readyz, checking what the API needs to serve them.
+// db is nil with db_driver memory, certificates is nil when the server runs
+// with http_only.
+func SetupRoutes(router *misc.Router, config config.Config, db *database.Conn, certificates *tlscert.Manager) {
+
+	h := &handlers{
+		db:              db,
+		imageDirectory:  config.PublicImagesDir,
+		certMinValidity: config.CertMinValidity,
+		certificates:    certificates,
+	}
+
+	router.GET("/healthz", getHealthz)
+	router.GET("/readyz", h.getReadyz)
+}
+
+func getHealthz(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	writeReport(w, Report{Status: StatusOk, Checks: map[string]Check{}})
+}
+
+func (h *handlers) getReadyz(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	report := Report{
+		Status: StatusOk,
+		Checks: map[string]Check{
+			"database":    h.checkDatabase(r.Context()),
+			"images":      h.checkImageDirectory(),
+			"certificate": h.checkCertificate(),
+			"acme":        h.checkAcmeCertificates(r.Context()),
+		},
+	}
+	for _, check := range report.Checks {
+		if check.Status == StatusFail {
+			report.Status = StatusFail
+		}
+	}
+
+	writeReport(w, report)
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+
+	reportJson, _ := misc.GetJsonFromJsonObjs(report)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == StatusFail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(reportJson)
+}
+
+// checkDatabase pings the database. /readyz is public, so the error, which
+// may tell the host or the user of the database, is only logged.
+func (h *handlers) checkDatabase(ctx context.Context) Check {
+
+	if h.db == nil {
+		return Check{Status: StatusSkipped, Message: "db_driver is memory"}
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if err := h.db.PingContext(pingCtx); err != nil {
+		logging.FromContext(ctx).Error("Error pinging the database", "error", err)
+		return Check{Status: StatusFail, Message: "Database unreachable"}
+	}
+	return Check{Status: StatusOk}
+}
+
+// checkImageDirectory makes sure the uploads can be stored by writing a file
+func (h *handlers) checkImageDirectory() Check {
+
+	file, err := os.CreateTemp(h.imageDirectory, ".readyz-*")
+	if err != nil {
+		return Check{Status: StatusFail, Message: err.Error()}
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	return Check{Status: StatusOk}
+}
+
+// checkCertificate fails when the certificate file expires within cert_min_validity
+func (h *handlers) checkCertificate() Check {
+
+	if h.certificates == nil {
+		return Check{Status: StatusSkipped, Message: "http_only is set"}
+	}
+
+	notAfter, err := h.certificates.GetFileCertificateExpiry()
+	if err != nil {
+		return Check{Status: StatusFail, Message: err.Error()}
+	}
+
+	return h.checkExpiry(notAfter)
+}
+
+// checkAcmeCertificates fails when an ACME certificate in the autocert cache
+// expires within cert_min_validity, which means autocert failed to renew it.
+// A certificate not obtained yet does not fail the check, autocert obtains it
+// on the first handshake for its domain and the certificate file is served
+// until then.
+func (h *handlers) checkAcmeCertificates(ctx context.Context) Check {
+
+	if h.certificates == nil {
+		return Check{Status: StatusSkipped, Message: "http_only is set"}
+	}
+
+	acmeCerts, err := h.certificates.GetAcmeCertificateExpiries(ctx)
+	if err != nil {
+		return Check{Status: StatusFail, Message: err.Error()}
+	}
+	if len(acmeCerts) == 0 {
+		return Check{Status: StatusSkipped, Message: "acme_domains is not set"}
+	}
+
+	result := Check{Status: StatusOk}
+	messages := make([]string, 0, len(acmeCerts))
+	for _, cert := range acmeCerts {
+		if cert.NotAfter.IsZero() {
+			messages = append(messages, cert.Domain+": Not obtained yet")
+			continue
+		}
+		check := h.checkExpiry(cert.NotAfter)
+		if check.Status == StatusFail {
+			result.Status = StatusFail
+		}
+		messages = append(messages, cert.Domain+": "+check.Message)
+	}
+	result.Message = strings.Join(messages, "; ")
+	return result
+}
+
+// checkExpiry fails when notAfter is within cert_min_validity
+func (h *handlers) checkExpiry(notAfter time.Time) Check {
+
+	validity := time.Until(notAfter)
+	message := fmt.Sprintf("Expires at %s", notAfter.UTC().Format(time.RFC3339))
+	if validity <= 0 {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("Expired at %s", notAfter.UTC().Format(time.RFC3339))}
+	}
+	if validity < h.certMinValidity {
+		return Check{Status: StatusFail, Message: message + ", in less than cert_min_validity " + h.certMinValidity.String()}
+	}
+	return Check{Status: StatusOk, Message: message}
+}
@@ -0,0 +1,136 @@
+package health
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/tlscert"
+
+	_ "modernc.org/sqlite"
+)
+
+// openSqlite opens a SQLite database in a temporary file
+func openSqlite(t *testing.T) *database.Conn {
+
+	t.Helper()
+
+	dialect, _ := database.GetDialect(config.SQLiteDriver)
+	connString := (&config.Config{DbDriver: config.SQLiteDriver, DbName: filepath.Join(t.TempDir(), "test.db")}).GetConnString()
+	db, err := sql.Open(dialect.DriverName(), connString)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &database.Conn{DB: db, Dialect: dialect}
+}
+
+// newCertificates returns the manager of a self-signed certificate, valid for a year
+func newCertificates(t *testing.T) *tlscert.Manager {
+
+	t.Helper()
+
+	dir := t.TempDir()
+	certificates, err := tlscert.NewManager(config.Config{
+		CertPath:       filepath.Join(dir, "server.crt"),
+		KeyPath:        filepath.Join(dir, "server.key"),
+		SelfSignedCert: true,
+	})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return certificates
+}
+
+func getReport(t *testing.T, router *misc.Router, path string) (int, Report) {
+
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	return w.Code, report
+}
+
+func TestHealthz(t *testing.T) {
+
+	router := misc.NewRouter()
+	SetupRoutes(router, config.Config{PublicImagesDir: filepath.Join(t.TempDir(), "missing")}, nil, nil)
+
+	// the process serves requests even when it is not ready
+	if code, report := getReport(t, router, "/healthz"); code != http.StatusOK || report.Status != StatusOk {
+		t.Errorf("GET /healthz: got %d %+v, want %d and ok", code, report, http.StatusOK)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+
+	imageDir := t.TempDir()
+	// a file where the image directory should be, which no image can be written in
+	notADir := filepath.Join(t.TempDir(), "images")
+	if err := os.WriteFile(notADir, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	closedDb := openSqlite(t)
+	closedDb.Close()
+
+	for _, test := range []struct {
+		name            string
+		db              *database.Conn
+		imageDir        string
+		certificates    *tlscert.Manager
+		certMinValidity time.Duration
+		wantCode        int
+		wantChecks      map[string]string
+	}{
+		{"memory and http_only", nil, imageDir, nil, 0, http.StatusOK,
+			map[string]string{"database": StatusSkipped, "images": StatusOk, "certificate": StatusSkipped, "acme": StatusSkipped}},
+		{"database and certificate", openSqlite(t), imageDir, newCertificates(t), 7 * 24 * time.Hour, http.StatusOK,
+			map[string]string{"database": StatusOk, "images": StatusOk, "certificate": StatusOk, "acme": StatusSkipped}},
+		{"closed database", closedDb, imageDir, nil, 0, http.StatusServiceUnavailable,
+			map[string]string{"database": StatusFail, "images": StatusOk}},
+		{"unwritable image directory", nil, notADir, nil, 0, http.StatusServiceUnavailable,
+			map[string]string{"database": StatusSkipped, "images": StatusFail}},
+		{"certificate expiring within cert_min_validity", nil, imageDir, newCertificates(t), 2 * 365 * 24 * time.Hour,
+			http.StatusServiceUnavailable, map[string]string{"images": StatusOk, "certificate": StatusFail}},
+	} {
+
+		router := misc.NewRouter()
+		SetupRoutes(router, config.Config{PublicImagesDir: test.imageDir, CertMinValidity: test.certMinValidity},
+			test.db, test.certificates)
+
+		code, report := getReport(t, router, "/readyz")
+		wantStatus := StatusOk
+		if test.wantCode != http.StatusOK {
+			wantStatus = StatusFail
+		}
+		if code != test.wantCode || report.Status != wantStatus {
+			t.Errorf("%s: got %d %s, want %d %s", test.name, code, report.Status, test.wantCode, wantStatus)
+		}
+		for name, want := range test.wantChecks {
+			if got := report.Checks[name].Status; got != want {
+				t.Errorf("%s: got %s check %s, want %s", test.name, name, got, want)
+			}
+		}
+	}
+
+	// the error of the database is logged, not told to anyone asking /readyz
+	router := misc.NewRouter()
+	SetupRoutes(router, config.Config{PublicImagesDir: imageDir}, closedDb, nil)
+	if _, report := getReport(t, router, "/readyz"); strings.Contains(report.Checks["database"].Message, "closed") {
+		t.Errorf("database check: got message %q, want no error details", report.Checks["database"].Message)
+	}
+}
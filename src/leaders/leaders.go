@@ -1,13 +1,24 @@
 package leaders
 
+import "confusion.com/bwoo/resource"
+
 type Leader struct {
 	ID          int64   `json:"_id"`
-	Name        *string `json:"name"`
-	Image       *string `json:"image"`
-	Designation *string `json:"designation"`
-	Abbr        *string `json:"abbr"`
-	Featured    *string `json:"featured"`
-	Description *string `json:"description"`
+	Name        *string `json:"name" validate:"required,max=50"`
+	Image       *string `json:"image" validate:"required,max=50"`
+	Designation *string `json:"designation" validate:"required,max=50"`
+	Abbr        *string `json:"abbr" validate:"required,max=10"`
+	Featured    *string `json:"featured" validate:"oneof=true false"`
+	Description *string `json:"description" validate:"required"`
 	CreatedAt   *string `json:"createdAt"`
 	UpdatedAt   *string `json:"updatedAt"`
 }
+
+// Resource declares the leader table and its routes under /leaders
+var Resource = resource.New[Leader](resource.Definition{
+	Name:         "leader",
+	Path:         "/leaders",
+	Table:        "leader",
+	Columns:      []string{"name", "image", "designation", "abbr", "featured", "description"},
+	CacheControl: "public, max-age=300",
+})
@@ -0,0 +1,18 @@
+package leaders
+
+import (
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/resource"
+)
+
+// LeaderStore is the persistence layer used by the leader handlers, a
+// resource.Store of the leader table.
+type LeaderStore = resource.Store[Leader]
+
+func NewDbStore(db *database.Conn) LeaderStore {
+	return resource.NewDbStore(db, Resource)
+}
+
+func NewMemoryStore() LeaderStore {
+	return resource.NewMemoryStore(Resource)
+}
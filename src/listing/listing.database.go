@@ -0,0 +1,135 @@
+package listing
+
+import (
+	"strings"
+)
+
+// Where returns the WHERE conditions of the filters, to be joined with AND, and their arguments
+func (p Params) Where() ([]string, []interface{}) {
+
+	conditions := make([]string, 0, len(p.Filters))
+	args := make([]interface{}, 0, len(p.Filters))
+	for _, filter := range p.Filters {
+		conditions = append(conditions, filter.Field.Column+" "+filter.Op+" ?")
+		args = append(args, filter.Value)
+	}
+	return conditions, args
+}
+
+// Seek returns the WHERE condition of the rows after the cursor, or before
+// it when paging backwards, and its arguments, none without a cursor. It is
+// left out of the count of the rows.
+func (p Params) Seek() (string, []interface{}) {
+
+	if p.Cursor == nil {
+		return "", nil
+	}
+
+	// the rows whose first keys equal the cursor and whose next key follows it
+	alternatives := make([]string, 0, len(p.Sort))
+	args := make([]interface{}, 0)
+	for i, key := range p.Sort {
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			condition, conditionArgs := equalCondition(p.Sort[j].Field.Column, p.Cursor.Values[j])
+			conditions = append(conditions, condition)
+			args = append(args, conditionArgs...)
+		}
+		condition, conditionArgs := followCondition(key.Field, p.Cursor.Values[i], key.Desc != p.Cursor.Before)
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+func equalCondition(column string, value interface{}) (string, []interface{}) {
+
+	if value == nil {
+		return column + " IS NULL", nil
+	}
+	return column + " = ?", []interface{}{value}
+}
+
+// followCondition returns the condition of the values of field after value,
+// NULL being below any value as OrderBy sorts it. The ids are never NULL.
+func followCondition(field Field, value interface{}, desc bool) (string, []interface{}) {
+
+	column := field.Column
+	switch {
+	case value == nil && desc:
+		return "1 = 0", nil
+	case value == nil:
+		return column + " IS NOT NULL", nil
+	case desc && field.Name != IdField:
+		return "(" + column + " < ? OR " + column + " IS NULL)", []interface{}{value}
+	case desc:
+		return column + " < ?", []interface{}{value}
+	}
+	return column + " > ?", []interface{}{value}
+}
+
+// OrderBy returns the ORDER BY clause of the sort keys, reversed when paging
+// backwards. It ends with the tiebreak column, unique to each row, so the
+// pages never overlap. NULL comes first, as MySQL and SQLite sort it and
+// PostgreSQL does not.
+func (p Params) OrderBy(tiebreak string) string {
+
+	backwards := p.Cursor != nil && p.Cursor.Before
+	keys := make([]string, 0, 2*len(p.Sort)+1)
+	sortedOnTiebreak := false
+	for _, key := range p.Sort {
+		if key.Field.Column == tiebreak {
+			sortedOnTiebreak = true
+			keys = append(keys, orderByKey(tiebreak, key.Desc != backwards))
+			continue
+		}
+		if key.Desc != backwards {
+			keys = append(keys, key.Field.Column+" IS NULL", key.Field.Column+" DESC")
+		} else {
+			keys = append(keys, key.Field.Column+" IS NULL DESC", key.Field.Column)
+		}
+	}
+	if !sortedOnTiebreak {
+		keys = append(keys, orderByKey(tiebreak, backwards))
+	}
+	return ` ORDER BY ` + strings.Join(keys, ", ")
+}
+
+func orderByKey(column string, desc bool) string {
+
+	if desc {
+		return column + " DESC"
+	}
+	return column
+}
+
+// Page returns the LIMIT clause of the page and its arguments, none when
+// listing all the rows. It reads a row more than the page, which tells Trim
+// whether more rows follow.
+func (p Params) Page() (string, []interface{}) {
+
+	if p.Limit == 0 {
+		return "", nil
+	}
+	if p.Offset > 0 {
+		return ` LIMIT ? OFFSET ?`, []interface{}{p.Limit + 1, p.Offset}
+	}
+	return ` LIMIT ?`, []interface{}{p.Limit + 1}
+}
+
+// Trim cuts the rows read through Page down to the page, in the order of the
+// sort keys, and tells if more rows follow it
+func Trim[T any](rows []T, p Params) ([]T, bool) {
+
+	more := p.Limit > 0 && len(rows) > p.Limit
+	if more {
+		rows = rows[:p.Limit]
+	}
+	if p.Cursor != nil && p.Cursor.Before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	return rows, more
+}
@@ -0,0 +1,75 @@
+package listing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeekAndOrderBy(t *testing.T) {
+
+	priceDesc := []SortKey{{Field: testPriceField, Desc: true}, {Field: testIdField}}
+	for _, test := range []struct {
+		name        string
+		params      Params
+		wantSeek    string
+		wantArgs    []interface{}
+		wantOrderBy string
+	}{
+		{"no cursor", Params{Sort: priceDesc}, "", nil,
+			" ORDER BY price IS NULL, price DESC, id"},
+		{"no sort", Params{}, "", nil, " ORDER BY id"},
+		{"after", Params{Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{float64(3), float64(7)}}},
+			"(((price < ? OR price IS NULL)) OR (price = ? AND id > ?))", []interface{}{float64(3), float64(3), float64(7)},
+			" ORDER BY price IS NULL, price DESC, id"},
+		{"before", Params{Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{float64(3), float64(7)}, Before: true}},
+			"((price > ?) OR (price = ? AND id < ?))", []interface{}{float64(3), float64(3), float64(7)},
+			" ORDER BY price IS NULL DESC, price, id DESC"},
+		{"after null", Params{Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{nil, float64(7)}}},
+			"((1 = 0) OR (price IS NULL AND id > ?))", []interface{}{float64(7)},
+			" ORDER BY price IS NULL, price DESC, id"},
+		{"before null", Params{Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{nil, float64(7)}, Before: true}},
+			"((price IS NOT NULL) OR (price IS NULL AND id < ?))", []interface{}{float64(7)},
+			" ORDER BY price IS NULL DESC, price, id DESC"},
+		{"id descending", Params{Sort: []SortKey{{Field: testIdField, Desc: true}},
+			Cursor: &Cursor{Values: []interface{}{float64(7)}}},
+			"((id < ?))", []interface{}{float64(7)}, " ORDER BY id DESC"},
+	} {
+
+		seek, args := test.params.Seek()
+		if seek != test.wantSeek || !reflect.DeepEqual(args, test.wantArgs) {
+			t.Errorf("%s: Seek: got %q %v, want %q %v", test.name, seek, args, test.wantSeek, test.wantArgs)
+		}
+		if orderBy := test.params.OrderBy("id"); orderBy != test.wantOrderBy {
+			t.Errorf("%s: OrderBy: got %q, want %q", test.name, orderBy, test.wantOrderBy)
+		}
+	}
+}
+
+func TestPageAndTrim(t *testing.T) {
+
+	for _, test := range []struct {
+		name     string
+		params   Params
+		rows     []int
+		wantPage string
+		wantArgs []interface{}
+		wantRows []int
+		wantMore bool
+	}{
+		{"all", Params{}, []int{1, 2, 3}, "", nil, []int{1, 2, 3}, false},
+		{"more", Params{Limit: 2}, []int{1, 2, 3}, " LIMIT ?", []interface{}{3}, []int{1, 2}, true},
+		{"last", Params{Limit: 2}, []int{1, 2}, " LIMIT ?", []interface{}{3}, []int{1, 2}, false},
+		{"offset", Params{Limit: 2, Offset: 4}, []int{5}, " LIMIT ? OFFSET ?", []interface{}{3, 4}, []int{5}, false},
+		{"before", Params{Limit: 2, Cursor: &Cursor{Before: true}}, []int{3, 2, 1}, " LIMIT ?", []interface{}{3},
+			[]int{2, 3}, true},
+	} {
+
+		page, args := test.params.Page()
+		if page != test.wantPage || !reflect.DeepEqual(args, test.wantArgs) {
+			t.Errorf("%s: Page: got %q %v, want %q %v", test.name, page, args, test.wantPage, test.wantArgs)
+		}
+		if rows, more := Trim(test.rows, test.params); !reflect.DeepEqual(rows, test.wantRows) || more != test.wantMore {
+			t.Errorf("%s: Trim: got %v %v, want %v %v", test.name, rows, more, test.wantRows, test.wantMore)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"confusion.com/bwoo/misc"
+)
+
+// The collections are listed a page at a time and can be sorted and filtered:
+//
+//	?limit=10&cursor=...    the page size and where the page starts, the cursor
+//	                        comes from the Link header of the previous page
+//	?offset=20              the number of rows before the page, kept for the
+//	                        clients which paged by offset
+//	?sort=price,-createdAt  the fields to sort on, a - sorts descending
+//	?category=mains         the rows whose field equals the value
+//	?price_lt=5             the rows whose field is below the value, likewise
+//	                        _lte, _gt, _gte and _ne
+//
+// The other parameters are left to the handlers, or ignored.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// IdField is the name of the field the pages are sorted on last, unique to each row
+const IdField = "_id"
+
+type FieldType int
+
+const (
+	String FieldType = iota
+	Number
+	Bool
+	// Timestamp fields can only be sorted on
+	Timestamp
+)
+
+// Field is a field of a model the collection can be sorted and filtered on
+type Field struct {
+	// Name is the JSON name of the field, used in the query string
+	Name string
+	// Column is the column of the field in the SQL query
+	Column string
+	Type   FieldType
+}
+
+type Filter struct {
+	Field Field
+	// Op is the SQL comparison operator
+	Op string
+	// Value is a string, a float64 or a bool, as the type of the field
+	Value interface{}
+}
+
+type SortKey struct {
+	Field Field
+	Desc  bool
+}
+
+// Cursor is the row a page starts after, by the values of its sort keys
+type Cursor struct {
+	// Values are the values of the sort keys of the row, as Filter.Value or nil
+	Values []interface{} `json:"v"`
+	// Before pages backwards: the page ends before the row
+	Before bool `json:"b,omitempty"`
+}
+
+// Params selects the page of a collection, the zero value lists all of it
+type Params struct {
+	// Limit is the maximum number of rows of the page, 0 for all of them
+	Limit int
+	// Cursor is where the page starts, nil for the first page
+	Cursor *Cursor
+	// Offset is the number of rows before the page, with a Limit and no Cursor
+	Offset  int
+	Sort    []SortKey
+	Filters []Filter
+}
+
+// PageInfo tells how a page lies in the collection
+type PageInfo struct {
+	// Total is the number of rows matching the filters
+	Total int
+	// More tells if rows follow the page, or precede it when paging backwards
+	More bool
+}
+
+// operators by the suffix of the filter parameters
+var operators = map[string]string{
+	"":    "=",
+	"ne":  "<>",
+	"lt":  "<",
+	"lte": "<=",
+	"gt":  ">",
+	"gte": ">=",
+}
+
+// Parse reads the page of the query string, sorted and filtered on fields.
+// The pages are sorted on the IdField of fields last, so the cursors always
+// point at a single row.
+func Parse(values url.Values, fields []Field) (Params, error) {
+
+	params := Params{Limit: DefaultLimit}
+	details := make([]misc.FieldError, 0)
+
+	fieldsByName := make(map[string]Field)
+	for _, field := range fields {
+		fieldsByName[field.Name] = field
+	}
+
+	// sorted, so the errors and the filters come in the same order every time
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := values.Get(name)
+		switch name {
+		case "limit":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > MaxLimit {
+				details = append(details, misc.FieldError{Field: name, Message: fmt.Sprintf("expected 1 to %d", MaxLimit)})
+			}
+			params.Limit = limit
+
+		case "cursor":
+			cursor, err := decodeCursor(value)
+			if err != nil {
+				details = append(details, misc.FieldError{Field: name, Message: "invalid cursor"})
+			}
+			params.Cursor = cursor
+
+		case "offset":
+			offset, err := strconv.Atoi(value)
+			if err != nil || offset < 0 {
+				details = append(details, misc.FieldError{Field: name, Message: "expected 0 or more"})
+			}
+			params.Offset = offset
+
+		case "sort":
+			// an empty sort= is a mistake rather than the default order
+			for _, key := range strings.Split(value, ",") {
+				field, ok := fieldsByName[strings.TrimPrefix(key, "-")]
+				if key == "" || key == "-" {
+					details = append(details, misc.FieldError{Field: name, Message: "expected a field to sort on"})
+					continue
+				}
+				if !ok {
+					details = append(details, misc.FieldError{Field: name, Message: "cannot sort on " + key})
+					continue
+				}
+				params.Sort = append(params.Sort, SortKey{Field: field, Desc: strings.HasPrefix(key, "-")})
+			}
+
+		default:
+			filters, fieldErr := parseFilters(name, values[name], fieldsByName)
+			if fieldErr != nil {
+				details = append(details, *fieldErr)
+			}
+			params.Filters = append(params.Filters, filters...)
+		}
+	}
+
+	if idField, ok := fieldsByName[IdField]; ok && !params.isSortedOn(idField) {
+		params.Sort = append(params.Sort, SortKey{Field: idField})
+	}
+
+	if params.Cursor != nil {
+		if params.Offset > 0 {
+			details = append(details, misc.FieldError{Field: "offset", Message: "cannot be combined with cursor"})
+		}
+		if !params.Cursor.matches(params.Sort) {
+			details = append(details, misc.FieldError{Field: "cursor", Message: "does not match the sort"})
+		}
+	}
+
+	if len(details) > 0 {
+		return Params{}, misc.NewError(misc.ErrorCodeBadRequest, "Invalid query parameters", details...)
+	}
+	return params, nil
+}
+
+func parseFilters(name string, values []string, fieldsByName map[string]Field) ([]Filter, *misc.FieldError) {
+
+	fieldName, suffix := name, ""
+	if i := strings.LastIndex(name, "_"); i >= 0 {
+		if _, ok := operators[name[i+1:]]; ok {
+			fieldName, suffix = name[:i], name[i+1:]
+		}
+	}
+
+	// the parameters of no field are not filters
+	field, ok := fieldsByName[fieldName]
+	if !ok {
+		return nil, nil
+	}
+	if field.Type == Timestamp {
+		return nil, &misc.FieldError{Field: name, Message: "cannot filter on " + fieldName}
+	}
+
+	filters := make([]Filter, 0, len(values))
+	for _, value := range values {
+		var typedValue interface{}
+		var err error
+		switch field.Type {
+		case Number:
+			typedValue, err = strconv.ParseFloat(value, 64)
+		case Bool:
+			typedValue, err = strconv.ParseBool(value)
+		default:
+			typedValue = value
+		}
+		if err != nil {
+			return nil, &misc.FieldError{Field: name, Message: "invalid value " + value}
+		}
+		filters = append(filters, Filter{Field: field, Op: operators[suffix], Value: typedValue})
+	}
+
+	return filters, nil
+}
+
+func (p Params) isSortedOn(field Field) bool {
+
+	for _, key := range p.Sort {
+		if key.Field.Column == field.Column {
+			return true
+		}
+	}
+	return false
+}
+
+// matches tells if the cursor holds a value of the type of each sort key,
+// the row of a cursor always has an id
+func (c *Cursor) matches(sort []SortKey) bool {
+
+	if len(c.Values) != len(sort) {
+		return false
+	}
+
+	for i, key := range sort {
+		var ok bool
+		switch c.Values[i].(type) {
+		case nil:
+			ok = key.Field.Name != IdField
+		case float64:
+			ok = key.Field.Type == Number
+		case bool:
+			ok = key.Field.Type == Bool
+		case string:
+			ok = key.Field.Type == String || key.Field.Type == Timestamp
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// the cursors are opaque to the clients, so the paging may change later on
+func encodeCursor(cursor Cursor) string {
+
+	cursorJson, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(cursorJson)
+}
+
+func decodeCursor(encoded string) (*Cursor, error) {
+
+	cursorJson, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(cursorJson, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,68 @@
+package listing
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WriteHeaders tells the client the number of rows matching the filters in
+// X-Total-Count, and where the next and previous pages are in the Link header.
+// The links point at the page through a cursor made of the sort keys of its
+// first or last item, read with get, or by offset when the request paged so.
+func WriteHeaders[T any](w http.ResponseWriter, r *http.Request, p Params, items []T, info PageInfo,
+	get func(item *T, field Field) interface{}) {
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(info.Total))
+	if p.Limit == 0 {
+		return
+	}
+
+	links := make([]string, 0, 2)
+	if r.URL.Query().Has("offset") {
+		if info.More {
+			links = append(links, getOffsetLink(r, p.Offset+p.Limit, "next"))
+		}
+		if p.Offset > 0 {
+			links = append(links, getOffsetLink(r, max(p.Offset-p.Limit, 0), "prev"))
+		}
+	} else if len(items) > 0 {
+		// the row of the cursor lies on the other side of the page, More
+		// tells about the rows on the side the cursor led to
+		backwards := p.Cursor != nil && p.Cursor.Before
+		if info.More || backwards {
+			links = append(links, getCursorLink(r, p, &items[len(items)-1], false, get, "next"))
+		}
+		if (info.More && backwards) || (p.Cursor != nil && !backwards) {
+			links = append(links, getCursorLink(r, p, &items[0], true, get, "prev"))
+		}
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// getCursorLink returns the link to the page after item, or before it, with
+// the same limit, sort and filters as the request
+func getCursorLink[T any](r *http.Request, p Params, item *T, before bool, get func(item *T, field Field) interface{},
+	rel string) string {
+
+	cursor := Cursor{Values: make([]interface{}, 0, len(p.Sort)), Before: before}
+	for _, key := range p.Sort {
+		cursor.Values = append(cursor.Values, get(item, key.Field))
+	}
+
+	query := r.URL.Query()
+	query.Set("cursor", encodeCursor(cursor))
+	return "<" + r.URL.Path + "?" + query.Encode() + `>; rel="` + rel + `"`
+}
+
+// getOffsetLink returns the link to the page starting at offset, with the
+// same limit, sort and filters as the request
+func getOffsetLink(r *http.Request, offset int, rel string) string {
+
+	query := r.URL.Query()
+	query.Set("offset", strconv.Itoa(offset))
+	return "<" + r.URL.Path + "?" + query.Encode() + `>; rel="` + rel + `"`
+}
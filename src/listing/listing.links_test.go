@@ -0,0 +1,78 @@
+package listing
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var testLinkPattern = regexp.MustCompile(`<([^>]+)>; rel="(\w+)"`)
+
+// getTestLinks returns the query strings of the Link header by rel
+func getTestLinks(t *testing.T, header string) map[string]url.Values {
+
+	t.Helper()
+
+	links := make(map[string]url.Values)
+	for _, match := range testLinkPattern.FindAllStringSubmatch(header, -1) {
+		link, err := url.Parse(match[1])
+		if err != nil {
+			t.Fatalf("Link %s: %v", match[1], err)
+		}
+		links[match[2]] = link.Query()
+	}
+	return links
+}
+
+func TestWriteHeaders(t *testing.T) {
+
+	priceDesc := []SortKey{{Field: testPriceField, Desc: true}, {Field: testIdField}}
+	page := testRows[:2]
+	first := Cursor{Values: []interface{}{float64(3), float64(1)}, Before: true}
+	last := Cursor{Values: []interface{}{float64(1), float64(2)}}
+
+	for _, test := range []struct {
+		name     string
+		query    string
+		params   Params
+		items    []testRow
+		info     PageInfo
+		wantNext string
+		wantPrev string
+	}{
+		{"all", "", Params{}, testRows, PageInfo{Total: 5}, "", ""},
+		{"first page", "limit=2", Params{Limit: 2, Sort: priceDesc}, page, PageInfo{Total: 5, More: true},
+			"cursor=" + encodeCursor(last) + "&limit=2", ""},
+		{"only page", "limit=2", Params{Limit: 2, Sort: priceDesc}, page, PageInfo{Total: 2}, "", ""},
+		{"middle page", "limit=2&cursor=x", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{}}, page,
+			PageInfo{Total: 5, More: true},
+			"cursor=" + encodeCursor(last) + "&limit=2", "cursor=" + encodeCursor(first) + "&limit=2"},
+		{"last page", "limit=2&cursor=x", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{}}, page,
+			PageInfo{Total: 5}, "", "cursor=" + encodeCursor(first) + "&limit=2"},
+		{"back to the first page", "limit=2&cursor=x", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{Before: true}},
+			page, PageInfo{Total: 5}, "cursor=" + encodeCursor(last) + "&limit=2", ""},
+		{"empty page", "limit=2&cursor=x", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{}}, nil,
+			PageInfo{Total: 5}, "", ""},
+		{"offset", "limit=2&offset=2", Params{Limit: 2, Offset: 2, Sort: priceDesc}, page, PageInfo{Total: 5, More: true},
+			"limit=2&offset=4", "limit=2&offset=0"},
+		{"first offset", "limit=2&offset=0", Params{Limit: 2, Sort: priceDesc}, page, PageInfo{Total: 5, More: true},
+			"limit=2&offset=2", ""},
+	} {
+
+		w := httptest.NewRecorder()
+		WriteHeaders(w, httptest.NewRequest("GET", "/items?"+test.query, nil), test.params, test.items, test.info,
+			getTestField)
+
+		if total := w.Header().Get("X-Total-Count"); total != strconv.Itoa(test.info.Total) {
+			t.Errorf("%s: got X-Total-Count %q, want %d", test.name, total, test.info.Total)
+		}
+		links := getTestLinks(t, w.Header().Get("Link"))
+		for rel, want := range map[string]string{"next": test.wantNext, "prev": test.wantPrev} {
+			if got, ok := links[rel]; (ok || want != "") && got.Encode() != want {
+				t.Errorf("%s: got %s link %q, want %q", test.name, rel, got.Encode(), want)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package listing
+
+import (
+	"sort"
+	"strings"
+)
+
+// Apply filters, sorts and pages items the way the SQL stores do, get returns
+// the value of a field of an item: a string, a float64, a bool or nil. The
+// items are expected in the order of their ids, which breaks the ties.
+func Apply[T any](items []T, p Params, get func(item *T, field Field) interface{}) ([]T, PageInfo) {
+
+	matching := make([]T, 0, len(items))
+	for i := range items {
+		if matches(&items[i], p.Filters, get) {
+			matching = append(matching, items[i])
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		for _, key := range p.Sort {
+			c := compare(get(&matching[i], key.Field), get(&matching[j], key.Field))
+			if c != 0 {
+				return (c < 0) != key.Desc
+			}
+		}
+		return false
+	})
+
+	info := PageInfo{Total: len(matching)}
+	if p.Limit == 0 {
+		return matching, info
+	}
+
+	start := min(p.Offset, len(matching))
+	if p.Cursor != nil {
+		// the first row after the cursor
+		start = sort.Search(len(matching), func(i int) bool { return compareCursor(&matching[i], p, get) > 0 })
+		if p.Cursor.Before {
+			end := sort.Search(len(matching), func(i int) bool { return compareCursor(&matching[i], p, get) >= 0 })
+			start = max(end-p.Limit, 0)
+			info.More = start > 0
+			return matching[start:end], info
+		}
+	}
+
+	end := min(start+p.Limit, len(matching))
+	info.More = end < len(matching)
+	return matching[start:end], info
+}
+
+// compareCursor returns -1, 0 or 1 as item comes before, at or after the
+// row of the cursor in the sort order
+func compareCursor[T any](item *T, p Params, get func(item *T, field Field) interface{}) int {
+
+	for i, key := range p.Sort {
+		c := compare(get(item, key.Field), p.Cursor.Values[i])
+		if c != 0 {
+			if key.Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+func matches[T any](item *T, filters []Filter, get func(item *T, field Field) interface{}) bool {
+
+	for _, filter := range filters {
+		value := get(item, filter.Field)
+		// like NULL in SQL, a missing value matches no filter
+		if value == nil {
+			return false
+		}
+
+		c := compare(value, filter.Value)
+		var ok bool
+		switch filter.Op {
+		case "=":
+			ok = c == 0
+		case "<>":
+			ok = c != 0
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compare returns -1, 0 or 1 as a is below, equal to or above b, nil being below anything
+func compare(a, b interface{}) int {
+
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	switch a := a.(type) {
+	case float64:
+		b, _ := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case bool:
+		b, _ := b.(bool)
+		switch {
+		case a == b:
+			return 0
+		case !a:
+			return -1
+		}
+		return 1
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	}
+	return 0
+}
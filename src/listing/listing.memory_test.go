@@ -0,0 +1,79 @@
+package listing
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testRow struct {
+	id    int
+	name  string
+	price interface{}
+}
+
+func getTestField(row *testRow, field Field) interface{} {
+
+	switch field.Name {
+	case IdField:
+		return float64(row.id)
+	case "name":
+		return row.name
+	case "price":
+		return row.price
+	}
+	return nil
+}
+
+// the rows in the order of their ids, the price of e is NULL
+var testRows = []testRow{
+	{1, "a", float64(3)}, {2, "b", float64(1)}, {3, "c", float64(3)}, {4, "d", nil}, {5, "e", float64(2)},
+}
+
+func getTestIds(rows []testRow) []int {
+
+	ids := make([]int, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.id)
+	}
+	return ids
+}
+
+func TestApply(t *testing.T) {
+
+	priceDesc := []SortKey{{Field: testPriceField, Desc: true}, {Field: testIdField}}
+	for _, test := range []struct {
+		name      string
+		params    Params
+		wantIds   []int
+		wantTotal int
+		wantMore  bool
+	}{
+		{"all", Params{}, []int{1, 2, 3, 4, 5}, 5, false},
+		{"sorted", Params{Sort: []SortKey{{Field: testPriceField}, {Field: testIdField}}}, []int{4, 2, 5, 1, 3}, 5, false},
+		{"descending", Params{Sort: priceDesc}, []int{1, 3, 5, 2, 4}, 5, false},
+		{"filtered", Params{Filters: []Filter{{Field: testPriceField, Op: ">=", Value: float64(2)}}}, []int{1, 3, 5}, 3, false},
+		{"not equal", Params{Filters: []Filter{{Field: testNameField, Op: "<>", Value: "a"}}}, []int{2, 3, 4, 5}, 4, false},
+		{"first page", Params{Limit: 2, Sort: priceDesc}, []int{1, 3}, 5, true},
+		{"offset", Params{Limit: 2, Offset: 4, Sort: priceDesc}, []int{4}, 5, false},
+		{"after", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{float64(3), float64(3)}}},
+			[]int{5, 2}, 5, true},
+		{"after to the end", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{float64(2), float64(5)}}},
+			[]int{2, 4}, 5, false},
+		{"after null", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{nil, float64(4)}}},
+			[]int{}, 5, false},
+		{"before", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{nil, float64(4)}, Before: true}},
+			[]int{5, 2}, 5, true},
+		{"before to the start", Params{Limit: 2, Sort: priceDesc,
+			Cursor: &Cursor{Values: []interface{}{float64(2), float64(5)}, Before: true}}, []int{1, 3}, 5, false},
+		{"deleted cursor row", Params{Limit: 2, Sort: priceDesc, Cursor: &Cursor{Values: []interface{}{float64(3), float64(2)}}},
+			[]int{3, 5}, 5, true},
+	} {
+
+		page, info := Apply(testRows, test.params, getTestField)
+		if ids := getTestIds(page); !reflect.DeepEqual(ids, test.wantIds) || info.Total != test.wantTotal ||
+			info.More != test.wantMore {
+			t.Errorf("%s: got %v %+v, want %v total %d more %v", test.name, ids, info, test.wantIds, test.wantTotal,
+				test.wantMore)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package listing
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"confusion.com/bwoo/misc"
+)
+
+var (
+	testIdField       = Field{Name: IdField, Column: "id", Type: Number}
+	testNameField     = Field{Name: "name", Column: "name", Type: String}
+	testPriceField    = Field{Name: "price", Column: "price", Type: Number}
+	testFeaturedField = Field{Name: "featured", Column: "featured", Type: Bool}
+	testCreatedField  = Field{Name: "createdAt", Column: "createdAt", Type: Timestamp}
+	testFields        = []Field{testIdField, testNameField, testPriceField, testFeaturedField, testCreatedField}
+)
+
+func TestParse(t *testing.T) {
+
+	for _, test := range []struct {
+		query string
+		want  Params
+	}{
+		{"", Params{Limit: DefaultLimit, Sort: []SortKey{{Field: testIdField}}}},
+		{"limit=10&offset=20", Params{Limit: 10, Offset: 20, Sort: []SortKey{{Field: testIdField}}}},
+		{"sort=price,-createdAt", Params{Limit: DefaultLimit, Sort: []SortKey{
+			{Field: testPriceField}, {Field: testCreatedField, Desc: true}, {Field: testIdField}}}},
+		{"sort=-_id,price", Params{Limit: DefaultLimit, Sort: []SortKey{
+			{Field: testIdField, Desc: true}, {Field: testPriceField}}}},
+		{"name=soup&price_lt=5&featured=true&_=123&callback=x", Params{Limit: DefaultLimit,
+			Sort: []SortKey{{Field: testIdField}},
+			Filters: []Filter{
+				{Field: testFeaturedField, Op: "=", Value: true},
+				{Field: testNameField, Op: "=", Value: "soup"},
+				{Field: testPriceField, Op: "<", Value: float64(5)},
+			}}},
+		{"price_gte=1&price_ne=2", Params{Limit: DefaultLimit, Sort: []SortKey{{Field: testIdField}},
+			Filters: []Filter{
+				{Field: testPriceField, Op: ">=", Value: float64(1)},
+				{Field: testPriceField, Op: "<>", Value: float64(2)},
+			}}},
+		{"sort=-price&cursor=" + encodeCursor(Cursor{Values: []interface{}{nil, float64(3)}, Before: true}),
+			Params{Limit: DefaultLimit, Cursor: &Cursor{Values: []interface{}{nil, float64(3)}, Before: true},
+				Sort: []SortKey{{Field: testPriceField, Desc: true}, {Field: testIdField}}}},
+	} {
+
+		values, _ := url.ParseQuery(test.query)
+		params, err := Parse(values, testFields)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", test.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(params, test.want) {
+			t.Errorf("Parse(%s): got %+v, want %+v", test.query, params, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	for _, test := range []struct {
+		query  string
+		fields []string
+	}{
+		{"limit=0&offset=-1", []string{"limit", "offset"}},
+		{"limit=1001&sort=color", []string{"limit", "sort"}},
+		{"price=cheap&featured=maybe", []string{"featured", "price"}},
+		{"createdAt_gt=2026-10-16", []string{"createdAt_gt"}},
+		{"sort=", []string{"sort"}},
+		{"sort=price,&limit=2", []string{"sort"}},
+		{"cursor=garbage!", []string{"cursor"}},
+		{"cursor=" + encodeCursor(Cursor{Values: []interface{}{float64(1)}}) + "&offset=2", []string{"offset"}},
+		{"sort=price&cursor=" + encodeCursor(Cursor{Values: []interface{}{float64(1)}}), []string{"cursor"}},
+		{"sort=price&cursor=" + encodeCursor(Cursor{Values: []interface{}{"1", float64(1)}}), []string{"cursor"}},
+		{"cursor=" + encodeCursor(Cursor{Values: []interface{}{nil}}), []string{"cursor"}},
+	} {
+
+		values, _ := url.ParseQuery(test.query)
+		_, err := Parse(values, testFields)
+		merr, ok := err.(*misc.Error)
+		if !ok || merr.Code != misc.ErrorCodeBadRequest {
+			t.Errorf("Parse(%s): got %v, want a bad request", test.query, err)
+			continue
+		}
+
+		fields := make([]string, 0, len(merr.Details))
+		for _, detail := range merr.Details {
+			fields = append(fields, detail.Field)
+		}
+		if !reflect.DeepEqual(fields, test.fields) {
+			t.Errorf("Parse(%s): got errors on %v, want %v", test.query, fields, test.fields)
+		}
+	}
+}
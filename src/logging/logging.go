@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"confusion.com/bwoo/config"
+)
+
+// Supported values of log_format
+const (
+	JsonFormat = "json"
+	TextFormat = "text"
+)
+
+const redacted = "[REDACTED]"
+
+// attributes with one of these in their lower cased key are never written
+var sensitiveKeys = []string{"password", "passwd", "token", "secret", "authorization", "jwt"}
+
+// sensitive query parameters and Bearer tokens inside messages, e.g. in the url of an error
+var sensitiveValueRegexp = regexp.MustCompile(`(?i)((?:access_token|token|password|passwd|secret)=)[^&\s"]+|(Bearer )[\w.~+/=-]+`)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestKey
+)
+
+// Setup makes the logger of log_level and log_format the default logger.
+// The std logger writes through it at level ERROR, as it is left to log.Fatal
+// and to the errors of the libraries, e.g. of net/http.
+func Setup(config config.Config) {
+
+	handler := newHandler(os.Stderr, config.LogFormat, config.LogLevel)
+	slog.SetDefault(slog.New(handler))
+	log.SetOutput(slog.NewLogLogger(handler, slog.LevelError).Writer())
+}
+
+func newHandler(w io.Writer, format, level string) slog.Handler {
+
+	var logLevel slog.Level
+	logLevel.UnmarshalText([]byte(level))
+
+	options := &slog.HandlerOptions{Level: logLevel, ReplaceAttr: redact}
+	if format == TextFormat {
+		return slog.NewTextHandler(w, options)
+	}
+	return slog.NewJSONHandler(w, options)
+}
+
+// redact hides the value of tokens and passwords, whichever package logs them
+func redact(groups []string, attr slog.Attr) slog.Attr {
+
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch value := attr.Value.Any().(type) {
+	case string:
+		return slog.String(attr.Key, redactValue(value))
+	case error:
+		return slog.String(attr.Key, redactValue(value.Error()))
+	}
+	return attr
+}
+
+func redactValue(value string) string {
+	return sensitiveValueRegexp.ReplaceAllString(value, "$1$2"+redacted)
+}
+
+func isSensitiveKey(key string) bool {
+
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromContext returns the logger of the request ctx belongs to, which adds
+// the request id and, once the user is verified, the user id to every line.
+// Outside of a request it returns the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// GetRequestId returns the id of the request ctx belongs to
+func GetRequestId(ctx context.Context) string {
+
+	if info, ok := ctx.Value(requestKey).(*requestInfo); ok {
+		return info.requestId
+	}
+	return ""
+}
+
+// WithUserId returns a copy of ctx whose logger adds the user id, which also
+// goes to the access log line of the request
+func WithUserId(ctx context.Context, userId string) context.Context {
+
+	if info, ok := ctx.Value(requestKey).(*requestInfo); ok {
+		info.userId = userId
+	}
+	return WithLogger(ctx, FromContext(ctx).With("userId", userId))
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"confusion.com/bwoo/recorder"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIdHeader carries the id of a request. An id sent by the client, e.g.
+// by a proxy in front of the server, is kept, otherwise one is generated.
+const RequestIdHeader = "X-Request-ID"
+
+// ids sent by clients end up in the logs, so only plain ones are accepted
+var requestIdRegexp = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestInfo is shared by the access log and the handlers, which only see
+// copies of the request
+type requestInfo struct {
+	requestId string
+	userId    string
+}
+
+func getRequestId(r *http.Request) string {
+
+	if requestId := r.Header.Get(RequestIdHeader); requestIdRegexp.MatchString(requestId) {
+		return requestId
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog gives every request an id, echoed in the X-Request-ID response
+// header, and a logger adding it, and the trace id of a traced request, to
+// every line, then logs one line per request
+// with its method, path, status, latency, response size and user id.
+// The query is left out as it may carry an access_token.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+		info := &requestInfo{requestId: getRequestId(r)}
+		w.Header().Set(RequestIdHeader, info.requestId)
+
+		logger := slog.Default().With("requestId", info.requestId)
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+			logger = logger.With("traceId", spanContext.TraceID().String())
+		}
+		ctx := WithLogger(r.Context(), logger)
+		ctx = context.WithValue(ctx, requestKey, info)
+
+		rec := recorder.New(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.Status(),
+			"latencyMs", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", rec.Bytes(),
+			"remoteAddr", r.RemoteAddr,
+		}
+		if info.userId != "" {
+			attrs = append(attrs, "userId", info.userId)
+		}
+		logger.Info("request", attrs...)
+	})
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLogs makes a JSON logger writing to the returned buffer the default logger for the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(newHandler(&buf, JsonFormat, "info")))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestRequestIdHeader(t *testing.T) {
+
+	for _, test := range []struct {
+		name      string
+		requestId string
+		keep      bool
+	}{
+		{"uuid", "0b6a2bd4-5f14-4b8e-9a51-3c1f0f6a8f0e", true},
+		{"proxy id", "host-1:1700000000.123_42", true},
+		{"longest", strings.Repeat("a", 128), true},
+		{"too long", strings.Repeat("a", 129), false},
+		{"space", "abc def", false},
+		{"new line", "abc\nlevel=ERROR msg=forged", false},
+		{"quote", `abc"}`, false},
+		{"none", "", false},
+	} {
+
+		captureLogs(t)
+		var seen string
+		handler := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = GetRequestId(r.Context())
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/dishes", nil)
+		if test.requestId != "" {
+			r.Header.Set(RequestIdHeader, test.requestId)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		echoed := w.Header().Get(RequestIdHeader)
+		if echoed != seen {
+			t.Errorf("%s: got %q in the header and %q in the handler, want the same id", test.name, echoed, seen)
+		}
+		if test.keep && echoed != test.requestId {
+			t.Errorf("%s: got %q, want the id of the client", test.name, echoed)
+		}
+		if !test.keep && (echoed == test.requestId || len(echoed) != 32) {
+			t.Errorf("%s: got %q, want a generated id", test.name, echoed)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+
+	buf := captureLogs(t)
+	handler := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		r = r.WithContext(WithUserId(r.Context(), "42"))
+		FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/auth/facebook/token?access_token=abc123", nil)
+	r.Header.Set(RequestIdHeader, "request-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if strings.Contains(buf.String(), "abc123") {
+		t.Errorf("AccessLog: got %s, want no query", buf.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("AccessLog: got %d lines, want the line of the handler and the access log", len(lines))
+	}
+	var handled, request map[string]any
+	json.Unmarshal([]byte(lines[0]), &handled)
+	json.Unmarshal([]byte(lines[1]), &request)
+
+	if handled["requestId"] != "request-1" || handled["userId"] != "42" {
+		t.Errorf("handler line: got %v, want the request and user ids", handled)
+	}
+	for key, want := range map[string]any{
+		"msg":       "request",
+		"requestId": "request-1",
+		"method":    http.MethodPost,
+		"path":      "/auth/facebook/token",
+		"status":    float64(http.StatusCreated),
+		"bytes":     float64(4),
+		"userId":    "42",
+	} {
+		if request[key] != want {
+			t.Errorf("access log %s: got %v, want %v", key, request[key], want)
+		}
+	}
+}
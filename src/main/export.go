@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/dbjson"
+)
+
+// runExportCommand implements "main export [-users] [-favorites] [file]",
+// writing the database in the db.json format to file or to stdout
+func runExportCommand(dbConfig config.Config, args []string) {
+
+	if dbConfig.DbDriver == config.MemoryDriver {
+		log.Fatal("db_driver memory starts empty, there is nothing to export")
+	}
+
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: main export [-users] [-favorites] [file]")
+		flags.PrintDefaults()
+	}
+	var options dbjson.ExportOptions
+	flags.BoolVar(&options.Users, "users", false, "include the users, without their passwords")
+	flags.BoolVar(&options.Favorites, "favorites", false, "include the favorite dishes of every user")
+	flags.Parse(args)
+
+	stores := setupStores(dbConfig)
+	defer database.DbConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	doc, err := dbjson.Export(ctx, getDbJsonStores(stores), options)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var out io.Writer = os.Stdout
+	if flags.NArg() > 0 {
+		file, err := os.Create(flags.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := dbjson.WriteDocument(out, doc); err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("Exported the database", "dishes", len(doc.Dishes), "promotions", len(doc.Promotions),
+		"leaders", len(doc.Leaders), "users", len(doc.Users), "favorites", len(doc.Favorites))
+}
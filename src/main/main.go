@@ -1,106 +1,259 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
-	"path/filepath"
-	"strings"
+	"os"
+	"time"
 
 	"confusion.com/bwoo/favoriteDishes"
 
 	"confusion.com/bwoo/config"
 	"confusion.com/bwoo/cors"
-	"confusion.com/bwoo/misc"
 	"confusion.com/bwoo/oauth2"
 
 	"confusion.com/bwoo/upload"
 
 	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/cache"
 	"confusion.com/bwoo/comments"
 	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/dbjson"
 	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/health"
 	"confusion.com/bwoo/leaders"
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/metrics"
+	"confusion.com/bwoo/misc"
 	"confusion.com/bwoo/promotions"
+	"confusion.com/bwoo/ratelimit"
+	"confusion.com/bwoo/resource"
+	"confusion.com/bwoo/search"
+	"confusion.com/bwoo/suggest"
+	"confusion.com/bwoo/tlscert"
+	"confusion.com/bwoo/tracing"
 	"github.com/julienschmidt/httprouter"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
-const serverListenPort = "0.0.0.0:3000"
-const sslPort = ":3443"
-const serverListenSslPort = "0.0.0.0" + sslPort
-const certPath = "../../certs/www.confusion.com.crt"
-const keyPath = "../../certs/www.confusion.com.key"
+// time given to the spans not sent yet when the server stops
+const tracingShutdownTimeout = 5 * time.Second
 
 func getIndex(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	fmt.Fprint(w, "Welcome to ConFusion!\n")
 }
 
-func setupDefaultRoutes(router *httprouter.Router) {
+func setupDefaultRoutes(router *misc.Router) {
 	router.GET("/", getIndex)
-}
 
-func redirectToSecurePort(w http.ResponseWriter, req *http.Request) {
-	// remove/add not default ports from req.Host
-	host := strings.Split(req.Host, ":")[0]
-	target := "https://" + host + sslPort + req.URL.Path
-	if len(req.URL.RawQuery) > 0 {
-		target += "?" + req.URL.RawQuery
+	// unknown routes, methods and panics get the same JSON error as the handlers
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		misc.WriteError(w, r, misc.NewNotFoundError(r.URL.Path+" not found"))
+	})
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		misc.WriteError(w, r, misc.NewMethodNotAllowedError(r))
+	})
+	router.PanicHandler = func(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+		misc.WriteError(w, r, fmt.Errorf("Panic: %v", recovered))
 	}
-	log.Printf("redirect to: %s", target)
-	http.Redirect(w, req, target, http.StatusTemporaryRedirect)
 }
 
-func listenOnInsecurePortAndRedirect() {
-
-	log.Println("Server starting. Listening on " + serverListenPort)
-	// redirect every http request to https
-	go http.ListenAndServe(serverListenPort, http.HandlerFunc(redirectToSecurePort))
+type stores struct {
+	dishes         dishes.DishStore
+	comments       comments.CommentStore
+	leaders        leaders.LeaderStore
+	promotions     promotions.PromotionStore
+	users          auth.UserStore
+	failedLogins   auth.FailedLoginStore
+	facebookUsers  oauth2.FacebookUserStore
+	favoriteDishes favoriteDishes.FavoriteDishStore
+	search         search.Store
 }
 
-func listenOnSecurePort(router *httprouter.Router) {
+func setupStores(dbConfig config.Config) stores {
+
+	if dbConfig.DbDriver == config.MemoryDriver {
+		slog.Warn("Using in-memory stores, data will be lost on exit")
+		users := auth.NewMemoryStore()
+		dishStore := dishes.NewMemoryStore()
+		leaderStore := leaders.NewMemoryStore()
+		promotionStore := promotions.NewMemoryStore()
+		return stores{
+			dishes:         dishStore,
+			comments:       comments.NewMemoryStore(users),
+			leaders:        leaderStore,
+			promotions:     promotionStore,
+			users:          users,
+			failedLogins:   auth.NewMemoryFailedLoginStore(),
+			facebookUsers:  oauth2.NewMemoryStore(users),
+			favoriteDishes: favoriteDishes.NewMemoryStore(dishStore),
+			search:         search.NewTokenizerStore(dishStore, promotionStore, leaderStore),
+		}
+	}
+
+	if err := database.SetupDatabase(dbConfig); err != nil {
+		log.Fatal(err)
+	}
+	// a SQLite database is just a file, so it is always brought up to date on start
+	if dbConfig.DbAutoMigrate || dbConfig.DbDriver == config.SQLiteDriver {
+		migrateUp()
+	}
+
+	db := database.DbConn
+	dishStore := dishes.NewDbStore(db)
+	leaderStore := leaders.NewDbStore(db)
+	promotionStore := promotions.NewDbStore(db)
 
-	log.Println("Server starting. Listening on " + serverListenSslPort)
+	// SQLite has no full-text index without the FTS5 extension
+	searchStore := search.NewDbStore(db)
+	if dbConfig.DbDriver == config.SQLiteDriver {
+		searchStore = search.NewTokenizerStore(dishStore, promotionStore, leaderStore)
+	}
 
-	// start server on https port
-	server := http.Server{
-		Addr:    serverListenSslPort,
-		Handler: router,
-		TLSConfig: &tls.Config{
-			NextProtos: []string{"h2", "http/1.1"},
-		},
+	return stores{
+		dishes:         dishStore,
+		comments:       comments.NewDbStore(db),
+		leaders:        leaderStore,
+		promotions:     promotionStore,
+		users:          auth.NewDbStore(db),
+		failedLogins:   auth.NewDbFailedLoginStore(db),
+		facebookUsers:  oauth2.NewDbStore(db),
+		favoriteDishes: favoriteDishes.NewDbStore(db),
+		search:         searchStore,
 	}
+}
+
+// setupCache reads the dishes, leaders and promotions through the cache of cache_backend, if any
+func setupCache(cfg config.Config, stores *stores) {
 
-	certFilePath, _ := filepath.Abs(certPath)
-	keyFilePath, _ := filepath.Abs(keyPath)
-	err := server.ListenAndServeTLS(certFilePath, keyFilePath)
+	backend, err := cache.Setup(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if backend == nil {
+		return
+	}
+
+	stores.dishes = resource.NewCachedStore(stores.dishes, dishes.Resource, backend, cfg.CacheTtl)
+	stores.leaders = resource.NewCachedStore(stores.leaders, leaders.Resource, backend, cfg.CacheTtl)
+	stores.promotions = resource.NewCachedStore(stores.promotions, promotions.Resource, backend, cfg.CacheTtl)
+}
+
+// setupSuggestions builds the index of GET /dishes/suggest and has the dish
+// and favorite stores keep it current
+func setupSuggestions(stores *stores) *suggest.Index {
+
+	index := suggest.NewIndex(stores.dishes, stores.favoriteDishes)
+	// the index is loaded again by the next change of the dishes
+	if err := index.Load(context.Background()); err != nil {
+		slog.Error("Error loading the dish suggestions", "error", err)
+	}
+
+	stores.dishes = suggest.NewIndexedDishStore(stores.dishes, index)
+	stores.favoriteDishes = suggest.NewIndexedFavoriteDishStore(stores.favoriteDishes, index)
+	return index
+}
+
+// getDbJsonStores returns the stores read by the seed and export commands
+func getDbJsonStores(stores stores) dbjson.Stores {
+	return dbjson.Stores{
+		Dishes:     stores.dishes,
+		Comments:   stores.comments,
+		Leaders:    stores.leaders,
+		Promotions: stores.promotions,
+		Users:      stores.users,
+		Favorites:  stores.favoriteDishes,
+	}
 }
 
 func main() {
 
-	configFilePath := misc.GetConfigFilePath()
-	config := config.ReadDbConfig(configFilePath)
+	config, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	logging.Setup(config)
+
+	// commands other than serving the API
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			runMigrateCommand(config, args[1:])
+		case "seed":
+			runSeedCommand(config, args[1:])
+		case "export":
+			runExportCommand(config, args[1:])
+		default:
+			log.Fatalf("Unknown command %s, expected migrate, seed or export", args[0])
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(config)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	database.SetupDatabase(config)
+	limiters, err := ratelimit.New(config)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	router := httprouter.New()
+	trustedProxies, err := config.GetTrustedProxies()
+	if err != nil {
+		log.Fatal(err)
+	}
+	misc.SetTrustedProxies(trustedProxies)
+
+	stores := setupStores(config)
+	setupCache(config, &stores)
+	suggestions := setupSuggestions(&stores)
+
+	var certificates *tlscert.Manager
+	if !config.HttpOnly {
+		certificates, err = tlscert.NewManager(config)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	router := misc.NewRouter()
 	cors.SetupCors(router)
-	dishes.SetupRoutes(router)
-	comments.SetupRoutes(router)
-	leaders.SetupRoutes(router)
-	promotions.SetupRoutes(router)
-	auth.SetupRoutes(router)
+	dishes.SetupRoutes(router, stores.dishes, suggest.Routes(suggestions)...)
+	comments.SetupRoutes(router, stores.comments, limiters)
+	leaders.SetupRoutes(router, stores.leaders)
+	promotions.SetupRoutes(router, stores.promotions)
+	auth.SetupRoutes(router, config, stores.users, stores.failedLogins, limiters)
 	upload.SetupRoutes(router, config)
-	oauth2.SetupRoutes(router, config)
-	favoriteDishes.SetupRoutes(router)
+	oauth2.SetupRoutes(router, config, stores.facebookUsers, limiters)
+	favoriteDishes.SetupRoutes(router, stores.favoriteDishes)
+	search.SetupRoutes(router, stores.search, limiters)
+	dbjson.SetupRoutes(router, getDbJsonStores(stores))
+	health.SetupRoutes(router, config, database.DbConn, certificates)
 	setupDefaultRoutes(router)
 
-	listenOnInsecurePortAndRedirect()
-	listenOnSecurePort(router)
+	var metricsHandler http.Handler
+	if config.MetricsListenAddr != "" {
+		metricsHandler = metrics.Handler()
+	}
+	exitCode := runServers(getServers(router, config, certificates, metricsHandler), config)
 
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error sending the last trace spans", "error", err)
+	}
+	cancel()
+
+	if database.DbConn != nil {
+		if err := database.DbConn.Close(); err != nil {
+			slog.Error("Error closing the database", "error", err)
+		}
+	}
+	os.Exit(exitCode)
 }
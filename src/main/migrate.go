@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/migrations"
+)
+
+const migrateUsage = `usage: main migrate up|down [steps]|status`
+
+// runMigrateCommand implements "main migrate up|down [steps]|status"
+func runMigrateCommand(dbConfig config.Config, args []string) {
+
+	if dbConfig.DbDriver == config.MemoryDriver {
+		log.Fatal("db_driver memory has no schema to migrate")
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	if err := database.SetupDatabase(dbConfig); err != nil {
+		log.Fatal(err)
+	}
+	defer database.DbConn.Close()
+
+	switch args[0] {
+	case "up":
+		migrateUp()
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps %s", args[1])
+			}
+		}
+		rolledBack, err := migrations.Down(database.DbConn, steps)
+		for _, migration := range rolledBack {
+			slog.Info("Rolled back migration", "version", migration.Version, "name", migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(rolledBack) == 0 {
+			slog.Info("No migration to roll back")
+		}
+
+	case "status":
+		statuses, err := migrations.Status(database.DbConn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = "applied " + status.AppliedAt
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, appliedAt)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
+
+// migrateUp applies the pending migrations to database.DbConn
+func migrateUp() {
+
+	applied, err := migrations.Up(database.DbConn)
+	for _, migration := range applied {
+		slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
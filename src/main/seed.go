@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/dbjson"
+)
+
+// runSeedCommand implements "main seed [file]", importing db.json into the
+// database from file, or from seed_file
+func runSeedCommand(dbConfig config.Config, args []string) {
+
+	if dbConfig.DbDriver == config.MemoryDriver {
+		log.Fatal("db_driver memory loses the seeded data on exit, use a database")
+	}
+
+	seedFile := dbConfig.SeedFile
+	if len(args) > 0 {
+		seedFile = args[0]
+	}
+	if _, err := os.Stat(seedFile); errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("Seed file %s not found, pass the path of db.json or set seed_file", seedFile)
+	}
+
+	doc, err := dbjson.ReadDocumentFile(seedFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stores := setupStores(dbConfig)
+	defer database.DbConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	summary, err := dbjson.Import(ctx, doc, getDbJsonStores(stores))
+	slog.Info("Seeded the database", "file", seedFile, "dishes", summary.Dishes, "comments", summary.Comments,
+		"promotions", summary.Promotions, "leaders", summary.Leaders, "users", summary.Users)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
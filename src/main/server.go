@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/metrics"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/tlscert"
+	"confusion.com/bwoo/tracing"
+)
+
+// Exit codes of the server
+const (
+	// stopped by SIGINT or SIGTERM once the running requests finished
+	exitCodeShutdown = 0
+	// a server could not start or stopped unexpectedly, e.g. its address is in use
+	exitCodeServerError = 1
+	// requests were still running when shutdown_timeout expired and were cut off
+	exitCodeShutdownTimeout = 3
+)
+
+func getRedirectToSecurePort(serverListenSslAddr string) http.HandlerFunc {
+
+	_, sslPort, _ := net.SplitHostPort(serverListenSslAddr)
+	return func(w http.ResponseWriter, req *http.Request) {
+		// replace the http port of req.Host with the https one, leaving out the default 443
+		host := req.Host
+		if hostWithoutPort, _, err := net.SplitHostPort(req.Host); err == nil {
+			host = hostWithoutPort
+		}
+		if sslPort != "443" {
+			host = net.JoinHostPort(host, sslPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + req.URL.Path
+		if len(req.URL.RawQuery) > 0 {
+			target += "?" + req.URL.RawQuery
+		}
+		logging.FromContext(req.Context()).Debug("Redirect to https", "target", target)
+		http.Redirect(w, req, target, http.StatusTemporaryRedirect)
+	}
+}
+
+// getServers returns the servers to run according to http_only and https_redirect,
+// and the one of metricsHandler on metrics_listen_addr. Every request goes
+// through the access log, the API requests also through the tracing and the metrics.
+func getServers(router *misc.Router, config config.Config, certificates *tlscert.Manager,
+	metricsHandler http.Handler) []*http.Server {
+
+	servers := make([]*http.Server, 0, 3)
+
+	// the metrics are kept off the public listeners
+	if config.MetricsListenAddr != "" {
+		servers = append(servers, &http.Server{Addr: config.MetricsListenAddr, Handler: metricsHandler})
+	}
+
+	handler := tracing.Trace(logging.AccessLog(metrics.Instrument(router)))
+	if config.HttpOnly {
+		return append(servers, &http.Server{Addr: config.ServerListenAddr, Handler: handler})
+	}
+
+	if config.HttpsRedirect {
+		// redirect every http request to https, except the ACME http-01 challenges
+		redirect := getRedirectToSecurePort(config.ServerListenSslAddr)
+		servers = append(servers, &http.Server{
+			Addr:    config.ServerListenAddr,
+			Handler: logging.AccessLog(certificates.HTTPHandler(redirect)),
+		})
+	}
+
+	servers = append(servers, &http.Server{
+		Addr:      config.ServerListenSslAddr,
+		Handler:   handler,
+		TLSConfig: certificates.TLSConfig(),
+	})
+	return servers
+}
+
+// listen opens the listeners of servers, in the same order. If one of the
+// addresses can't be listened on, e.g. it is in use, none is left open.
+func listen(servers []*http.Server) ([]net.Listener, error) {
+
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, server := range servers {
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			for _, listener := range listeners {
+				listener.Close()
+			}
+			return nil, fmt.Errorf("Server on %s failed: %v", server.Addr, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+func serveListener(server *http.Server, listener net.Listener) error {
+
+	if server.TLSConfig == nil {
+		slog.Info("Server starting", "addr", listener.Addr().String())
+		return server.Serve(listener)
+	}
+
+	// the certificates come from TLSConfig.GetCertificate
+	slog.Info("Server starting", "addr", listener.Addr().String(), "tls", true)
+	return server.ServeTLS(listener, "", "")
+}
+
+// runServers serves until SIGINT or SIGTERM is received or a server fails and
+// returns the exit code, see serve.
+func runServers(servers []*http.Server, config config.Config) int {
+
+	listeners, err := listen(servers)
+	if err != nil {
+		slog.Error("Server failed", "error", err)
+		return exitCodeServerError
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	return serve(servers, listeners, signals, config.ShutdownTimeout)
+}
+
+// serve serves each of servers on its listener until a signal is received on
+// signals or a server fails, then stops accepting connections and gives the
+// running requests, and the transactions they hold, shutdownTimeout to finish.
+// It returns the exit code.
+func serve(servers []*http.Server, listeners []net.Listener, signals chan os.Signal, shutdownTimeout time.Duration) int {
+
+	serverErrors := make(chan error, len(servers))
+	for i, server := range servers {
+		go func(server *http.Server, listener net.Listener) {
+			err := serveListener(server, listener)
+			if err != http.ErrServerClosed {
+				serverErrors <- fmt.Errorf("Server on %s failed: %v", server.Addr, err)
+			}
+		}(server, listeners[i])
+	}
+
+	exitCode := exitCodeShutdown
+	select {
+	case sig := <-signals:
+		slog.Info("Shutting down", "signal", sig.String())
+	case err := <-serverErrors:
+		slog.Error("Server failed", "error", err)
+		exitCode = exitCodeServerError
+	}
+	// a second signal kills the process without waiting
+	signal.Stop(signals)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var timedOut bool
+	var mutex sync.Mutex
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				slog.Warn("Server did not drain in time", "addr", server.Addr,
+					"shutdownTimeout", shutdownTimeout.String(), "error", err)
+				server.Close()
+				mutex.Lock()
+				timedOut = true
+				mutex.Unlock()
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	if timedOut && exitCode == exitCodeShutdown {
+		exitCode = exitCodeShutdownTimeout
+	}
+	slog.Info("Server stopped")
+	return exitCode
+}
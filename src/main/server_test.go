@@ -0,0 +1,298 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/tlscert"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func newTestCertificates(t *testing.T) *tlscert.Manager {
+
+	t.Helper()
+
+	dir := t.TempDir()
+	certificates, err := tlscert.NewManager(config.Config{
+		CertPath:       filepath.Join(dir, "server.crt"),
+		KeyPath:        filepath.Join(dir, "server.key"),
+		SelfSignedCert: true,
+	})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return certificates
+}
+
+func TestGetServers(t *testing.T) {
+
+	router := misc.NewRouter()
+	router.GET("/dishes", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		fmt.Fprint(w, "dishes")
+	})
+	metricsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "metrics")
+	})
+	certificates := newTestCertificates(t)
+
+	type server struct {
+		addr string
+		tls  bool
+		// the answer to GET /dishes?id=1
+		status int
+		body   string
+	}
+	api := func(addr string, tls bool) server { return server{addr, tls, http.StatusOK, "dishes"} }
+	metricsServer := server{"127.0.0.1:9090", false, http.StatusOK, "metrics"}
+	redirect := server{":3000", false, http.StatusTemporaryRedirect, ""}
+
+	for _, test := range []struct {
+		name              string
+		httpOnly          bool
+		httpsRedirect     bool
+		metricsListenAddr string
+		want              []server
+	}{
+		{"http_only", true, false, "", []server{api(":3000", false)}},
+		{"http_only ignores https_redirect", true, true, "", []server{api(":3000", false)}},
+		{"http_only and metrics_listen_addr", true, false, "127.0.0.1:9090", []server{metricsServer, api(":3000", false)}},
+		{"https_redirect", false, true, "", []server{redirect, api(":3443", true)}},
+		{"https only", false, false, "", []server{api(":3443", true)}},
+		{"https_redirect and metrics_listen_addr", false, true, "127.0.0.1:9090",
+			[]server{metricsServer, redirect, api(":3443", true)}},
+	} {
+
+		// like main, http_only has no certificates
+		certificates := certificates
+		if test.httpOnly {
+			certificates = nil
+		}
+		servers := getServers(router, config.Config{
+			ServerListenAddr:    ":3000",
+			ServerListenSslAddr: ":3443",
+			HttpOnly:            test.httpOnly,
+			HttpsRedirect:       test.httpsRedirect,
+			MetricsListenAddr:   test.metricsListenAddr,
+		}, certificates, metricsHandler)
+
+		if len(servers) != len(test.want) {
+			t.Errorf("%s: got %d servers, want %d", test.name, len(servers), len(test.want))
+			continue
+		}
+		for i, want := range test.want {
+
+			got := servers[i]
+			if got.Addr != want.addr || (got.TLSConfig != nil) != want.tls {
+				t.Errorf("%s: got server %d on %s with tls %t, want %s with tls %t",
+					test.name, i, got.Addr, got.TLSConfig != nil, want.addr, want.tls)
+			}
+
+			w := httptest.NewRecorder()
+			got.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:3000/dishes?id=1", nil))
+			if w.Code != want.status || (want.body != "" && w.Body.String() != want.body) {
+				t.Errorf("%s: got %d %q from server %d, want %d %q", test.name, w.Code, w.Body.String(), i, want.status, want.body)
+			}
+			if want.status == http.StatusTemporaryRedirect && w.Header().Get("Location") != "https://localhost:3443/dishes?id=1" {
+				t.Errorf("%s: got a redirect to %s, want the https port", test.name, w.Header().Get("Location"))
+			}
+
+			// the metrics are only served on their own listener
+			if want.body == "dishes" {
+				w := httptest.NewRecorder()
+				got.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+				if w.Code != http.StatusNotFound {
+					t.Errorf("%s: got %d for /metrics on %s, want %d", test.name, w.Code, got.Addr, http.StatusNotFound)
+				}
+			}
+		}
+	}
+}
+
+func TestRedirectToSecurePort(t *testing.T) {
+
+	for _, test := range []struct {
+		sslAddr, url, want string
+	}{
+		{":3443", "http://localhost:3000/dishes", "https://localhost:3443/dishes"},
+		{":3443", "http://localhost/dishes?limit=2&sort=-price", "https://localhost:3443/dishes?limit=2&sort=-price"},
+		{":443", "http://example.com:80/", "https://example.com/"},
+		{":443", "http://[::1]:3000/leaders", "https://[::1]/leaders"},
+		{"0.0.0.0:8443", "http://[::1]:3000/leaders", "https://[::1]:8443/leaders"},
+	} {
+
+		w := httptest.NewRecorder()
+		getRedirectToSecurePort(test.sslAddr)(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+		if got := w.Header().Get("Location"); w.Code != http.StatusTemporaryRedirect || got != test.want {
+			t.Errorf("%s to %s: got %d to %s, want %d to %s", test.url, test.sslAddr, w.Code, got, http.StatusTemporaryRedirect, test.want)
+		}
+	}
+}
+
+func TestListenAddressInUse(t *testing.T) {
+
+	inUse, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer inUse.Close()
+
+	free := &http.Server{Addr: "127.0.0.1:0"}
+	if _, err := listen([]*http.Server{free, {Addr: inUse.Addr().String()}}); err == nil {
+		t.Fatalf("listen: got no error, want the address in use")
+	}
+
+	servers := []*http.Server{{Addr: inUse.Addr().String()}}
+	if exitCode := runServers(servers, config.Config{ShutdownTimeout: time.Second}); exitCode != exitCodeServerError {
+		t.Errorf("runServers: got exit code %d, want %d", exitCode, exitCodeServerError)
+	}
+}
+
+// serveTest serves handler on a free port until a signal is sent on the
+// returned channel and returns the url of the server and the exit code of serve
+func serveTest(t *testing.T, handler http.Handler, shutdownTimeout time.Duration) (string, chan os.Signal, <-chan int) {
+
+	t.Helper()
+
+	servers := []*http.Server{{Addr: "127.0.0.1:0", Handler: handler}}
+	listeners, err := listen(servers)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	exitCode := make(chan int, 1)
+	go func() { exitCode <- serve(servers, listeners, signals, shutdownTimeout) }()
+	return "http://" + listeners[0].Addr().String(), signals, exitCode
+}
+
+func TestServeShutdown(t *testing.T) {
+
+	for _, test := range []struct {
+		name string
+		// how long the request runs after the signal
+		requestTime     time.Duration
+		shutdownTimeout time.Duration
+		wantExitCode    int
+		wantAnswer      bool
+	}{
+		{"no running request", 0, time.Second, exitCodeShutdown, true},
+		{"request drained in time", 100 * time.Millisecond, 5 * time.Second, exitCodeShutdown, true},
+		{"request cut off at shutdown_timeout", 5 * time.Second, 100 * time.Millisecond, exitCodeShutdownTimeout, false},
+	} {
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			close(started)
+			select {
+			case <-release:
+			case <-time.After(test.requestTime):
+			}
+			fmt.Fprint(w, "done")
+		})
+		url, signals, exitCode := serveTest(t, handler, test.shutdownTimeout)
+
+		answered := make(chan bool, 1)
+		go func() {
+			response, err := http.Get(url)
+			if err != nil {
+				answered <- false
+				return
+			}
+			body, _ := io.ReadAll(response.Body)
+			response.Body.Close()
+			answered <- string(body) == "done"
+		}()
+		<-started
+		signals <- syscall.SIGTERM
+
+		select {
+		case got := <-exitCode:
+			if got != test.wantExitCode {
+				t.Errorf("%s: got exit code %d, want %d", test.name, got, test.wantExitCode)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s: serve did not return", test.name)
+		}
+		close(release)
+
+		if got := <-answered; got != test.wantAnswer {
+			t.Errorf("%s: got answered %t, want %t", test.name, got, test.wantAnswer)
+		}
+
+		// the listener is closed once serve returns
+		if _, err := http.Get(url); err == nil {
+			t.Errorf("%s: got an answer after serve returned, want none", test.name)
+		}
+	}
+}
+
+// the https server answers with the certificate of the manager
+func TestServeTls(t *testing.T) {
+
+	router := misc.NewRouter()
+	router.GET("/dishes", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		fmt.Fprint(w, "dishes")
+	})
+	servers := getServers(router, config.Config{ServerListenSslAddr: "127.0.0.1:0"}, newTestCertificates(t), nil)
+	listeners, err := listen(servers)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	signals := make(chan os.Signal, 1)
+	exitCode := make(chan int, 1)
+	go func() { exitCode <- serve(servers, listeners, signals, time.Second) }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	response, err := client.Get("https://" + listeners[0].Addr().String() + "/dishes")
+	if err != nil {
+		t.Fatalf("GET /dishes: %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if string(body) != "dishes" || len(response.TLS.PeerCertificates) == 0 {
+		t.Errorf("GET /dishes: got %q, want the dishes over tls", body)
+	} else if subject := response.TLS.PeerCertificates[0].Subject.CommonName; subject != "www.confusion.com" {
+		t.Errorf("GET /dishes: got a certificate of %q, want the self-signed one", subject)
+	}
+
+	signals <- syscall.SIGTERM
+	if got := <-exitCode; got != exitCodeShutdown {
+		t.Errorf("serve: got exit code %d, want %d", got, exitCodeShutdown)
+	}
+}
+
+func TestServeServerError(t *testing.T) {
+
+	servers := []*http.Server{{Addr: "127.0.0.1:0"}, {Addr: "127.0.0.1:0"}}
+	listeners, err := listen(servers)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	// the second server fails as soon as it serves
+	listeners[1].Close()
+
+	exitCode := make(chan int, 1)
+	go func() { exitCode <- serve(servers, listeners, make(chan os.Signal, 1), time.Second) }()
+	select {
+	case got := <-exitCode:
+		if got != exitCodeServerError {
+			t.Errorf("serve: got exit code %d, want %d", got, exitCodeServerError)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("serve: did not return after a server failed")
+	}
+}
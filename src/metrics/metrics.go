@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/recorder"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "confusion"
+
+// Logins counts the logins of auth by result, success or failure
+var Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "logins_total",
+	Help:      "Number of logins with a username and password, by result.",
+}, []string{"result"})
+
+// UploadedBytes counts the bytes of the images stored by upload
+var UploadedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "upload_bytes_total",
+	Help:      "Number of bytes of the uploaded images.",
+})
+
+// RateLimited counts the requests refused by ratelimit, by the name of the limit
+var RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "rate_limited_total",
+	Help:      "Number of requests refused by a rate limit, by limit.",
+}, []string{"limit"})
+
+// CacheRequests counts the reads of the cache of the resources, by resource
+// and result: hit, miss, or error when the cache could not be read
+var CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "cache_requests_total",
+	Help:      "Number of reads of the cache, by cache and result.",
+}, []string{"cache", "result"})
+
+var requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "http_requests_total",
+	Help:      "Number of HTTP requests, by method, route pattern and status.",
+}, []string{"method", "route", "status"})
+
+var requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "http_request_duration_seconds",
+	Help:      "Latency of the HTTP requests, by method and route pattern.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method", "route"})
+
+var registry = prometheus.NewRegistry()
+
+// Handler registers the metrics and returns the handler serving them on
+// /metrics. The pool of the database connection is reported when there is one.
+func Handler() http.Handler {
+
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requests,
+		requestDuration,
+		Logins,
+		UploadedBytes,
+		RateLimited,
+		CacheRequests,
+	)
+	if database.DbConn != nil {
+		registry.MustRegister(collectors.NewDBStatsCollector(database.DbConn.DB, database.DbConn.Dialect.Name()))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// Instrument counts the requests served by the misc.Router of next and
+// measures their latency, labelled by route pattern rather than path. Requests
+// matching no route are labelled unmatched, so unknown paths don't create new series.
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+		r, getRoute := misc.GetRoutePattern(r)
+		rec := recorder.New(w)
+		next.ServeHTTP(rec, r)
+
+		route := getRoute()
+		requests.WithLabelValues(r.Method, route, strconv.Itoa(rec.Status())).Inc()
+		requestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
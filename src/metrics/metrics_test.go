@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"confusion.com/bwoo/misc"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrument(t *testing.T) {
+
+	router := misc.NewRouter()
+	router.GET("/dishes/:dishId", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("dishId") == "0" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	handler := Instrument(router)
+	requests.Reset()
+	requestDuration.Reset()
+
+	for _, path := range []string{"/dishes/1", "/dishes/2", "/dishes/0", "/unknown", "/unknown/too", "/dishes/1/x"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	for _, test := range []struct {
+		route, status string
+		want          float64
+	}{
+		{"/dishes/:dishId", "200", 2},
+		{"/dishes/:dishId", "404", 1},
+		// the paths of no route share one series
+		{misc.UnmatchedRoute, "404", 3},
+	} {
+
+		if got := testutil.ToFloat64(requests.WithLabelValues(http.MethodGet, test.route, test.status)); got != test.want {
+			t.Errorf("requests %s %s: got %v, want %v", test.route, test.status, got, test.want)
+		}
+	}
+	if got := testutil.CollectAndCount(requests); got != 3 {
+		t.Errorf("requests: got %d series, want 3", got)
+	}
+	if got := testutil.CollectAndCount(requestDuration); got != 2 {
+		t.Errorf("requestDuration: got %d series, want one per route", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+
+	Logins.WithLabelValues("success").Inc()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics: got %d, want %d", w.Code, http.StatusOK)
+	}
+	for _, want := range []string{`confusion_logins_total{result="success"}`, "go_goroutines", "process_cpu_seconds_total"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("GET /metrics: want %s in the body", want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"confusion.com/bwoo/database"
+)
+
+// The migrations of every dialect live in sql/<db_driver>/ and are named
+// <version>_<name>.up.sql / <version>_<name>.down.sql
+//
+//go:embed sql
+var migrationFiles embed.FS
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+const sqlCreateMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+									version   BIGINT PRIMARY KEY,
+									name      VARCHAR(255) NOT NULL,
+									appliedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+								)`
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// Load returns the migrations of a dialect, sorted by version
+func Load(dialectName string) ([]Migration, error) {
+
+	dir := path.Join("sql", dialectName)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("No migrations for db_driver %s", dialectName)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+
+		match := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("Unexpected migration file %s", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("Migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("Migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func getAppliedMigrations(ctx context.Context, conn *database.Conn) (map[int64]string, error) {
+
+	if _, err := conn.ExecContext(ctx, sqlCreateMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, appliedAt FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]string)
+	for rows.Next() {
+		var version int64
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// Status lists every known migration and whether it has been applied
+func Status(conn *database.Conn) ([]MigrationStatus, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	migrations, err := Load(conn.Dialect.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := getAppliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+
+	return statuses, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied
+func Up(conn *database.Conn) ([]Migration, error) {
+
+	statuses, err := Status(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedNow := make([]Migration, 0)
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+
+		err := runMigration(conn, status.Migration, status.Up, func(ctx context.Context, tx *database.Tx) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations(version, name) VALUES (?,?)`,
+				status.Version, status.Name)
+			return err
+		})
+		if err != nil {
+			return appliedNow, err
+		}
+		appliedNow = append(appliedNow, status.Migration)
+	}
+
+	return appliedNow, nil
+}
+
+// Down rolls back the latest steps applied migrations and returns the ones rolled back
+func Down(conn *database.Conn, steps int) ([]Migration, error) {
+
+	statuses, err := Status(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	rolledBack := make([]Migration, 0)
+	for i := len(statuses) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+
+		err := runMigration(conn, status.Migration, status.Down, func(ctx context.Context, tx *database.Tx) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, status.Version)
+			return err
+		})
+		if err != nil {
+			return rolledBack, err
+		}
+		rolledBack = append(rolledBack, status.Migration)
+	}
+
+	return rolledBack, nil
+}
+
+// runMigration runs the statements of a migration file and the bookkeeping
+// in one transaction. MySQL commits DDL statements implicitly, so there a
+// failing migration may be left half applied.
+func runMigration(conn *database.Conn, migration Migration, script string,
+	bookkeeping func(ctx context.Context, tx *database.Tx) error) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range splitStatements(script) {
+		if _, err := tx.Tx.ExecContext(ctx, statement); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Migration %d_%s failed: %v", migration.Version, migration.Name, err)
+		}
+	}
+
+	if err := bookkeeping(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// splitStatements splits a migration file on the ; ending a line, except inside
+// $$ quoted PostgreSQL function bodies and BEGIN ... END; blocks of SQLite triggers
+func splitStatements(script string) []string {
+
+	statements := make([]string, 0)
+	var current strings.Builder
+	inDollarQuote := false
+	inBlock := false
+
+	for _, line := range strings.Split(script, "\n") {
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.Count(line, "$$")%2 == 1 {
+			inDollarQuote = !inDollarQuote
+		}
+		if strings.EqualFold(trimmed, "BEGIN") {
+			inBlock = true
+		} else if strings.EqualFold(trimmed, "END;") {
+			inBlock = false
+		}
+
+		if !inDollarQuote && !inBlock && strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
@@ -0,0 +1,140 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/database"
+)
+
+func TestSplitStatements(t *testing.T) {
+
+	for _, test := range []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"one per line", "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			[]string{"CREATE TABLE a (id INT);", "CREATE TABLE b (id INT);"}},
+		{"over several lines", "CREATE TABLE a (\n  id INT\n);\n",
+			[]string{"CREATE TABLE a (\n  id INT\n);"}},
+		{"comments and blank lines", "-- the dishes\n\nCREATE TABLE a (id INT); \n  -- done\n",
+			[]string{"CREATE TABLE a (id INT);"}},
+		{"no final ;", "CREATE TABLE a (id INT);\nDROP TABLE b",
+			[]string{"CREATE TABLE a (id INT);", "DROP TABLE b"}},
+		{"; within a line", "INSERT INTO a VALUES ('x;y');\n",
+			[]string{"INSERT INTO a VALUES ('x;y');"}},
+		{"$$ function body",
+			"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.a := 1;\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\nCREATE TABLE a (id INT);\n",
+			[]string{"CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.a := 1;\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;",
+				"CREATE TABLE a (id INT);"}},
+		{"$$ on one line", "DO $$ BEGIN PERFORM 1; END $$;\nSELECT 1;\n",
+			[]string{"DO $$ BEGIN PERFORM 1; END $$;", "SELECT 1;"}},
+		{"trigger block",
+			"CREATE TRIGGER t AFTER INSERT ON a\nBEGIN\n  UPDATE b SET n = n + 1;\n  DELETE FROM c;\nEND;\nDROP TABLE c;\n",
+			[]string{"CREATE TRIGGER t AFTER INSERT ON a\nBEGIN\n  UPDATE b SET n = n + 1;\n  DELETE FROM c;\nEND;", "DROP TABLE c;"}},
+		{"lowercase trigger block", "create trigger t after insert on a\nbegin\n  delete from c;\nend;\n",
+			[]string{"create trigger t after insert on a\nbegin\n  delete from c;\nend;"}},
+		{"empty", "\n-- nothing\n", []string{}},
+	} {
+
+		if got := splitStatements(test.script); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	for _, dbDriver := range []string{config.MySQLDriver, config.SQLiteDriver, config.PostgresDriver} {
+
+		migrations, err := Load(dbDriver)
+		if err != nil {
+			t.Errorf("%s: %v", dbDriver, err)
+			continue
+		}
+		if len(migrations) == 0 || migrations[0].Version != 1 {
+			t.Errorf("%s: got %d migrations, want the initial schema first", dbDriver, len(migrations))
+			continue
+		}
+		for i, migration := range migrations {
+			if i > 0 && migration.Version <= migrations[i-1].Version {
+				t.Errorf("%s: migration %d after %d", dbDriver, migration.Version, migrations[i-1].Version)
+			}
+			if len(splitStatements(migration.Up)) == 0 || len(splitStatements(migration.Down)) == 0 {
+				t.Errorf("%s: migration %d_%s has no statements", dbDriver, migration.Version, migration.Name)
+			}
+		}
+	}
+
+	if _, err := Load("oracle"); err == nil {
+		t.Errorf("oracle: got no error, want one")
+	}
+}
+
+// the SQLite migrations are applied, rolled back and applied again
+func TestUpDown(t *testing.T) {
+
+	connString := (&config.Config{DbDriver: config.SQLiteDriver, DbName: filepath.Join(t.TempDir(), "test.db")}).GetConnString()
+	dialect, _ := database.GetDialect(config.SQLiteDriver)
+	db, err := sql.Open(dialect.DriverName(), connString)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	conn := &database.Conn{DB: db, Dialect: dialect}
+
+	migrations, err := Load(config.SQLiteDriver)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	countApplied := func() int {
+		statuses, err := Status(conn)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		applied := 0
+		for _, status := range statuses {
+			if status.Applied {
+				applied++
+			}
+		}
+		return applied
+	}
+
+	for _, test := range []struct {
+		name        string
+		run         func() ([]Migration, error)
+		wantRun     int
+		wantApplied int
+	}{
+		{"up", func() ([]Migration, error) { return Up(conn) }, len(migrations), len(migrations)},
+		{"up again", func() ([]Migration, error) { return Up(conn) }, 0, len(migrations)},
+		{"down 1", func() ([]Migration, error) { return Down(conn, 1) }, 1, len(migrations) - 1},
+		{"up the last one", func() ([]Migration, error) { return Up(conn) }, 1, len(migrations)},
+		{"down all", func() ([]Migration, error) { return Down(conn, len(migrations)+1) }, len(migrations), 0},
+		{"up from scratch", func() ([]Migration, error) { return Up(conn) }, len(migrations), len(migrations)},
+	} {
+
+		run, err := test.run()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(run) != test.wantRun {
+			t.Errorf("%s: got %d migrations run, want %d", test.name, len(run), test.wantRun)
+		}
+		if applied := countApplied(); applied != test.wantApplied {
+			t.Errorf("%s: got %d migrations applied, want %d", test.name, applied, test.wantApplied)
+		}
+	}
+
+	// the schema of the migrations is there
+	if _, err := conn.ExecContext(context.Background(), `INSERT INTO failedLogin (username, ip, attemptedAt) VALUES ('jane', '127.0.0.1', CURRENT_TIMESTAMP)`); err != nil {
+		t.Errorf("INSERT INTO failedLogin: %v", err)
+	}
+}
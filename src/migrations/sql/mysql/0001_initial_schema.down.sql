@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS favoriteDish;
+DROP TABLE IF EXISTS comment;
+DROP TABLE IF EXISTS user;
+DROP TABLE IF EXISTS promotion;
+DROP TABLE IF EXISTS leader;
+DROP TABLE IF EXISTS dish;
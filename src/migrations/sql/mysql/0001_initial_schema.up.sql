@@ -1,7 +1,5 @@
-use confusion;
-
-CREATE TABLE dish (
-    id          INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+CREATE TABLE IF NOT EXISTS dish (
+	id          INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
 	name        VARCHAR(50) UNIQUE NOT NULL,
 	image       VARCHAR(50) NOT NULL,
 	category    VARCHAR(20) NOT NULL,
@@ -13,18 +11,7 @@ CREATE TABLE dish (
 	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
-CREATE TABLE comment (
-	id        INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
-	dishId    INT(6) UNSIGNED NOT NULL,
-	rating    TINYINT(1) UNSIGNED NOT NULL,
-	comment   TEXT,
-	authorId  INT(6) UNSIGNED NOT NULL,
-	date      TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-	FOREIGN KEY (dishId) REFERENCES dish(id) ON DELETE CASCADE,
-	FOREIGN KEY (authorId) REFERENCES user(id) ON DELETE CASCADE
-);
-
-CREATE TABLE leader (
+CREATE TABLE IF NOT EXISTS leader (
 	id          INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
 	name        VARCHAR(50) NOT NULL,
 	image       VARCHAR(50) NOT NULL,
@@ -36,7 +23,7 @@ CREATE TABLE leader (
 	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
-CREATE TABLE promotion (
+CREATE TABLE IF NOT EXISTS promotion (
 	id          INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
 	name        VARCHAR(50) NOT NULL,
 	image       VARCHAR(50) NOT NULL,
@@ -48,7 +35,7 @@ CREATE TABLE promotion (
 	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
-CREATE TABLE user (
+CREATE TABLE IF NOT EXISTS user (
 	id          INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
 	facebookId  VARCHAR(50),
 	firstname   VARCHAR(50) NOT NULL,
@@ -60,13 +47,24 @@ CREATE TABLE user (
 	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
-CREATE TABLE favoriteDish (
+CREATE TABLE IF NOT EXISTS comment (
+	id        INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+	dishId    INT(6) UNSIGNED NOT NULL,
+	rating    TINYINT(1) UNSIGNED NOT NULL,
+	comment   TEXT,
+	authorId  INT(6) UNSIGNED NOT NULL,
+	date      TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	FOREIGN KEY (dishId) REFERENCES dish(id) ON DELETE CASCADE,
+	FOREIGN KEY (authorId) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS favoriteDish (
 	id          INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
 	userId      INT(6) UNSIGNED,
 	dishId      INT(6) UNSIGNED,
 	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-	UNIQUE KEY  `unique_userId_dishId` (`userId`, `dishId`),
+	UNIQUE KEY  unique_userId_dishId (userId, dishId),
 	FOREIGN KEY (userId) REFERENCES user(id) ON DELETE CASCADE,
 	FOREIGN KEY (dishId) REFERENCES dish(id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
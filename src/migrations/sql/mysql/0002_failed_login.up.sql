@@ -0,0 +1,13 @@
+-- attemptedAt is in unix seconds, so the lockout compares it the same way on every database
+CREATE TABLE IF NOT EXISTS failedLogin (
+	id          INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+	username    VARCHAR(50) NOT NULL,
+	ip          VARCHAR(45) NOT NULL,
+	attemptedAt BIGINT NOT NULL,
+	cleared     BOOLEAN NOT NULL DEFAULT 0,
+	INDEX failedLogin_username (username, attemptedAt),
+	INDEX failedLogin_ip (ip, attemptedAt)
+);
+
+-- the failed logins older than lockout_window and lockout_duration together are deleted by attemptedAt alone
+CREATE INDEX failedLogin_attemptedAt ON failedLogin (attemptedAt);
@@ -0,0 +1,2 @@
+ALTER TABLE promotion MODIFY price FLOAT NOT NULL;
+ALTER TABLE dish MODIFY price FLOAT NOT NULL;
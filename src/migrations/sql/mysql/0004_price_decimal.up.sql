@@ -0,0 +1,4 @@
+-- a FLOAT keeps 4.99 as 4.98999977, which equals no price of the filters and
+-- breaks the ties of the pages sorted on price
+ALTER TABLE dish MODIFY price DECIMAL(10,2) NOT NULL;
+ALTER TABLE promotion MODIFY price DECIMAL(10,2) NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE dish DROP INDEX dish_search;
+ALTER TABLE promotion DROP INDEX promotion_search;
+ALTER TABLE leader DROP INDEX leader_search;
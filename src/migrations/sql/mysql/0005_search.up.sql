@@ -0,0 +1,4 @@
+-- the full-text indexes searched by GET /search, the columns of MATCH() have to be those of an index
+ALTER TABLE dish ADD FULLTEXT INDEX dish_search (name, description);
+ALTER TABLE promotion ADD FULLTEXT INDEX promotion_search (description);
+ALTER TABLE leader ADD FULLTEXT INDEX leader_search (description);
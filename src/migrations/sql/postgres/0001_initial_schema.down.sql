@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS favoriteDish;
+DROP TABLE IF EXISTS comment;
+DROP TABLE IF EXISTS "user";
+DROP TABLE IF EXISTS promotion;
+DROP TABLE IF EXISTS leader;
+DROP TABLE IF EXISTS dish;
+DROP FUNCTION IF EXISTS set_updatedat();
+DROP FUNCTION IF EXISTS set_date();
@@ -0,0 +1,106 @@
+CREATE TABLE IF NOT EXISTS dish (
+	id          SERIAL PRIMARY KEY,
+	name        VARCHAR(50) UNIQUE NOT NULL,
+	image       VARCHAR(50) NOT NULL,
+	category    VARCHAR(20) NOT NULL,
+	label       VARCHAR(10) DEFAULT '',
+	price       DOUBLE PRECISION NOT NULL,
+	featured    BOOLEAN NOT NULL DEFAULT false,
+	description TEXT NOT NULL,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS leader (
+	id          SERIAL PRIMARY KEY,
+	name        VARCHAR(50) NOT NULL,
+	image       VARCHAR(50) NOT NULL,
+	designation VARCHAR(50) NOT NULL,
+	abbr        VARCHAR(10) NOT NULL,
+	featured    BOOLEAN NOT NULL DEFAULT false,
+	description TEXT NOT NULL,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS promotion (
+	id          SERIAL PRIMARY KEY,
+	name        VARCHAR(50) NOT NULL,
+	image       VARCHAR(50) NOT NULL,
+	label       VARCHAR(20) NOT NULL DEFAULT '',
+	price       DOUBLE PRECISION NOT NULL,
+	featured    BOOLEAN NOT NULL DEFAULT false,
+	description TEXT NOT NULL,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS "user" (
+	id          SERIAL PRIMARY KEY,
+	facebookId  VARCHAR(50),
+	firstname   VARCHAR(50) NOT NULL,
+	lastname    VARCHAR(50) NOT NULL,
+	admin       BOOLEAN NOT NULL DEFAULT false,
+	username    VARCHAR(15) UNIQUE NOT NULL,
+	password    TEXT,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS comment (
+	id        SERIAL PRIMARY KEY,
+	dishId    INTEGER NOT NULL REFERENCES dish(id) ON DELETE CASCADE,
+	rating    SMALLINT NOT NULL,
+	comment   TEXT,
+	authorId  INTEGER NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	date      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS favoriteDish (
+	id          SERIAL PRIMARY KEY,
+	userId      INTEGER REFERENCES "user"(id) ON DELETE CASCADE,
+	dishId      INTEGER REFERENCES dish(id) ON DELETE CASCADE,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT unique_userId_dishId UNIQUE (userId, dishId)
+);
+
+-- PostgreSQL has no ON UPDATE CURRENT_TIMESTAMP, so the timestamps are kept current with triggers.
+-- Unquoted identifiers are folded to lower case, hence updatedat.
+CREATE OR REPLACE FUNCTION set_updatedat() RETURNS TRIGGER AS $$
+BEGIN
+	NEW.updatedat = CURRENT_TIMESTAMP;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_date() RETURNS TRIGGER AS $$
+BEGIN
+	NEW.date = CURRENT_TIMESTAMP;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS dish_updatedat ON dish;
+CREATE TRIGGER dish_updatedat BEFORE UPDATE ON dish
+	FOR EACH ROW EXECUTE FUNCTION set_updatedat();
+
+DROP TRIGGER IF EXISTS leader_updatedat ON leader;
+CREATE TRIGGER leader_updatedat BEFORE UPDATE ON leader
+	FOR EACH ROW EXECUTE FUNCTION set_updatedat();
+
+DROP TRIGGER IF EXISTS promotion_updatedat ON promotion;
+CREATE TRIGGER promotion_updatedat BEFORE UPDATE ON promotion
+	FOR EACH ROW EXECUTE FUNCTION set_updatedat();
+
+DROP TRIGGER IF EXISTS user_updatedat ON "user";
+CREATE TRIGGER user_updatedat BEFORE UPDATE ON "user"
+	FOR EACH ROW EXECUTE FUNCTION set_updatedat();
+
+DROP TRIGGER IF EXISTS comment_date ON comment;
+CREATE TRIGGER comment_date BEFORE UPDATE ON comment
+	FOR EACH ROW EXECUTE FUNCTION set_date();
+
+DROP TRIGGER IF EXISTS favoriteDish_updatedat ON favoriteDish;
+CREATE TRIGGER favoriteDish_updatedat BEFORE UPDATE ON favoriteDish
+	FOR EACH ROW EXECUTE FUNCTION set_updatedat();
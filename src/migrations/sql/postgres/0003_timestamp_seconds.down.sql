@@ -0,0 +1,6 @@
+ALTER TABLE favoriteDish ALTER COLUMN createdAt TYPE TIMESTAMP, ALTER COLUMN updatedAt TYPE TIMESTAMP;
+ALTER TABLE comment ALTER COLUMN date TYPE TIMESTAMP;
+ALTER TABLE "user" ALTER COLUMN createdAt TYPE TIMESTAMP, ALTER COLUMN updatedAt TYPE TIMESTAMP;
+ALTER TABLE promotion ALTER COLUMN createdAt TYPE TIMESTAMP, ALTER COLUMN updatedAt TYPE TIMESTAMP;
+ALTER TABLE leader ALTER COLUMN createdAt TYPE TIMESTAMP, ALTER COLUMN updatedAt TYPE TIMESTAMP;
+ALTER TABLE dish ALTER COLUMN createdAt TYPE TIMESTAMP, ALTER COLUMN updatedAt TYPE TIMESTAMP;
@@ -0,0 +1,8 @@
+-- the timestamps are read to the second, so the pages sorted on them can start
+-- at the exact row of their cursor, as they are in MySQL and SQLite
+ALTER TABLE dish ALTER COLUMN createdAt TYPE TIMESTAMP(0), ALTER COLUMN updatedAt TYPE TIMESTAMP(0);
+ALTER TABLE leader ALTER COLUMN createdAt TYPE TIMESTAMP(0), ALTER COLUMN updatedAt TYPE TIMESTAMP(0);
+ALTER TABLE promotion ALTER COLUMN createdAt TYPE TIMESTAMP(0), ALTER COLUMN updatedAt TYPE TIMESTAMP(0);
+ALTER TABLE "user" ALTER COLUMN createdAt TYPE TIMESTAMP(0), ALTER COLUMN updatedAt TYPE TIMESTAMP(0);
+ALTER TABLE comment ALTER COLUMN date TYPE TIMESTAMP(0);
+ALTER TABLE favoriteDish ALTER COLUMN createdAt TYPE TIMESTAMP(0), ALTER COLUMN updatedAt TYPE TIMESTAMP(0);
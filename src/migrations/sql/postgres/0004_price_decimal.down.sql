@@ -0,0 +1,2 @@
+ALTER TABLE promotion ALTER COLUMN price TYPE DOUBLE PRECISION;
+ALTER TABLE dish ALTER COLUMN price TYPE DOUBLE PRECISION;
@@ -0,0 +1,3 @@
+-- the prices are exact, like in MySQL
+ALTER TABLE dish ALTER COLUMN price TYPE NUMERIC(10,2);
+ALTER TABLE promotion ALTER COLUMN price TYPE NUMERIC(10,2);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS dish_search;
+DROP INDEX IF EXISTS promotion_search;
+DROP INDEX IF EXISTS leader_search;
@@ -0,0 +1,4 @@
+-- the full-text indexes searched by GET /search, the queries use the same expressions
+CREATE INDEX IF NOT EXISTS dish_search ON dish USING GIN (to_tsvector('english', name || ' ' || description));
+CREATE INDEX IF NOT EXISTS promotion_search ON promotion USING GIN (to_tsvector('english', description));
+CREATE INDEX IF NOT EXISTS leader_search ON leader USING GIN (to_tsvector('english', description));
@@ -0,0 +1,107 @@
+CREATE TABLE IF NOT EXISTS dish (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	name        VARCHAR(50) UNIQUE NOT NULL,
+	image       VARCHAR(50) NOT NULL,
+	category    VARCHAR(20) NOT NULL,
+	label       VARCHAR(10) DEFAULT '',
+	price       FLOAT NOT NULL,
+	featured    BOOLEAN NOT NULL DEFAULT 0,
+	description TEXT NOT NULL,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS leader (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	name        VARCHAR(50) NOT NULL,
+	image       VARCHAR(50) NOT NULL,
+	designation VARCHAR(50) NOT NULL,
+	abbr        VARCHAR(10) NOT NULL,
+	featured    BOOLEAN NOT NULL DEFAULT 0,
+	description TEXT NOT NULL,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS promotion (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	name        VARCHAR(50) NOT NULL,
+	image       VARCHAR(50) NOT NULL,
+	label       VARCHAR(20) NOT NULL DEFAULT '',
+	price       FLOAT NOT NULL,
+	featured    BOOLEAN NOT NULL DEFAULT 0,
+	description TEXT NOT NULL,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	facebookId  VARCHAR(50),
+	firstname   VARCHAR(50) NOT NULL,
+	lastname    VARCHAR(50) NOT NULL,
+	admin       BOOLEAN NOT NULL DEFAULT false,
+	username    VARCHAR(15) UNIQUE NOT NULL,
+	password    TEXT,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS comment (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	dishId    INTEGER NOT NULL,
+	rating    TINYINT NOT NULL,
+	comment   TEXT,
+	authorId  INTEGER NOT NULL,
+	date      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (dishId) REFERENCES dish(id) ON DELETE CASCADE,
+	FOREIGN KEY (authorId) REFERENCES user(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS favoriteDish (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	userId      INTEGER,
+	dishId      INTEGER,
+	createdAt   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (userId, dishId),
+	FOREIGN KEY (userId) REFERENCES user(id) ON DELETE CASCADE,
+	FOREIGN KEY (dishId) REFERENCES dish(id) ON DELETE CASCADE
+);
+
+-- SQLite has no ON UPDATE CURRENT_TIMESTAMP, so the timestamps are kept current with triggers
+CREATE TRIGGER IF NOT EXISTS dish_updatedAt AFTER UPDATE ON dish
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+	UPDATE dish SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS leader_updatedAt AFTER UPDATE ON leader
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+	UPDATE leader SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS promotion_updatedAt AFTER UPDATE ON promotion
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+	UPDATE promotion SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS user_updatedAt AFTER UPDATE ON user
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+	UPDATE user SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS comment_date AFTER UPDATE ON comment
+FOR EACH ROW WHEN NEW.date IS OLD.date
+BEGIN
+	UPDATE comment SET date = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS favoriteDish_updatedAt AFTER UPDATE ON favoriteDish
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+	UPDATE favoriteDish SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
@@ -0,0 +1,14 @@
+-- attemptedAt is in unix seconds, so the lockout compares it the same way on every database
+CREATE TABLE IF NOT EXISTS failedLogin (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	username    VARCHAR(50) NOT NULL,
+	ip          VARCHAR(45) NOT NULL,
+	attemptedAt BIGINT NOT NULL,
+	cleared     BOOLEAN NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS failedLogin_username ON failedLogin (username, attemptedAt);
+CREATE INDEX IF NOT EXISTS failedLogin_ip ON failedLogin (ip, attemptedAt);
+
+-- the failed logins older than lockout_window and lockout_duration together are deleted by attemptedAt alone
+CREATE INDEX IF NOT EXISTS failedLogin_attemptedAt ON failedLogin (attemptedAt);
@@ -0,0 +1,62 @@
+package misc
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// trustedProxies are the proxies of trusted_proxies, set by SetTrustedProxies
+var trustedProxies []netip.Prefix
+
+// SetTrustedProxies sets the proxies whose X-Forwarded-For and X-Real-IP
+// headers GetClientIp believes, it runs before the routes are served
+func SetTrustedProxies(prefixes []netip.Prefix) {
+	trustedProxies = prefixes
+}
+
+func isTrustedProxy(addr netip.Addr) bool {
+
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetClientIp returns the IP address of the client that sent r. When r comes
+// from a trusted proxy, it is the last address of X-Forwarded-For which is
+// not a trusted proxy, or else X-Real-IP: the addresses before it were set
+// by the client, which can put anything there.
+func GetClientIp(r *http.Request) string {
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !isTrustedProxy(addr) {
+		return ip
+	}
+
+	forwardedFor := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		forwardedAddr, err := netip.ParseAddr(strings.TrimSpace(forwardedFor[i]))
+		if err != nil {
+			// a malformed hop can't be trusted further
+			break
+		}
+		addr = forwardedAddr
+		if !isTrustedProxy(addr) {
+			return addr.Unmap().String()
+		}
+	}
+
+	if realIp, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return realIp.Unmap().String()
+	}
+	return addr.Unmap().String()
+}
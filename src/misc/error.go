@@ -0,0 +1,158 @@
+package misc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"confusion.com/bwoo/logging"
+)
+
+// Error codes of the error responses, each one always sent with the same HTTP status
+const (
+	ErrorCodeBadRequest         = "bad_request"
+	ErrorCodeValidation         = "validation_failed"
+	ErrorCodeUnauthorized       = "unauthorized"
+	ErrorCodeForbidden          = "forbidden"
+	ErrorCodeNotFound           = "not_found"
+	ErrorCodeMethodNotAllowed   = "method_not_allowed"
+	ErrorCodeConflict           = "conflict"
+	ErrorCodePreconditionFailed = "precondition_failed"
+	ErrorCodeTooLarge           = "request_too_large"
+	ErrorCodeInvalidReference   = "invalid_reference"
+	ErrorCodeTooManyRequests    = "too_many_requests"
+	ErrorCodeInternal           = "internal_error"
+)
+
+var errorCodeStatuses = map[string]int{
+	ErrorCodeBadRequest:         http.StatusBadRequest,
+	ErrorCodeValidation:         http.StatusBadRequest,
+	ErrorCodeUnauthorized:       http.StatusUnauthorized,
+	ErrorCodeForbidden:          http.StatusForbidden,
+	ErrorCodeNotFound:           http.StatusNotFound,
+	ErrorCodeMethodNotAllowed:   http.StatusMethodNotAllowed,
+	ErrorCodeConflict:           http.StatusConflict,
+	ErrorCodePreconditionFailed: http.StatusPreconditionFailed,
+	ErrorCodeTooLarge:           http.StatusRequestEntityTooLarge,
+	ErrorCodeInvalidReference:   http.StatusUnprocessableEntity,
+	ErrorCodeTooManyRequests:    http.StatusTooManyRequests,
+	ErrorCodeInternal:           http.StatusInternalServerError,
+}
+
+// FieldError tells what is wrong with one field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is the error handlers reply with, written by WriteError as
+// {"error": {"code": ..., "message": ..., "details": [...], "requestId": ...}}
+type Error struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestId string       `json:"requestId,omitempty"`
+}
+
+type errorResponse struct {
+	Error Error `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Status returns the HTTP status of the error code
+func (e *Error) Status() int {
+
+	status, ok := errorCodeStatuses[e.Code]
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+func NewError(code, message string, details ...FieldError) *Error {
+	return &Error{Code: code, Message: message, Details: details}
+}
+
+func NewBadRequestError(message string) *Error {
+	return NewError(ErrorCodeBadRequest, message)
+}
+
+// NewValidationError reports the fields of a request body which are invalid
+func NewValidationError(details ...FieldError) *Error {
+	return NewError(ErrorCodeValidation, "Validation failed", details...)
+}
+
+func NewUnauthorizedError(message string) *Error {
+	return NewError(ErrorCodeUnauthorized, message)
+}
+
+func NewForbiddenError(message string) *Error {
+	return NewError(ErrorCodeForbidden, message)
+}
+
+func NewNotFoundError(message string) *Error {
+	return NewError(ErrorCodeNotFound, message)
+}
+
+func NewMethodNotAllowedError(r *http.Request) *Error {
+	return NewError(ErrorCodeMethodNotAllowed, r.Method+" operation not supported on "+r.URL.Path)
+}
+
+func NewConflictError(message string) *Error {
+	return NewError(ErrorCodeConflict, message)
+}
+
+func NewPreconditionFailedError(message string) *Error {
+	return NewError(ErrorCodePreconditionFailed, message)
+}
+
+func NewTooLargeError(message string) *Error {
+	return NewError(ErrorCodeTooLarge, message)
+}
+
+// NewInvalidReferenceError reports a request referencing a row which does not exist
+func NewInvalidReferenceError(message string) *Error {
+	return NewError(ErrorCodeInvalidReference, message)
+}
+
+func NewTooManyRequestsError(message string) *Error {
+	return NewError(ErrorCodeTooManyRequests, message)
+}
+
+func NewInternalError() *Error {
+	return NewError(ErrorCodeInternal, "Internal server error")
+}
+
+// HasErrorCode tells if err is, or wraps, an *Error with the code
+func HasErrorCode(err error, code string) bool {
+
+	var replyErr *Error
+	return errors.As(err, &replyErr) && replyErr.Code == code
+}
+
+// GetRequestId returns the id of the request, given by logging.AccessLog
+func GetRequestId(r *http.Request) string {
+	return logging.GetRequestId(r.Context())
+}
+
+// WriteError replies to the request with err. Errors which are not an *Error
+// are logged and replied as an internal error, so their message does not leak.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+
+	var replyErr *Error
+	if !errors.As(err, &replyErr) {
+		logging.FromContext(r.Context()).Error("Request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		replyErr = NewInternalError()
+	}
+
+	response := errorResponse{Error: *replyErr}
+	response.Error.RequestId = GetRequestId(r)
+	responseJson, _ := json.Marshal(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(replyErr.Status())
+	w.Write(responseJson)
+}
@@ -2,12 +2,14 @@ package misc
 
 import (
 	"encoding/json"
-	"os"
 	"strconv"
 )
 
 const EmptyJsonString string = "{}"
 
+// TimestampFormat is the layout of the createdAt / updatedAt columns
+const TimestampFormat string = "2006-01-02 15:04:05"
+
 func GetJsonFromJsonObjs(obj interface{}) ([]byte, error) {
 
 	jsonBytes, err := json.Marshal(&obj)
@@ -19,23 +21,22 @@ func GetInt64FromString(numberStr string) (int64, error) {
 	return strconv.ParseInt(numberStr, 10, 64)
 }
 
-func GetEmptyJsonByteArray() []byte {
-	return []byte(EmptyJsonString)
+// CopyString returns a pointer to a copy of *s, or nil if s is nil
+func CopyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
 }
 
-func GetConfigFilePath() string {
-	configFilePath := os.Getenv("CONFUSION_CONFIG_PATH")
-	if configFilePath == "" {
-		panic("CONFUSION_CONFIG_PATH is not set!")
-
-	} else {
-		info, err := os.Stat(configFilePath)
-		if err != nil {
-			panic(err)
-		}
-		if info.IsDir() {
-			panic(configFilePath + " is a directory!")
-		}
-		return configFilePath
-	}
+// GetStringFromBool returns "true" or "false", the way featured flags are
+// represented in the JSON models
+func GetStringFromBool(b bool) *string {
+	boolStr := strconv.FormatBool(b)
+	return &boolStr
+}
+
+func GetEmptyJsonByteArray() []byte {
+	return []byte(EmptyJsonString)
 }
@@ -0,0 +1,93 @@
+package misc
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// UnmatchedRoute is the route pattern of the requests no route matched
+const UnmatchedRoute = "unmatched"
+
+// Router is an httprouter.Router whose routes tell their pattern, e.g.
+// /dishes/:dishId/comments for /dishes/1/comments, to the middleware through
+// GetRoutePattern. httprouter does not tell which route matched, so each
+// handle is wrapped with its pattern when it is registered.
+type Router struct {
+	*httprouter.Router
+}
+
+func NewRouter() *Router {
+	return &Router{Router: httprouter.New()}
+}
+
+func (router *Router) GET(path string, handle httprouter.Handle) {
+	router.Handle(http.MethodGet, path, handle)
+}
+
+func (router *Router) HEAD(path string, handle httprouter.Handle) {
+	router.Handle(http.MethodHead, path, handle)
+}
+
+func (router *Router) OPTIONS(path string, handle httprouter.Handle) {
+	router.Handle(http.MethodOptions, path, handle)
+}
+
+func (router *Router) POST(path string, handle httprouter.Handle) {
+	router.Handle(http.MethodPost, path, handle)
+}
+
+func (router *Router) PUT(path string, handle httprouter.Handle) {
+	router.Handle(http.MethodPut, path, handle)
+}
+
+func (router *Router) PATCH(path string, handle httprouter.Handle) {
+	router.Handle(http.MethodPatch, path, handle)
+}
+
+func (router *Router) DELETE(path string, handle httprouter.Handle) {
+	router.Handle(http.MethodDelete, path, handle)
+}
+
+func (router *Router) Handle(method, path string, handle httprouter.Handle) {
+	router.Router.Handle(method, path, RouteHandle(path, handle))
+}
+
+func (router *Router) Handler(method, path string, handler http.Handler) {
+	router.Handle(method, path, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, ps)))
+	})
+}
+
+func (router *Router) HandlerFunc(method, path string, handler http.HandlerFunc) {
+	router.Handler(method, path, handler)
+}
+
+// routePatternKey is the context key of the pattern of the route a request matched
+type routePatternKey struct{}
+
+// RouteHandle has handle tell pattern to GetRoutePattern, for the handles
+// serving a route of their own behind another one, like /dishes/suggest
+// behind /dishes/:dishId
+func RouteHandle(pattern string, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+		if matched, ok := r.Context().Value(routePatternKey{}).(*string); ok {
+			*matched = pattern
+		}
+		handle(w, r, ps)
+	}
+}
+
+// GetRoutePattern returns r, ready to be served, and a function returning the
+// pattern of the route r matched once it is served, UnmatchedRoute if none did
+func GetRoutePattern(r *http.Request) (*http.Request, func() string) {
+
+	if matched, ok := r.Context().Value(routePatternKey{}).(*string); ok {
+		return r, func() string { return *matched }
+	}
+
+	matched := UnmatchedRoute
+	return r.WithContext(context.WithValue(r.Context(), routePatternKey{}, &matched)), func() string { return matched }
+}
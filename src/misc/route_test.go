@@ -0,0 +1,44 @@
+package misc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestGetRoutePattern(t *testing.T) {
+
+	router := NewRouter()
+	noop := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {}
+	suggest := RouteHandle("/dishes/suggest", noop)
+	router.GET("/dishes/:dishId", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("dishId") == "suggest" {
+			suggest(w, r, ps)
+		}
+	})
+	router.GET("/dishes/:dishId/comments/:commentId", noop)
+	router.PUT("/dishes/:dishId/comments/:commentId", noop)
+	router.HandlerFunc(http.MethodGet, "/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, test := range []struct {
+		method, path, want string
+	}{
+		{http.MethodGet, "/dishes/1", "/dishes/:dishId"},
+		{http.MethodGet, "/dishes/dishId", "/dishes/:dishId"},
+		{http.MethodGet, "/dishes/suggest", "/dishes/suggest"},
+		{http.MethodGet, "/dishes/1/comments/1", "/dishes/:dishId/comments/:commentId"},
+		{http.MethodPut, "/dishes/1/comments/2", "/dishes/:dishId/comments/:commentId"},
+		{http.MethodGet, "/healthz", "/healthz"},
+		{http.MethodGet, "/unknown", UnmatchedRoute},
+		{http.MethodDelete, "/dishes/1", UnmatchedRoute},
+	} {
+
+		r, getRoute := GetRoutePattern(httptest.NewRequest(test.method, test.path, nil))
+		router.ServeHTTP(httptest.NewRecorder(), r)
+		if route := getRoute(); route != test.want {
+			t.Errorf("%s %s: got %s, want %s", test.method, test.path, route, test.want)
+		}
+	}
+}
@@ -4,6 +4,8 @@ package misc
 type Status struct {
 	NumOfRowsAffected int64 `json:"n"`
 	IsOk              int8  `json:"ok"`
+	// ID is the id of the row a Create inserted
+	ID int64 `json:"_id,omitempty"`
 }
 
 func (ds *Status) SetStatus(numOfRowsAffected int64, isOk int8) {
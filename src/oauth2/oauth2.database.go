@@ -9,12 +9,20 @@ import (
 	"confusion.com/bwoo/database"
 )
 
-func createUserInDb(userInfo FacebookUserInfo) (int64, bool) {
+type dbFacebookUserStore struct {
+	db *database.Conn
+}
+
+func NewDbStore(db *database.Conn) FacebookUserStore {
+	return &dbFacebookUserStore{db: db}
+}
+
+func (s *dbFacebookUserStore) CreateFacebookUser(ctx context.Context, userInfo FacebookUserInfo) (int64, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	results, err := database.DbConn.ExecContext(ctx, `INSERT INTO user(
+	userId, err := s.db.InsertReturningId(ctx, `INSERT INTO user(
 															facebookId,
 															firstname,
 															lastname,
@@ -28,19 +36,18 @@ func createUserInDb(userInfo FacebookUserInfo) (int64, bool) {
 		userInfo.LastName,
 		userInfo.Name)
 	if err != nil {
-		return userIdNotFound, false
+		return userIdNotFound, err
 	}
 
-	userId, _ := results.LastInsertId()
-	return userId, true
+	return userId, nil
 }
 
-func getFacebookUserFromDb(facebookId string) (*auth.UserInfo, error) {
+func (s *dbFacebookUserStore) GetFacebookUser(ctx context.Context, facebookId string) (*auth.UserInfo, error) {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	row := database.DbConn.QueryRowContext(ctx, `SELECT 
+	row := s.db.QueryRowContext(ctx, `SELECT 
 													id,
 													admin,
 													firstname,
@@ -55,8 +62,8 @@ func getFacebookUserFromDb(facebookId string) (*auth.UserInfo, error) {
 		&userInfo.Admin,
 		&userInfo.Firstname,
 		&userInfo.Lastname,
-		&userInfo.CreatedAt,
-		&userInfo.UpdatedAt)
+		database.ScanTimestamp(&userInfo.CreatedAt),
+		database.ScanTimestamp(&userInfo.UpdatedAt))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
@@ -0,0 +1,48 @@
+package oauth2
+
+import (
+	"context"
+	"sync"
+
+	"confusion.com/bwoo/auth"
+)
+
+// memoryFacebookUserStore keeps Facebook users in the given auth.UserStore,
+// so they share ids with users who signed up with a password
+type memoryFacebookUserStore struct {
+	mu          sync.RWMutex
+	users       auth.UserStore
+	facebookIds map[string]int64
+}
+
+func NewMemoryStore(users auth.UserStore) FacebookUserStore {
+	return &memoryFacebookUserStore{users: users, facebookIds: make(map[string]int64)}
+}
+
+func (s *memoryFacebookUserStore) CreateFacebookUser(ctx context.Context, userInfo FacebookUserInfo) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := auth.UserInfo{Firstname: userInfo.FirstName, Lastname: userInfo.LastName}
+	user.Username = userInfo.Name
+	userId, err := s.users.CreateUser(ctx, user, nil)
+	if err != nil {
+		return userIdNotFound, err
+	}
+
+	s.facebookIds[userInfo.ID] = userId
+	return userId, nil
+}
+
+func (s *memoryFacebookUserStore) GetFacebookUser(ctx context.Context, facebookId string) (*auth.UserInfo, error) {
+
+	s.mu.RLock()
+	userId, ok := s.facebookIds[facebookId]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+
+	return s.users.GetUser(ctx, userId)
+}
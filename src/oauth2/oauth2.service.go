@@ -1,10 +1,11 @@
 package oauth2
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
@@ -12,32 +13,46 @@ import (
 	"confusion.com/bwoo/auth"
 	"confusion.com/bwoo/config"
 	"confusion.com/bwoo/cors"
+	"confusion.com/bwoo/logging"
 	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/ratelimit"
 	"github.com/julienschmidt/httprouter"
 	"golang.org/x/oauth2"
 )
 
-var facebookConfig *oauth2.Config
+// handlers log in with the Facebook app of config, keeping its users in store
+type handlers struct {
+	config *oauth2.Config
+	store  FacebookUserStore
+}
 
-func SetupRoutes(router *httprouter.Router, config config.Config) {
+func SetupRoutes(router *misc.Router, config config.Config, store FacebookUserStore, limiters *ratelimit.Limiters) {
 
-	facebookConfig = GetOauthFbConfig(config.Oauth2FbClientID,
-		config.Oauth2FbClientSecret,
-		config.Oauth2FbRedirectUrl)
+	if config.Oauth2FbClientID == "" {
+		slog.Info("oauth2_fb_client_id is not set, the Facebook login is disabled")
+		return
+	}
+
+	h := &handlers{
+		config: GetOauthFbConfig(config.Oauth2FbClientID,
+			config.Oauth2FbClientSecret,
+			config.Oauth2FbRedirectUrl),
+		store: store,
+	}
 
 	// facebook OAuth related
-	router.GET("/facebook/login", cors.Cors(loginFacebook))
-	router.GET("/facebook/callback", cors.Cors(facebookLoginCallback))
-	router.GET("/facebook/token", cors.Cors(loginWithFacebookToken))
+	router.GET("/facebook/login", cors.Cors(h.loginFacebook))
+	router.GET("/facebook/callback", cors.Cors(h.facebookLoginCallback))
+	router.GET("/facebook/token", cors.Cors(limiters.RateLimit("login", nil, h.loginWithFacebookToken)))
 
 }
 
 // This requires the browser to call by the user.
 // When the user successfully logged in, facebookLoginCallback() will be called
-func loginFacebook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) loginFacebook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	oauthState := generateStateOauthCookie(w)
-	url := facebookConfig.AuthCodeURL(oauthState)
+	url := h.config.AuthCodeURL(oauthState)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
@@ -53,29 +68,32 @@ func generateStateOauthCookie(w http.ResponseWriter) string {
 	return state
 }
 
-func facebookLoginCallback(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) facebookLoginCallback(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	logger := logging.FromContext(r.Context())
 
 	// Read oauthState from Cookie
 	stateOauthCookie, err := r.Cookie("oauthstate")
 	if err != nil {
-		fmt.Println("Cannot get state token", err)
-		w.WriteHeader(http.StatusUnauthorized)
+		logger.Warn("Cannot get state token", "error", err)
+		misc.WriteError(w, r, misc.NewUnauthorizedError("Missing OAuth state"))
 		return
 	}
 	if stateOauthCookie != nil && r.FormValue("state") != stateOauthCookie.Value {
-		log.Println("invalid OAuth state")
-		w.WriteHeader(http.StatusUnauthorized)
+		logger.Warn("Invalid OAuth state")
+		misc.WriteError(w, r, misc.NewUnauthorizedError("Invalid OAuth state"))
 		return
 	}
 
-	token, err := facebookConfig.Exchange(oauth2.NoContext, r.FormValue("code"))
+	token, err := h.config.Exchange(oauth2.NoContext, r.FormValue("code"))
 	if err != nil {
-		fmt.Printf("facebookConfig.Exchange() failed with '%s'\n", err)
-		w.WriteHeader(http.StatusUnauthorized)
+		logger.Warn("Exchange of the Facebook code failed", "error", err)
+		misc.WriteError(w, r, misc.NewUnauthorizedError("Unable to login to Facebook"))
 		return
 	}
 
-	fmt.Println("Facebook Access Token:", token.AccessToken)
+	// the access token itself is never logged
+	logger.Info("Facebook login callback succeeded", "expiry", token.Expiry)
 }
 
 func getAccessToken(r *http.Request, ps httprouter.Params) (string, error) {
@@ -98,38 +116,39 @@ func getAccessToken(r *http.Request, ps httprouter.Params) (string, error) {
 	return "", fmt.Errorf("AccessToken NOT found")
 }
 
-func loginWithFacebookToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+func (h *handlers) loginWithFacebookToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	accessToken, err := getAccessToken(r, ps)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
+		misc.WriteError(w, r, misc.NewUnauthorizedError(err.Error()))
 		return
 	}
 
 	resp, err := http.Get("https://graph.facebook.com/me?fields=id,name,first_name,last_name,email&access_token=" +
 		url.QueryEscape(accessToken))
-	if resp.StatusCode >= http.StatusBadRequest {
-		fmt.Println("Unable to login to Facebook")
-		w.WriteHeader(http.StatusUnauthorized)
-		return
-	}
 	if err != nil {
-		fmt.Printf("Facebook Graph API error: %s\n", err)
-		w.WriteHeader(http.StatusUnauthorized)
+		logging.FromContext(r.Context()).Warn("Facebook Graph API error", "error", err)
+		misc.WriteError(w, r, misc.NewUnauthorizedError("Unable to login to Facebook"))
 		return
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= http.StatusBadRequest {
+		logging.FromContext(r.Context()).Warn("Unable to login to Facebook", "status", resp.StatusCode)
+		misc.WriteError(w, r, misc.NewUnauthorizedError("Unable to login to Facebook"))
+		return
+	}
+
+	// the body comes from the Graph API, not from the client
 	facebookUserInfo, err := getFacebookUserInfoFromBody(resp.Body)
 	if err != nil {
-		fmt.Printf("ReadAll: %s\n", err)
-		w.WriteHeader(http.StatusUnauthorized)
+		misc.WriteError(w, r, fmt.Errorf("Error decoding the Facebook user info: %v", err))
 		return
 	}
 
-	userInfo, err := findUserByFacebookIdCreateIfNotFound(facebookUserInfo)
+	userInfo, err := h.findUserByFacebookIdCreateIfNotFound(r.Context(), facebookUserInfo)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 
@@ -143,17 +162,17 @@ func loginWithFacebookToken(w http.ResponseWriter, r *http.Request, ps httproute
 	w.Write(resultJson)
 }
 
-func findUserByFacebookIdCreateIfNotFound(facebookUserInfo FacebookUserInfo) (*auth.UserInfo, error) {
+func (h *handlers) findUserByFacebookIdCreateIfNotFound(ctx context.Context, facebookUserInfo FacebookUserInfo) (*auth.UserInfo, error) {
 
-	userInfo, err := getFacebookUserFromDb(facebookUserInfo.ID)
+	userInfo, err := h.store.GetFacebookUser(ctx, facebookUserInfo.ID)
 	if err != nil {
 		return nil, err
 	} else if userInfo == nil {
-		_, ok := createUserInDb(facebookUserInfo)
-		if !ok {
+		_, err := h.store.CreateFacebookUser(ctx, facebookUserInfo)
+		if err != nil {
 			return nil, fmt.Errorf("Unable to create user")
 		}
-		userInfo, _ = getFacebookUserFromDb(facebookUserInfo.ID)
+		userInfo, _ = h.store.GetFacebookUser(ctx, facebookUserInfo.ID)
 	}
 
 	return userInfo, nil
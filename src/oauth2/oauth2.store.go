@@ -0,0 +1,13 @@
+package oauth2
+
+import (
+	"context"
+
+	"confusion.com/bwoo/auth"
+)
+
+// FacebookUserStore is the persistence layer for users logging in with Facebook.
+type FacebookUserStore interface {
+	CreateFacebookUser(ctx context.Context, userInfo FacebookUserInfo) (int64, error)
+	GetFacebookUser(ctx context.Context, facebookId string) (*auth.UserInfo, error)
+}
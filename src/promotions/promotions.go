@@ -1,13 +1,25 @@
 package promotions
 
+import "confusion.com/bwoo/resource"
+
 type Promotion struct {
 	ID          int64   `json:"_id"`
-	Name        *string `json:"name"`
-	Image       *string `json:"image"`
-	Label       *string `json:"label"`
-	Price       *string `json:"price"`
-	Featured    *string `json:"featured"`
-	Description *string `json:"description"`
+	Name        *string `json:"name" validate:"required,max=50"`
+	Image       *string `json:"image" validate:"required,max=50"`
+	Label       *string `json:"label" validate:"max=20"`
+	Price       *string `json:"price" validate:"required,number"`
+	Featured    *string `json:"featured" validate:"oneof=true false"`
+	Description *string `json:"description" validate:"required"`
 	CreatedAt   *string `json:"createdAt"`
 	UpdatedAt   *string `json:"updatedAt"`
 }
+
+// Resource declares the promotion table and its routes under /promotions
+var Resource = resource.New[Promotion](resource.Definition{
+	Name:          "promotion",
+	Path:          "/promotions",
+	Table:         "promotion",
+	Columns:       []string{"name", "image", "label", "price", "featured", "description"},
+	NumberColumns: []string{"price"},
+	CacheControl:  "public, max-age=300",
+})
@@ -0,0 +1,18 @@
+package promotions
+
+import (
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/resource"
+)
+
+// PromotionStore is the persistence layer used by the promotion handlers, a
+// resource.Store of the promotion table.
+type PromotionStore = resource.Store[Promotion]
+
+func NewDbStore(db *database.Conn) PromotionStore {
+	return resource.NewDbStore(db, Resource)
+}
+
+func NewMemoryStore() PromotionStore {
+	return resource.NewMemoryStore(Resource)
+}
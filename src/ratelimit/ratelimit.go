@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"confusion.com/bwoo/config"
+)
+
+// bucket is the token bucket of one client: it holds up to burst tokens,
+// refilled at rate tokens per second, and every request takes one
+type bucket struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+// limiter holds the buckets of every client of one limit
+type limiter struct {
+	limit config.RateLimit
+	// tokens per second
+	rate float64
+
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// result tells whether a request may pass and what the RateLimit-* headers report
+type result struct {
+	allowed   bool
+	remaining int
+	// time until the next token, when the request is refused
+	retryAfter time.Duration
+	// time until the bucket is full again
+	reset time.Duration
+}
+
+func newLimiter(limit config.RateLimit) *limiter {
+	return &limiter{
+		limit:     limit,
+		rate:      float64(limit.Requests) / limit.Period.Seconds(),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *limiter) allow(key string, now time.Time) result {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), lastFill: now}
+		l.buckets[key] = b
+	}
+	l.fill(b, now)
+
+	var res result
+	if b.tokens >= 1 {
+		b.tokens--
+		res.allowed = true
+	} else {
+		res.retryAfter = l.getDuration(1 - b.tokens)
+	}
+	res.remaining = int(math.Floor(b.tokens))
+	res.reset = l.getDuration(float64(l.limit.Burst) - b.tokens)
+	return res
+}
+
+func (l *limiter) fill(b *bucket, now time.Time) {
+
+	b.tokens = math.Min(float64(l.limit.Burst), b.tokens+now.Sub(b.lastFill).Seconds()*l.rate)
+	b.lastFill = now
+}
+
+// getDuration returns the time it takes to refill tokens
+func (l *limiter) getDuration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep forgets the clients whose bucket is full again once per period,
+// a full bucket is the same as no bucket
+func (l *limiter) sweep(now time.Time) {
+
+	if now.Sub(l.lastSweep) < l.limit.Period {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		l.fill(b, now)
+		if b.tokens >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/metrics"
+	"confusion.com/bwoo/misc"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Limiters are the limiters of rate_limits by name. Every router has its
+// own, so the clients of one router never take the tokens of another.
+// A nil *Limiters lets every request through.
+type Limiters struct {
+	limiters map[string]*limiter
+}
+
+// UserIdFunc returns the id of the user verified for a request, empty for
+// anonymous requests
+type UserIdFunc func(r *http.Request) string
+
+// New creates the limiters of rate_limits
+func New(config config.Config) (*Limiters, error) {
+
+	limits, err := config.GetRateLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Limiters{limiters: make(map[string]*limiter)}
+	for name, limit := range limits {
+		l.limiters[name] = newLimiter(limit)
+	}
+	return l, nil
+}
+
+// getKey returns the client a request counts against: the user told by
+// getUserId, the IP address for anonymous requests or without getUserId
+func getKey(r *http.Request, getUserId UserIdFunc) string {
+
+	if getUserId != nil {
+		if userId := getUserId(r); userId != "" {
+			return "user:" + userId
+		}
+	}
+	return "ip:" + misc.GetClientIp(r)
+}
+
+func getSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// RateLimit lets the requests of a client through while the limit called
+// name in rate_limits allows it, answering 429 with a Retry-After header
+// otherwise. A name without a limit lets every request through. getUserId,
+// nil on the routes of anonymous users, tells the users apart, which runs
+// after auth.VerifyUser.
+func (limiters *Limiters) RateLimit(name string, getUserId UserIdFunc, next httprouter.Handle) httprouter.Handle {
+
+	if limiters == nil {
+		return next
+	}
+	l, ok := limiters.limiters[name]
+	if !ok {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+		res := l.allow(getKey(r, getUserId), time.Now())
+
+		// the RateLimit header fields of the IETF httpapi draft
+		wHeader := w.Header()
+		wHeader.Set("RateLimit-Limit", strconv.Itoa(l.limit.Burst))
+		wHeader.Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+		wHeader.Set("RateLimit-Reset", getSeconds(res.reset))
+		wHeader.Set("RateLimit-Policy", strconv.Itoa(l.limit.Requests)+";w="+getSeconds(l.limit.Period)+
+			";burst="+strconv.Itoa(l.limit.Burst))
+
+		if !res.allowed {
+			metrics.RateLimited.WithLabelValues(name).Inc()
+			wHeader.Set("Retry-After", getSeconds(res.retryAfter))
+			misc.WriteError(w, r, misc.NewTooManyRequestsError("Too many requests, retry in "+getSeconds(res.retryAfter)+"s"))
+			return
+		}
+		next(w, r, ps)
+	}
+}
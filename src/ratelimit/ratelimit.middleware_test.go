@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/misc"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestRateLimit(t *testing.T) {
+
+	limiters := &Limiters{limiters: map[string]*limiter{
+		"test": newLimiter(config.RateLimit{Requests: 60, Period: time.Minute, Burst: 2}),
+	}}
+
+	// the users are told by a header
+	getUserId := func(r *http.Request) string { return r.Header.Get("X-User") }
+
+	ok := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {}
+
+	for i, test := range []struct {
+		name       string
+		remoteAddr string
+		user       string
+		wantStatus int
+		// the RateLimit-* headers, none when empty
+		remaining, reset, retryAfter string
+	}{
+		{"test", "192.0.2.1:1234", "", http.StatusOK, "1", "1", ""},
+		{"test", "192.0.2.1:5678", "", http.StatusOK, "0", "2", ""},
+		{"test", "192.0.2.1:1234", "", http.StatusTooManyRequests, "0", "2", "1"},
+		// another address, and users whatever their address, have their own limit
+		{"test", "192.0.2.2:1234", "", http.StatusOK, "1", "1", ""},
+		{"test", "192.0.2.1:1234", "jane", http.StatusOK, "1", "1", ""},
+		{"test", "192.0.2.1:1234", "joe", http.StatusOK, "1", "1", ""},
+		// a name without a limit is not limited
+		{"unlimited", "192.0.2.1:1234", "", http.StatusOK, "", "", ""},
+	} {
+
+		r := httptest.NewRequest(http.MethodPost, "/comments", nil)
+		r.RemoteAddr = test.remoteAddr
+		if test.user != "" {
+			r.Header.Set("X-User", test.user)
+		}
+		w := httptest.NewRecorder()
+		limiters.RateLimit(test.name, getUserId, ok)(w, r, nil)
+
+		if w.Code != test.wantStatus {
+			t.Errorf("request %d: got status %d, want %d", i, w.Code, test.wantStatus)
+		}
+
+		wantHeaders := map[string]string{
+			"RateLimit-Remaining": test.remaining,
+			"RateLimit-Reset":     test.reset,
+			"Retry-After":         test.retryAfter,
+		}
+		if test.remaining != "" {
+			wantHeaders["RateLimit-Limit"] = "2"
+			wantHeaders["RateLimit-Policy"] = "60;w=60;burst=2"
+		}
+		for header, want := range wantHeaders {
+			if got := w.Header().Get(header); got != want {
+				t.Errorf("request %d: %s: got %q, want %q", i, header, got, want)
+			}
+		}
+
+		if test.wantStatus == http.StatusTooManyRequests {
+			var response struct{ Error misc.Error }
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error.Code != misc.ErrorCodeTooManyRequests {
+				t.Errorf("request %d: got %s, want a too_many_requests error", i, w.Body.String())
+			}
+		}
+	}
+}
+
+// every router has its own limiters, and the routes without them are not limited
+func TestLimitersOfEachRouter(t *testing.T) {
+
+	ok := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {}
+	serve := func(limiters *Limiters, getUserId UserIdFunc) int {
+
+		r := httptest.NewRequest(http.MethodPost, "/users/login", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		limiters.RateLimit("login", getUserId, ok)(w, r, nil)
+		return w.Code
+	}
+
+	first, err := New(config.Config{RateLimits: "login=1/1m"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	second, _ := New(config.Config{RateLimits: "login=1/1m"})
+	anonymous := func(r *http.Request) string { return "" }
+
+	for i, test := range []struct {
+		limiters   *Limiters
+		getUserId  UserIdFunc
+		wantStatus int
+	}{
+		{first, nil, http.StatusOK},
+		// an anonymous request counts against the IP address as without getUserId
+		{first, anonymous, http.StatusTooManyRequests},
+		{second, nil, http.StatusOK},
+		{second, nil, http.StatusTooManyRequests},
+		{nil, nil, http.StatusOK},
+	} {
+
+		if got := serve(test.limiters, test.getUserId); got != test.wantStatus {
+			t.Errorf("request %d: got status %d, want %d", i, got, test.wantStatus)
+		}
+	}
+
+	if _, err := New(config.Config{RateLimits: "login=ten/1m"}); err == nil {
+		t.Errorf("New with an invalid limit: got no error, want one")
+	}
+}
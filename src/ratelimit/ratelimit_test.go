@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/config"
+)
+
+func TestAllow(t *testing.T) {
+
+	// a token per second, up to 3
+	l := newLimiter(config.RateLimit{Requests: 2, Period: 2 * time.Second, Burst: 3})
+	start := time.Now()
+
+	for i, test := range []struct {
+		elapsed    time.Duration
+		key        string
+		want       bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{0, "ip:a", true, 2, 0, time.Second},
+		{0, "ip:a", true, 1, 0, 2 * time.Second},
+		{0, "ip:a", true, 0, 0, 3 * time.Second},
+		{0, "ip:a", false, 0, time.Second, 3 * time.Second},
+		// every client has its own bucket
+		{0, "ip:b", true, 2, 0, time.Second},
+		{500 * time.Millisecond, "ip:a", false, 0, 500 * time.Millisecond, 2500 * time.Millisecond},
+		{time.Second, "ip:a", true, 0, 0, 3 * time.Second},
+		// the bucket never holds more than the burst
+		{time.Minute, "ip:a", true, 2, 0, time.Second},
+	} {
+
+		res := l.allow(test.key, start.Add(test.elapsed))
+		if res.allowed != test.want || res.remaining != test.remaining || res.retryAfter != test.retryAfter || res.reset != test.reset {
+			t.Errorf("request %d of %s after %v: got %+v, want allowed %v, remaining %d, retryAfter %v, reset %v",
+				i, test.key, test.elapsed, res, test.want, test.remaining, test.retryAfter, test.reset)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+
+	l := newLimiter(config.RateLimit{Requests: 10, Period: time.Minute, Burst: 10})
+	start := time.Now()
+
+	l.allow("ip:a", start)
+	for i := 0; i < 5; i++ {
+		l.allow("ip:b", start.Add(55*time.Second))
+	}
+	if len(l.buckets) != 2 {
+		t.Fatalf("got %d buckets, want 2", len(l.buckets))
+	}
+
+	// a period later, the bucket of a is full again and forgotten, not the one of b
+	l.allow("ip:c", start.Add(time.Minute+time.Second))
+	if _, ok := l.buckets["ip:a"]; ok || len(l.buckets) != 2 {
+		t.Errorf("after a period: got %d buckets, want the ones of b and c", len(l.buckets))
+	}
+
+	// the next sweep waits for another period
+	l.allow("ip:d", start.Add(time.Minute+50*time.Second))
+	if len(l.buckets) != 3 {
+		t.Errorf("within the period: got %d buckets, want 3", len(l.buckets))
+	}
+}
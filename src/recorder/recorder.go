@@ -0,0 +1,53 @@
+package recorder
+
+import "net/http"
+
+// ResponseRecorder remembers the status and the size of the response written
+// through it, for the middlewares which report them: the access log, the
+// metrics and the traces
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func New(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w}
+}
+
+func (rec *ResponseRecorder) WriteHeader(status int) {
+
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *ResponseRecorder) Write(b []byte) (int, error) {
+
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the Flusher and Hijacker of the connection
+func (rec *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Status returns the status of the response, 200 when the handler wrote none
+func (rec *ResponseRecorder) Status() int {
+
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// Bytes returns the size of the body written so far
+func (rec *ResponseRecorder) Bytes() int64 {
+	return rec.bytes
+}
@@ -0,0 +1,147 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"confusion.com/bwoo/cache"
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/metrics"
+	"confusion.com/bwoo/misc"
+)
+
+// cachedStore reads the rows through a cache. Its keys hold the generation of
+// the resource, a counter incremented by every change, so a change drops all
+// the cached rows and pages at once. A page read before a change and cached
+// after it is cached under the former generation, where it is never read.
+type cachedStore[T any] struct {
+	store   Store[T]
+	res     *Resource[T]
+	backend cache.Backend
+	ttl     time.Duration
+}
+
+// cachedPage is a cached result of List
+type cachedPage[T any] struct {
+	Items []T              `json:"items"`
+	Info  listing.PageInfo `json:"info"`
+}
+
+// NewCachedStore returns store, with its reads cached in backend for ttl and
+// dropped by the changes made through it
+func NewCachedStore[T any](store Store[T], res *Resource[T], backend cache.Backend, ttl time.Duration) Store[T] {
+	return &cachedStore[T]{store: store, res: res, backend: backend, ttl: ttl}
+}
+
+func (s *cachedStore[T]) getGenerationKey() string {
+	return cache.KeyPrefix + s.res.Name + ":generation"
+}
+
+// getKey returns the key of a read in the current generation
+func (s *cachedStore[T]) getKey(ctx context.Context, read string) (string, error) {
+
+	generation, err := s.backend.GetCounter(ctx, s.getGenerationKey())
+	if err != nil {
+		return "", err
+	}
+	return cache.KeyPrefix + s.res.Name + ":" + strconv.FormatInt(generation, 10) + ":" + read, nil
+}
+
+// readThrough returns the result of the read from the cache, or from load,
+// caching it. The cache failing only costs the read from the store.
+func readThrough[V any, T any](ctx context.Context, s *cachedStore[T], read string, load func() (V, error)) (V, error) {
+
+	logger := logging.FromContext(ctx)
+
+	key, err := s.getKey(ctx, read)
+	if err == nil {
+		var data []byte
+		var ok bool
+		if data, ok, err = s.backend.Get(ctx, key); err == nil && ok {
+			var value V
+			if err = json.Unmarshal(data, &value); err == nil {
+				metrics.CacheRequests.WithLabelValues(s.res.Name, "hit").Inc()
+				return value, nil
+			}
+		}
+	}
+
+	if err != nil {
+		metrics.CacheRequests.WithLabelValues(s.res.Name, "error").Inc()
+		logger.Warn("Error reading the cache", "key", key, "error", err)
+	} else {
+		metrics.CacheRequests.WithLabelValues(s.res.Name, "miss").Inc()
+	}
+
+	value, loadErr := load()
+	if loadErr != nil || key == "" {
+		return value, loadErr
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = s.backend.Set(ctx, key, data, s.ttl)
+	}
+	if err != nil {
+		logger.Warn("Error writing the cache", "key", key, "error", err)
+	}
+	return value, nil
+}
+
+// invalidate starts a new generation after a change, failed or not
+func (s *cachedStore[T]) invalidate(ctx context.Context) {
+
+	if _, err := s.backend.Incr(ctx, s.getGenerationKey()); err != nil {
+		logging.FromContext(ctx).Error("Error invalidating the cache, it is stale until cache_ttl", "resource", s.res.Name, "error", err)
+	}
+}
+
+func (s *cachedStore[T]) Get(ctx context.Context, id int64) (*T, error) {
+
+	return readThrough(ctx, s, "get:"+strconv.FormatInt(id, 10), func() (*T, error) {
+		return s.store.Get(ctx, id)
+	})
+}
+
+func (s *cachedStore[T]) List(ctx context.Context, params listing.Params) ([]T, listing.PageInfo, error) {
+
+	paramsJson, err := json.Marshal(params)
+	if err != nil {
+		return nil, listing.PageInfo{}, err
+	}
+
+	result, err := readThrough(ctx, s, "list:"+string(paramsJson), func() (cachedPage[T], error) {
+		items, info, err := s.store.List(ctx, params)
+		return cachedPage[T]{Items: items, Info: info}, err
+	})
+	return result.Items, result.Info, err
+}
+
+func (s *cachedStore[T]) Create(ctx context.Context, item T) (*misc.Status, error) {
+
+	defer s.invalidate(ctx)
+	return s.store.Create(ctx, item)
+}
+
+// Update and Delete hand the precondition to store, which checks it on the row
+// it holds, never on a cached one
+func (s *cachedStore[T]) Update(ctx context.Context, id int64, item T, precondition Precondition[T]) (*T, error) {
+
+	defer s.invalidate(ctx)
+	return s.store.Update(ctx, id, item, precondition)
+}
+
+func (s *cachedStore[T]) Delete(ctx context.Context, id int64, precondition Precondition[T]) (*misc.Status, error) {
+
+	defer s.invalidate(ctx)
+	return s.store.Delete(ctx, id, precondition)
+}
+
+func (s *cachedStore[T]) DeleteAll(ctx context.Context) (*misc.Status, error) {
+
+	defer s.invalidate(ctx)
+	return s.store.DeleteAll(ctx)
+}
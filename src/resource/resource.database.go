@@ -0,0 +1,299 @@
+package resource
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/misc"
+)
+
+type dbStore[T any] struct {
+	db  *database.Conn
+	res *Resource[T]
+}
+
+func NewDbStore[T any](db *database.Conn, res *Resource[T]) Store[T] {
+	return &dbStore[T]{db: db, res: res}
+}
+
+// selectColumns lists the columns read into the model
+func (s *dbStore[T]) selectColumns() string {
+	return "id, " + strings.Join(s.res.Columns, ", ") + ", createdAt, updatedAt"
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *dbStore[T]) scan(row rowScanner) (*T, error) {
+
+	var item T
+	value := reflect.ValueOf(&item).Elem()
+
+	// the featured column is a BOOLEAN, the model holds "true" or "false"
+	var featured bool
+	featuredIndex := -1
+
+	dest := []interface{}{value.FieldByIndex(s.res.idField).Addr().Interface()}
+	for i, column := range s.res.Columns {
+		if column == FeaturedColumn {
+			featuredIndex = i
+			dest = append(dest, &featured)
+		} else {
+			dest = append(dest, value.FieldByIndex(s.res.columnFields[i]).Addr().Interface())
+		}
+	}
+	dest = append(dest,
+		database.ScanNullTimestamp(value.FieldByIndex(s.res.createdAtField).Addr().Interface().(**string)),
+		database.ScanNullTimestamp(value.FieldByIndex(s.res.updatedAtField).Addr().Interface().(**string)))
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	if featuredIndex >= 0 {
+		value.FieldByIndex(s.res.columnFields[featuredIndex]).Set(reflect.ValueOf(misc.GetStringFromBool(featured)))
+	}
+	return &item, nil
+}
+
+// getConflictError names the duplicate value of the unique column, if the
+// conflict is on it, so the client learns which value is taken
+func (s *dbStore[T]) getConflictError(err error, item *T) error {
+
+	value, ok := s.res.getUniqueValue(item)
+	if !misc.HasErrorCode(err, misc.ErrorCodeConflict) || !ok {
+		return err
+	}
+	return misc.NewConflictError(fmt.Sprintf("Duplicate %s %s %s", s.res.Name, s.res.UniqueColumn, value))
+}
+
+func (s *dbStore[T]) Create(ctx context.Context, item T) (*misc.Status, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// the columns left out get the default of the table
+	columns := make([]string, 0, len(s.res.Columns))
+	args := make([]interface{}, 0, len(s.res.Columns))
+	for i, column := range s.res.Columns {
+		if arg := s.res.getColumnArg(&item, i); arg != nil {
+			columns = append(columns, column)
+			args = append(args, arg)
+		}
+	}
+
+	status := &misc.Status{}
+	if len(columns) == 0 {
+		status.SetStatus(0, 0)
+		return status, misc.NewBadRequestError(fmt.Sprintf("Missing required %s fields", s.res.Name))
+	}
+
+	id, err := s.db.InsertReturningId(ctx, fmt.Sprintf(`INSERT INTO %s(%s) VALUES (%s)`,
+		s.res.Table, strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")),
+		args...)
+	if err != nil {
+		status.SetStatus(0, 0)
+		return status, s.getConflictError(err, &item)
+	}
+
+	status.SetStatus(1, 1)
+	status.ID = id
+	return status, nil
+}
+
+func (s *dbStore[T]) DeleteAll(ctx context.Context) (*misc.Status, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	status := &misc.Status{}
+	results, err := s.db.ExecContext(ctx, `DELETE FROM `+s.res.Table)
+	if err != nil {
+		status.SetStatus(0, 0)
+		return status, err
+	}
+
+	numRowsDeleted, _ := results.RowsAffected()
+	status.SetStatus(numRowsDeleted, 1)
+	return status, nil
+}
+
+// change runs apply on the connection, or when there is a precondition, in a
+// transaction which checks it on the current row, locked until apply is done
+func (s *dbStore[T]) change(ctx context.Context, id int64, precondition Precondition[T], apply func(q database.Queryer) error) error {
+
+	if precondition == nil {
+		return apply(s.db)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.get(ctx, tx, id, s.db.Dialect.ForUpdate())
+	if err == nil {
+		err = precondition(current)
+	}
+	if err == nil {
+		err = apply(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *dbStore[T]) Delete(ctx context.Context, id int64, precondition Precondition[T]) (*misc.Status, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	status := &misc.Status{}
+	err := s.change(ctx, id, precondition, func(q database.Queryer) error {
+
+		results, err := q.ExecContext(ctx, `DELETE FROM `+s.res.Table+` WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+
+		numRowsDeleted, _ := results.RowsAffected()
+		status.SetStatus(numRowsDeleted, 1)
+		return nil
+	})
+	if err != nil {
+		status.SetStatus(0, 0)
+		return status, err
+	}
+
+	return status, nil
+}
+
+func (s *dbStore[T]) Update(ctx context.Context, id int64, item T, precondition Precondition[T]) (*T, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	assignments := make([]string, 0, len(s.res.Columns))
+	args := make([]interface{}, 0, len(s.res.Columns)+1)
+	for i, column := range s.res.Columns {
+		if arg := s.res.getColumnArg(&item, i); arg != nil {
+			assignments = append(assignments, column+" = ?")
+			args = append(args, arg)
+		}
+	}
+
+	if len(assignments) == 0 {
+		return nil, misc.NewBadRequestError("Nothing to update")
+	}
+
+	args = append(args, id)
+	var updated *T
+	err := s.change(ctx, id, precondition, func(q database.Queryer) error {
+
+		results, err := q.ExecContext(ctx, `UPDATE `+s.res.Table+` SET `+strings.Join(assignments, ", ")+` WHERE id = ?`,
+			args...)
+		if err != nil {
+			err = s.getConflictError(err, &item)
+			if !misc.HasErrorCode(err, misc.ErrorCodeConflict) {
+				logging.FromContext(ctx).Error("Error updating record", "table", s.res.Table, "id", id, "error", err)
+			}
+			return err
+		}
+
+		numRowsUpdated, _ := results.RowsAffected()
+		if numRowsUpdated == 0 {
+			return misc.NewNotFoundError(fmt.Sprintf("%s %d not found", s.res.Title(), id))
+		}
+
+		updated, err = s.get(ctx, q, id, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *dbStore[T]) Get(ctx context.Context, id int64) (*T, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	return s.get(ctx, s.db, id, "")
+}
+
+// get reads the row, with the lock of forUpdate
+func (s *dbStore[T]) get(ctx context.Context, q database.Queryer, id int64, forUpdate string) (*T, error) {
+
+	row := q.QueryRowContext(ctx, `SELECT `+s.selectColumns()+` FROM `+s.res.Table+` WHERE id = ?`+forUpdate, id)
+
+	item, err := s.scan(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (s *dbStore[T]) List(ctx context.Context, params listing.Params) ([]T, listing.PageInfo, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var info listing.PageInfo
+	conditions, args := params.Where()
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+s.res.Table+getWhere(conditions), args...).Scan(&info.Total); err != nil {
+		return nil, info, err
+	}
+
+	// the count is of all the pages, the cursor only selects the rows of this one
+	if seek, seekArgs := params.Seek(); seek != "" {
+		conditions = append(conditions, seek)
+		args = append(args, seekArgs...)
+	}
+	page, pageArgs := params.Page()
+	rows, err := s.db.QueryContext(ctx, `SELECT `+s.selectColumns()+` FROM `+s.res.Table+getWhere(conditions)+
+		params.OrderBy("id")+page, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, info, err
+	}
+	defer rows.Close()
+
+	items := make([]T, 0)
+	for rows.Next() {
+
+		item, err := s.scan(rows)
+		if err != nil {
+			return nil, info, err
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, info, err
+	}
+
+	items, info.More = listing.Trim(items, params)
+	return items, info, nil
+}
+
+func getWhere(conditions []string) string {
+
+	if len(conditions) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(conditions, " AND ")
+}
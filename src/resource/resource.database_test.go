@@ -0,0 +1,140 @@
+package resource
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/database"
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/misc"
+)
+
+// the tables of testItem for each db_driver, priced like the dishes and the
+// promotions since 0004_price_decimal
+var testItemTables = map[string]string{
+	config.SQLiteDriver: `CREATE TABLE item (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		name      VARCHAR(50) UNIQUE NOT NULL,
+		price     FLOAT NOT NULL DEFAULT 0,
+		featured  BOOLEAN NOT NULL DEFAULT 0,
+		createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+	config.MySQLDriver: `CREATE TABLE item (
+		id        INT AUTO_INCREMENT PRIMARY KEY,
+		name      VARCHAR(50) UNIQUE NOT NULL,
+		price     DECIMAL(10,2) NOT NULL DEFAULT 0,
+		featured  BOOLEAN NOT NULL DEFAULT false,
+		createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP)`,
+	config.PostgresDriver: `CREATE TABLE item (
+		id        SERIAL PRIMARY KEY,
+		name      VARCHAR(50) UNIQUE NOT NULL,
+		price     NUMERIC(10,2) NOT NULL DEFAULT 0,
+		featured  BOOLEAN NOT NULL DEFAULT false,
+		createdAt TIMESTAMP(0) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP)`,
+}
+
+// openTestDb connects to a database of dbDriver holding an empty item table.
+// SQLite runs in a temporary file, MySQL and PostgreSQL on the servers of the
+// CONFUSION_TEST_MYSQL_DSN and CONFUSION_TEST_POSTGRES_DSN connection strings,
+// the test is skipped without them.
+func openTestDb(t *testing.T, dbDriver string) *database.Conn {
+
+	t.Helper()
+
+	dialect, _ := database.GetDialect(dbDriver)
+	var connString string
+	switch dbDriver {
+	case config.SQLiteDriver:
+		connString = (&config.Config{DbDriver: dbDriver, DbName: filepath.Join(t.TempDir(), "test.db")}).GetConnString()
+	case config.MySQLDriver:
+		connString = os.Getenv("CONFUSION_TEST_MYSQL_DSN")
+	case config.PostgresDriver:
+		connString = os.Getenv("CONFUSION_TEST_POSTGRES_DSN")
+	}
+	if connString == "" {
+		t.Skipf("no %s database to test on", dbDriver)
+	}
+
+	db, err := sql.Open(dialect.DriverName(), connString)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	conn := &database.Conn{DB: db, Dialect: dialect}
+	ctx := context.Background()
+	if _, err := conn.ExecContext(ctx, `DROP TABLE IF EXISTS item`); err != nil {
+		t.Fatalf("DROP TABLE: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, testItemTables[dbDriver]); err != nil {
+		t.Fatalf("CREATE TABLE: %v", err)
+	}
+	t.Cleanup(func() { conn.ExecContext(context.Background(), `DROP TABLE IF EXISTS item`) })
+	return conn
+}
+
+var testDbDrivers = []string{config.SQLiteDriver, config.MySQLDriver, config.PostgresDriver}
+
+func TestDbStoreTimestamps(t *testing.T) {
+
+	for _, dbDriver := range testDbDrivers {
+		t.Run(dbDriver, func(t *testing.T) {
+
+			store := NewDbStore(openTestDb(t, dbDriver), testResource)
+			ctx := context.Background()
+
+			status, err := store.Create(ctx, newTestItem("soup", "4"))
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			item, err := store.Get(ctx, status.ID)
+			if err != nil || item == nil {
+				t.Fatalf("Get: got %v, %v", item, err)
+			}
+			list, _, err := store.List(ctx, listing.Params{})
+			if err != nil || len(list) != 1 {
+				t.Fatalf("List: got %v, %v", list, err)
+			}
+
+			for name, timestamp := range map[string]*string{
+				"Get createdAt":  item.CreatedAt,
+				"Get updatedAt":  item.UpdatedAt,
+				"List createdAt": list[0].CreatedAt,
+			} {
+				if timestamp == nil {
+					t.Errorf("%s: got nil", name)
+				} else if _, err := time.Parse(misc.TimestampFormat, *timestamp); err != nil {
+					t.Errorf("%s: got %q, want the format %s", name, *timestamp, misc.TimestampFormat)
+				}
+			}
+		})
+	}
+}
+
+func TestDbStorePages(t *testing.T) {
+
+	for _, dbDriver := range testDbDrivers {
+		t.Run(dbDriver, func(t *testing.T) {
+
+			store := NewDbStore(openTestDb(t, dbDriver), testResource)
+			for _, item := range testPagingItems {
+				if _, err := store.Create(context.Background(), item); err != nil {
+					t.Fatalf("Create: %v", err)
+				}
+			}
+
+			router := misc.NewRouter()
+			SetupRoutes(router, testResource, store)
+			testPaging(t, router)
+			testFiltering(t, router)
+		})
+	}
+}
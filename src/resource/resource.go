@@ -0,0 +1,210 @@
+package resource
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/validation"
+)
+
+// FeaturedColumn is the BOOLEAN column the collections are filtered on with
+// ?featured=true. The models hold it as the strings "true" or "false".
+const FeaturedColumn = "featured"
+
+// Definition declares a resource: a table with an id, createdAt and updatedAt
+// column, and a model struct with a field for each of those and for each of
+// Columns, found by its JSON name. The column fields are pointers, nil when
+// left out of a request body.
+type Definition struct {
+	// Name is the singular name of a row, like "dish". It names the id
+	// parameter of the routes, like :dishId, and appears in the error messages.
+	Name string
+	// Path is the path of the collection, like "/dishes"
+	Path string
+	// Table is the table the rows are stored in
+	Table string
+	// Columns are the columns written by the requests, in the order of the table
+	Columns []string
+	// UniqueColumn is the column with a UNIQUE constraint, if any, named in the conflict errors
+	UniqueColumn string
+	// NumberColumns are the columns holding numbers, like price, compared as
+	// numbers when the collection is filtered or sorted on them
+	NumberColumns []string
+	// CacheControl is the Cache-Control policy of the GET responses, like
+	// "public, max-age=60", none when empty
+	CacheControl string
+}
+
+// Resource is a Definition bound to its model T
+type Resource[T any] struct {
+	Definition
+	idField        []int
+	createdAtField []int
+	updatedAtField []int
+	columnFields   [][]int
+	// fields are the fields the collection is filtered and sorted on, with
+	// the index of their struct field in fieldIndexes
+	fields       []listing.Field
+	fieldIndexes map[string][]int
+}
+
+// New checks that T has a field for each column of def, and validate tags
+// fitting its fields, and returns the resource. It panics otherwise, as the
+// definitions are declared in the code.
+func New[T any](def Definition) *Resource[T] {
+
+	res := &Resource[T]{Definition: def}
+	fields := getJsonFields(reflect.TypeOf((*T)(nil)).Elem())
+
+	getField := func(name string, kinds ...reflect.Kind) []int {
+		field, ok := fields[name]
+		if !ok {
+			panic(fmt.Sprintf("Resource %s: no field for the column %s", def.Name, name))
+		}
+		for _, kind := range kinds {
+			if field.Type.Kind() == kind {
+				return field.Index
+			}
+		}
+		panic(fmt.Sprintf("Resource %s: the field of the column %s is a %s", def.Name, name, field.Type))
+	}
+
+	res.idField = getField("_id", reflect.Int64)
+	res.createdAtField = getField("createdAt", reflect.Ptr)
+	res.updatedAtField = getField("updatedAt", reflect.Ptr)
+	for _, name := range []string{"createdAt", "updatedAt"} {
+		// the timestamps are read as strings in misc.TimestampFormat
+		if fields[name].Type != reflect.TypeOf((*string)(nil)) {
+			panic(fmt.Sprintf("Resource %s: the field of the column %s is a %s", def.Name, name, fields[name].Type))
+		}
+	}
+	for _, column := range def.Columns {
+		res.columnFields = append(res.columnFields, getField(column, reflect.Ptr))
+	}
+
+	res.fields = []listing.Field{{Name: "_id", Column: "id", Type: listing.Number}}
+	res.fieldIndexes = map[string][]int{"_id": res.idField}
+	for i, column := range def.Columns {
+		res.fields = append(res.fields, listing.Field{Name: column, Column: column, Type: res.getColumnType(column)})
+		res.fieldIndexes[column] = res.columnFields[i]
+	}
+	res.fields = append(res.fields,
+		listing.Field{Name: "createdAt", Column: "createdAt", Type: listing.Timestamp},
+		listing.Field{Name: "updatedAt", Column: "updatedAt", Type: listing.Timestamp})
+	res.fieldIndexes["createdAt"] = res.createdAtField
+	res.fieldIndexes["updatedAt"] = res.updatedAtField
+
+	if err := validation.Register((*T)(nil)); err != nil {
+		panic(fmt.Sprintf("Resource %s: %v", def.Name, err))
+	}
+
+	return res
+}
+
+// getJsonFields returns the fields of a struct type by their JSON name
+func getJsonFields(t reflect.Type) map[string]reflect.StructField {
+
+	fields := make(map[string]reflect.StructField)
+	for _, field := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name != "" && name != "-" && field.IsExported() {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+// IdParam is the name of the id parameter of the routes, like dishId
+func (res *Resource[T]) IdParam() string {
+	return res.Name + "Id"
+}
+
+// Title is the name starting with a capital, like Dish
+func (res *Resource[T]) Title() string {
+	return strings.ToUpper(res.Name[:1]) + res.Name[1:]
+}
+
+func (res *Resource[T]) getId(item *T) int64 {
+	return reflect.ValueOf(item).Elem().FieldByIndex(res.idField).Int()
+}
+
+func (res *Resource[T]) setId(item *T, id int64) {
+	reflect.ValueOf(item).Elem().FieldByIndex(res.idField).SetInt(id)
+}
+
+// getColumn returns the field of the i-th column of item, a nil pointer when not set
+func (res *Resource[T]) getColumn(item *T, i int) reflect.Value {
+	return reflect.ValueOf(item).Elem().FieldByIndex(res.columnFields[i])
+}
+
+// getColumnArg returns the value written to the i-th column, nil when not set
+func (res *Resource[T]) getColumnArg(item *T, i int) interface{} {
+
+	field := res.getColumn(item, i)
+	if field.IsNil() {
+		return nil
+	}
+	if res.Columns[i] == FeaturedColumn {
+		featured, _ := strconv.ParseBool(field.Elem().String())
+		return featured
+	}
+	return field.Elem().Interface()
+}
+
+func (res *Resource[T]) getColumnType(column string) listing.FieldType {
+
+	if column == FeaturedColumn {
+		return listing.Bool
+	}
+	for _, numberColumn := range res.NumberColumns {
+		if column == numberColumn {
+			return listing.Number
+		}
+	}
+	return listing.String
+}
+
+// getFieldValue returns the value of a field of item as the listing compares
+// it, nil when not set
+func (res *Resource[T]) getFieldValue(item *T, field listing.Field) interface{} {
+
+	value := reflect.ValueOf(item).Elem().FieldByIndex(res.fieldIndexes[field.Name])
+	if value.Kind() == reflect.Int64 {
+		return float64(value.Int())
+	}
+	if value.IsNil() {
+		return nil
+	}
+
+	str := fmt.Sprint(value.Elem().Interface())
+	switch field.Type {
+	case listing.Number:
+		number, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil
+		}
+		return number
+	case listing.Bool:
+		boolean, _ := strconv.ParseBool(str)
+		return boolean
+	}
+	return str
+}
+
+// getUniqueValue returns the value of the unique column of item, if the resource has one and it is set
+func (res *Resource[T]) getUniqueValue(item *T) (string, bool) {
+
+	for i, column := range res.Columns {
+		if column == res.UniqueColumn {
+			field := res.getColumn(item, i)
+			if field.IsNil() {
+				return "", false
+			}
+			return fmt.Sprint(field.Elem().Interface()), true
+		}
+	}
+	return "", false
+}
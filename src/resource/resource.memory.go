@@ -0,0 +1,233 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/validation"
+)
+
+type memoryStore[T any] struct {
+	mu     sync.RWMutex
+	res    *Resource[T]
+	nextId int64
+	items  map[int64]T
+}
+
+func NewMemoryStore[T any](res *Resource[T]) Store[T] {
+	return &memoryStore[T]{res: res, nextId: 1, items: make(map[int64]T)}
+}
+
+// copyPointer returns a pointer to a copy of the value ptr points to, or ptr if it is nil
+func copyPointer(ptr reflect.Value) reflect.Value {
+
+	if ptr.IsNil() {
+		return ptr
+	}
+	c := reflect.New(ptr.Type().Elem())
+	c.Elem().Set(ptr.Elem())
+	return c
+}
+
+// copyItem copies the id, the columns and the timestamps of item, the other fields are not stored
+func (s *memoryStore[T]) copyItem(item *T) T {
+
+	var c T
+	from := reflect.ValueOf(item).Elem()
+	to := reflect.ValueOf(&c).Elem()
+
+	to.FieldByIndex(s.res.idField).Set(from.FieldByIndex(s.res.idField))
+	for _, index := range append([][]int{s.res.createdAtField, s.res.updatedAtField}, s.res.columnFields...) {
+		to.FieldByIndex(index).Set(copyPointer(from.FieldByIndex(index)))
+	}
+	return c
+}
+
+// setColumn stores the i-th column the way the database returns it: featured as
+// "true" or "false", the others as they are
+func (s *memoryStore[T]) setColumn(item *T, i int, value reflect.Value) {
+
+	if s.res.Columns[i] == FeaturedColumn {
+		isFeatured := false
+		if !value.IsNil() {
+			isFeatured, _ = strconv.ParseBool(value.Elem().String())
+		}
+		value = reflect.ValueOf(misc.GetStringFromBool(isFeatured))
+	}
+	s.res.getColumn(item, i).Set(value)
+}
+
+func (s *memoryStore[T]) setTimestamp(item *T, index []int, timestamp string) {
+	reflect.ValueOf(item).Elem().FieldByIndex(index).Set(reflect.ValueOf(&timestamp))
+}
+
+func (s *memoryStore[T]) Create(ctx context.Context, item T) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// mirror the NOT NULL constraints of the SQL store
+	status := &misc.Status{}
+	if err := validation.Validate(&item, false); err != nil {
+		status.SetStatus(0, 0)
+		return status, err
+	}
+
+	if value, ok := s.res.getUniqueValue(&item); ok {
+		for _, existing := range s.items {
+			if existingValue, _ := s.res.getUniqueValue(&existing); existingValue == value {
+				status.SetStatus(0, 0)
+				return status, misc.NewConflictError(fmt.Sprintf("Duplicate %s %s %s", s.res.Name, s.res.UniqueColumn, value))
+			}
+		}
+	}
+
+	newItem := s.copyItem(&item)
+	s.res.setId(&newItem, s.nextId)
+	for i := range s.res.Columns {
+		// the columns left out get the default of the table, an empty string
+		value := s.res.getColumn(&newItem, i)
+		if value.IsNil() && s.res.Columns[i] != FeaturedColumn {
+			value = reflect.New(value.Type().Elem())
+		}
+		s.setColumn(&newItem, i, value)
+	}
+
+	now := time.Now().UTC().Format(misc.TimestampFormat)
+	s.setTimestamp(&newItem, s.res.createdAtField, now)
+	s.setTimestamp(&newItem, s.res.updatedAtField, now)
+
+	s.items[s.nextId] = newItem
+	status.SetStatus(1, 1)
+	status.ID = s.nextId
+	s.nextId++
+
+	return status, nil
+}
+
+func (s *memoryStore[T]) DeleteAll(ctx context.Context) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &misc.Status{}
+	status.SetStatus(int64(len(s.items)), 1)
+	s.items = make(map[int64]T)
+	return status, nil
+}
+
+// checkPrecondition checks precondition, if any, on the current row, with s.mu locked
+func (s *memoryStore[T]) checkPrecondition(id int64, precondition Precondition[T]) error {
+
+	if precondition == nil {
+		return nil
+	}
+	existing, ok := s.items[id]
+	if !ok {
+		return precondition(nil)
+	}
+	current := s.copyItem(&existing)
+	return precondition(&current)
+}
+
+func (s *memoryStore[T]) Delete(ctx context.Context, id int64, precondition Precondition[T]) (*misc.Status, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &misc.Status{}
+	if err := s.checkPrecondition(id, precondition); err != nil {
+		status.SetStatus(0, 0)
+		return status, err
+	}
+
+	if _, ok := s.items[id]; !ok {
+		status.SetStatus(0, 1)
+		return status, nil
+	}
+
+	delete(s.items, id)
+	status.SetStatus(1, 1)
+	return status, nil
+}
+
+func (s *memoryStore[T]) Update(ctx context.Context, id int64, item T, precondition Precondition[T]) (*T, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// mirror the SQL store: an update without any column is an error
+	input := s.copyItem(&item)
+	hasColumn := false
+	for i := range s.res.Columns {
+		hasColumn = hasColumn || !s.res.getColumn(&input, i).IsNil()
+	}
+	if !hasColumn {
+		return nil, misc.NewBadRequestError("Nothing to update")
+	}
+
+	if err := s.checkPrecondition(id, precondition); err != nil {
+		return nil, err
+	}
+
+	existing, ok := s.items[id]
+	if !ok {
+		return nil, misc.NewNotFoundError(fmt.Sprintf("%s %d not found", s.res.Title(), id))
+	}
+
+	if value, ok := s.res.getUniqueValue(&input); ok {
+		for otherId, other := range s.items {
+			if otherValue, _ := s.res.getUniqueValue(&other); otherId != id && otherValue == value {
+				return nil, misc.NewConflictError(fmt.Sprintf("Duplicate %s %s %s", s.res.Name, s.res.UniqueColumn, value))
+			}
+		}
+	}
+
+	for i := range s.res.Columns {
+		if value := s.res.getColumn(&input, i); !value.IsNil() {
+			s.setColumn(&existing, i, value)
+		}
+	}
+
+	s.setTimestamp(&existing, s.res.updatedAtField, time.Now().UTC().Format(misc.TimestampFormat))
+	s.items[id] = existing
+
+	updated := s.copyItem(&existing)
+	return &updated, nil
+}
+
+func (s *memoryStore[T]) Get(ctx context.Context, id int64) (*T, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return nil, nil
+	}
+
+	found := s.copyItem(&item)
+	return &found, nil
+}
+
+func (s *memoryStore[T]) List(ctx context.Context, params listing.Params) ([]T, listing.PageInfo, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]T, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, s.copyItem(&item))
+	}
+
+	sort.Slice(items, func(i, j int) bool { return s.res.getId(&items[i]) < s.res.getId(&items[j]) })
+	page, info := listing.Apply(items, params, s.res.getFieldValue)
+	return page, info, nil
+}
@@ -0,0 +1,264 @@
+package resource
+
+import (
+	"net/http"
+	"strconv"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/cache"
+	"confusion.com/bwoo/cors"
+	"confusion.com/bwoo/etag"
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/validation"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Route is a GET route under the path of the collection, like /dishes/suggest.
+// httprouter can't have it next to the row route /dishes/:dishId, so the row
+// route hands the requests for it over to Handle, which brings its own
+// middleware. Its metrics and traces are still labelled with its own pattern.
+type Route struct {
+	Name   string
+	Handle httprouter.Handle
+}
+
+type handlers[T any] struct {
+	res    *Resource[T]
+	store  Store[T]
+	routes map[string]httprouter.Handle
+}
+
+// SetupRoutes serves the resource from store: anyone reads it, admins write it.
+// PUT and PATCH update a row partially, POST creates one on the collection.
+func SetupRoutes[T any](router *misc.Router, res *Resource[T], store Store[T], routes ...Route) {
+
+	h := &handlers[T]{res: res, store: store, routes: make(map[string]httprouter.Handle)}
+	for _, route := range routes {
+		h.routes[route.Name] = misc.RouteHandle(res.Path+"/"+route.Name, route.Handle)
+	}
+	itemPath := res.Path + "/:" + res.IdParam()
+
+	// row
+	router.GET(itemPath, h.getItemOrRoute(cors.CorsAllOrigin(cache.Control(res.CacheControl, h.getItem))))
+	router.PUT(itemPath, cors.Cors(auth.VerifyUser(auth.VerifyAdmin(h.patchItem))))
+	router.PATCH(itemPath, cors.Cors(auth.VerifyUser(auth.VerifyAdmin(h.patchItem))))
+	router.POST(itemPath, cors.Cors(auth.VerifyUser(auth.VerifyAdmin(notAllowed))))
+	router.DELETE(itemPath, cors.Cors(auth.VerifyUser(auth.VerifyAdmin(h.deleteItem))))
+
+	// collection
+	router.GET(res.Path, cors.CorsAllOrigin(cache.Control(res.CacheControl, h.getItems)))
+	router.PUT(res.Path, cors.Cors(auth.VerifyUser(auth.VerifyAdmin(notAllowed))))
+	router.POST(res.Path, cors.Cors(auth.VerifyUser(auth.VerifyAdmin(h.postItems))))
+	router.DELETE(res.Path, cors.Cors(auth.VerifyUser(auth.VerifyAdmin(h.deleteItems))))
+}
+
+/****************************
+* Helper functions
+****************************/
+func notAllowed(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	misc.WriteError(w, r, misc.NewMethodNotAllowedError(r))
+}
+
+func writeJson(w http.ResponseWriter, r *http.Request, obj interface{}) {
+
+	objJson, err := misc.GetJsonFromJsonObjs(obj)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(objJson)
+}
+
+// writeJsonWithETag writes obj with its ETag, or 304 Not Modified when If-None-Match has
+// it. headers are the values of the headers which are part of the representation.
+func writeJsonWithETag(w http.ResponseWriter, r *http.Request, obj interface{}, headers ...string) {
+
+	objJson, err := misc.GetJsonFromJsonObjs(obj)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	parts := [][]byte{objJson}
+	for _, header := range headers {
+		parts = append(parts, []byte(header))
+	}
+	etag.WriteJson(w, r, objJson, etag.Compute(parts...))
+}
+
+// getETag returns the ETag of item as GET returns it, "" when it is nil
+func getETag[T any](item *T) (string, error) {
+
+	if item == nil {
+		return "", nil
+	}
+	itemJson, err := misc.GetJsonFromJsonObjs(item)
+	if err != nil {
+		return "", err
+	}
+	return etag.Compute(itemJson), nil
+}
+
+func (h *handlers[T]) getId(ps httprouter.Params) (int64, error) {
+
+	idStr := ps.ByName(h.res.IdParam())
+	id, err := misc.GetInt64FromString(idStr)
+	if err != nil {
+		return 0, misc.NewBadRequestError("Invalid " + h.res.Name + " id " + idStr)
+	}
+	return id, nil
+}
+
+/****************************
+* Row operations
+****************************/
+func (h *handlers[T]) getItemOrRoute(getItem httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+		if route, ok := h.routes[ps.ByName(h.res.IdParam())]; ok {
+			route(w, r, ps)
+			return
+		}
+		getItem(w, r, ps)
+	}
+}
+
+func (h *handlers[T]) getItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	id, err := h.getId(ps)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	item, err := h.store.Get(r.Context(), id)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	if item == nil {
+		misc.WriteError(w, r, misc.NewNotFoundError(h.res.Title()+" "+strconv.FormatInt(id, 10)+" not found"))
+		return
+	}
+
+	writeJsonWithETag(w, r, item)
+}
+
+// ifMatch returns the precondition of the If-Match header of the request, nil
+// when it has none. It fails with precondition_failed unless the row is the
+// one the header names.
+func (h *handlers[T]) ifMatch(r *http.Request) Precondition[T] {
+
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+
+	return func(current *T) error {
+
+		currentETag, err := getETag(current)
+		if err != nil {
+			return err
+		}
+		return etag.CheckIfMatch(r, currentETag)
+	}
+}
+
+func (h *handlers[T]) patchItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	id, err := h.getId(ps)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	// only the fields to change
+	var item T
+	if err := validation.DecodeAndValidate(w, r, &item, true); err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	updatedItem, err := h.store.Update(r.Context(), id, item, h.ifMatch(r))
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	// the ETag to send in If-Match with the next change
+	if updatedETag, err := getETag(updatedItem); err == nil {
+		w.Header().Set("ETag", updatedETag)
+	}
+	writeJson(w, r, updatedItem)
+}
+
+func (h *handlers[T]) deleteItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	id, err := h.getId(ps)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	status, err := h.store.Delete(r.Context(), id, h.ifMatch(r))
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	writeJson(w, r, status)
+}
+
+/****************************
+* Collection operations
+****************************/
+func (h *handlers[T]) getItems(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	params, err := listing.Parse(r.URL.Query(), h.res.fields)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	items, info, err := h.store.List(r.Context(), params)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	listing.WriteHeaders(w, r, params, items, info, h.res.getFieldValue)
+	writeJsonWithETag(w, r, items, w.Header().Get("X-Total-Count"), w.Header().Get("Link"))
+}
+
+func (h *handlers[T]) postItems(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	var item T
+	if err := validation.DecodeAndValidate(w, r, &item, false); err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	status, err := h.store.Create(r.Context(), item)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	writeJson(w, r, status)
+}
+
+func (h *handlers[T]) deleteItems(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	status, err := h.store.DeleteAll(r.Context())
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	writeJson(w, r, status)
+}
@@ -0,0 +1,499 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/auth"
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/misc"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type testItem struct {
+	ID        int64   `json:"_id"`
+	Name      *string `json:"name" validate:"required,max=50"`
+	Price     *string `json:"price" validate:"number"`
+	Featured  *string `json:"featured" validate:"oneof=true false"`
+	CreatedAt *string `json:"createdAt"`
+	UpdatedAt *string `json:"updatedAt"`
+}
+
+var testResource = New[testItem](Definition{
+	Name:          "item",
+	Path:          "/items",
+	Table:         "item",
+	Columns:       []string{"name", "price", "featured"},
+	UniqueColumn:  "name",
+	NumberColumns: []string{"price"},
+})
+
+func newTestItem(name, price string) testItem {
+	return testItem{Name: &name, Price: &price}
+}
+
+func newFeaturedTestItem(name, price, featured string) testItem {
+
+	item := newTestItem(name, price)
+	item.Featured = &featured
+	return item
+}
+
+var testConfig = config.Config{
+	JwtKey:           "test",
+	JwtExpiration:    time.Hour,
+	PasswordHashCost: bcrypt.MinCost,
+}
+
+// newTestRouter serves the items of a memory store holding items, and the
+// login of admin, an admin, and jane, whose passwords are "secret"
+func newTestRouter(t *testing.T, items ...testItem) (*misc.Router, Store[testItem]) {
+
+	t.Helper()
+
+	store := NewMemoryStore(testResource)
+	for _, item := range items {
+		if _, err := store.Create(context.Background(), item); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	users := auth.NewMemoryStore()
+	admin := auth.UserInfo{Firstname: "Ada", Lastname: "Admin"}
+	admin.Username = "admin"
+	jane := auth.UserInfo{Firstname: "Jane", Lastname: "Doe"}
+	jane.Username = "jane"
+	if _, err := auth.CreateMemoryAdmin(context.Background(), users, admin, hash); err != nil {
+		t.Fatalf("CreateMemoryAdmin: %v", err)
+	}
+	if _, err := users.CreateUser(context.Background(), jane, hash); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	router := misc.NewRouter()
+	SetupRoutes(router, testResource, store)
+	auth.SetupRoutes(router, testConfig, users, auth.NewMemoryFailedLoginStore(), nil)
+	return router, store
+}
+
+func serveWithHeaders(router *misc.Router, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func login(t *testing.T, router *misc.Router, username string) string {
+
+	t.Helper()
+
+	w := serveWithHeaders(router, http.MethodPost, "/users/login", `{"username":"`+username+`","password":"secret"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /users/login as %s: got status %d, want %d", username, w.Code, http.StatusOK)
+	}
+
+	var result struct{ Token string }
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("POST /users/login: %v", err)
+	}
+	return result.Token
+}
+
+func serve(router *misc.Router, method, path string) *httptest.ResponseRecorder {
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestGetItem(t *testing.T) {
+
+	// each router keeps its own store
+	soup, _ := newTestRouter(t, newTestItem("soup", "4"))
+	salad, _ := newTestRouter(t, newTestItem("salad", "6"))
+
+	for _, test := range []struct {
+		router *misc.Router
+		name   string
+	}{{soup, "soup"}, {salad, "salad"}} {
+
+		w := serve(test.router, http.MethodGet, "/items/1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /items/1: got status %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var item testItem
+		if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+			t.Fatalf("GET /items/1: %v", err)
+		}
+		if item.Name == nil || *item.Name != test.name || item.Featured == nil || *item.Featured != "false" {
+			t.Errorf("GET /items/1: got %+v, want %s, not featured", item, test.name)
+		}
+	}
+}
+
+func TestGetItems(t *testing.T) {
+
+	router, store := newTestRouter(t, newTestItem("soup", "4"), newTestItem("salad", "6"))
+	if _, err := store.Delete(context.Background(), 1, nil); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	w := serve(router, http.MethodGet, "/items")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /items: got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var items []testItem
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatalf("GET /items: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != 2 || *items[0].Name != "salad" {
+		t.Errorf("GET /items: got %+v, want salad only", items)
+	}
+}
+
+func TestItemChanges(t *testing.T) {
+
+	router, _ := newTestRouter(t, newTestItem("soup", "4"))
+	admin := map[string]string{"Authorization": "Bearer " + login(t, router, "admin")}
+	jane := map[string]string{"Authorization": "Bearer " + login(t, router, "jane")}
+
+	for _, test := range []struct {
+		method, path, body string
+		headers            map[string]string
+		status             int
+		code               string
+	}{
+		{http.MethodPost, "/items", `{"name":"salad","price":"6"}`, nil, http.StatusUnauthorized, misc.ErrorCodeUnauthorized},
+		{http.MethodPost, "/items", `{"name":"salad","price":"6"}`, jane, http.StatusForbidden, misc.ErrorCodeForbidden},
+		{http.MethodPost, "/items", `{"name":"salad","price":"6"}`, admin, http.StatusOK, ""},
+		{http.MethodPost, "/items", `{"name":"salad","price":"7"}`, admin, http.StatusConflict, misc.ErrorCodeConflict},
+		{http.MethodPost, "/items", `{"price":"6"}`, admin, http.StatusBadRequest, misc.ErrorCodeValidation},
+		{http.MethodPost, "/items", `{"name":"stew","price":"cheap"}`, admin, http.StatusBadRequest, misc.ErrorCodeValidation},
+		{http.MethodPost, "/items", `{"name":"stew","featured":"maybe"}`, admin, http.StatusBadRequest, misc.ErrorCodeValidation},
+		{http.MethodPost, "/items/1", `{"name":"stew"}`, admin, http.StatusMethodNotAllowed, misc.ErrorCodeMethodNotAllowed},
+		{http.MethodPut, "/items", `{"name":"stew"}`, admin, http.StatusMethodNotAllowed, misc.ErrorCodeMethodNotAllowed},
+		// PUT and PATCH change the fields they are given
+		{http.MethodPatch, "/items/1", `{"price":"5"}`, admin, http.StatusOK, ""},
+		{http.MethodPut, "/items/1", `{"featured":"true"}`, admin, http.StatusOK, ""},
+		{http.MethodPatch, "/items/1", `{}`, admin, http.StatusBadRequest, misc.ErrorCodeBadRequest},
+		{http.MethodPatch, "/items/1", `{"name":"salad"}`, admin, http.StatusConflict, misc.ErrorCodeConflict},
+		{http.MethodPatch, "/items/9", `{"price":"5"}`, admin, http.StatusNotFound, misc.ErrorCodeNotFound},
+		{http.MethodPatch, "/items/x", `{"price":"5"}`, admin, http.StatusBadRequest, misc.ErrorCodeBadRequest},
+		{http.MethodPatch, "/items/1", `{"price":"5"}`, jane, http.StatusForbidden, misc.ErrorCodeForbidden},
+		{http.MethodDelete, "/items/2", "", jane, http.StatusForbidden, misc.ErrorCodeForbidden},
+		{http.MethodDelete, "/items/2", "", admin, http.StatusOK, ""},
+	} {
+
+		w := serveWithHeaders(router, test.method, test.path, test.body, test.headers)
+		if w.Code != test.status {
+			t.Errorf("%s %s %s: got status %d, want %d", test.method, test.path, test.body, w.Code, test.status)
+			continue
+		}
+		if test.code == "" {
+			continue
+		}
+
+		var response struct{ Error misc.Error }
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s: %v", test.method, test.path, err)
+		}
+		if response.Error.Code != test.code {
+			t.Errorf("%s %s %s: got code %q, want %q", test.method, test.path, test.body, response.Error.Code, test.code)
+		}
+	}
+
+	w := serve(router, http.MethodGet, "/items")
+	var items []testItem
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatalf("GET /items: %v", err)
+	}
+	if len(items) != 1 || *items[0].Name != "soup" || *items[0].Price != "5" || *items[0].Featured != "true" {
+		t.Errorf("GET /items: got %+v, want soup at 5, featured", items)
+	}
+
+	if w := serveWithHeaders(router, http.MethodDelete, "/items", "", admin); w.Code != http.StatusOK {
+		t.Errorf("DELETE /items: got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := serve(router, http.MethodGet, "/items/1"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /items/1 after DELETE /items: got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestSetupRoutesWithRoute(t *testing.T) {
+
+	store := NewMemoryStore(testResource)
+	if _, err := store.Create(context.Background(), newTestItem("soup", "4")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	router := misc.NewRouter()
+	SetupRoutes(router, testResource, store, Route{Name: "suggest", Handle: func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w.Write([]byte("suggested"))
+	}})
+
+	for _, test := range []struct {
+		path    string
+		status  int
+		pattern string
+		body    string
+	}{
+		{"/items/suggest", http.StatusOK, "/items/suggest", "suggested"},
+		{"/items/1", http.StatusOK, "/items/:itemId", ""},
+		{"/items/other", http.StatusBadRequest, "/items/:itemId", ""},
+	} {
+
+		r, getRoute := misc.GetRoutePattern(httptest.NewRequest(http.MethodGet, test.path, nil))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("GET %s: got status %d, want %d", test.path, w.Code, test.status)
+		}
+		if route := getRoute(); route != test.pattern {
+			t.Errorf("GET %s: got route %s, want %s", test.path, route, test.pattern)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("GET %s: got %q, want %q", test.path, w.Body.String(), test.body)
+		}
+	}
+}
+
+// the items paged through by testPaging
+// the fractional prices tie, which a FLOAT column of MySQL would not keep equal
+var testPagingItems = []testItem{
+	newFeaturedTestItem("a", "3.99", "true"), newFeaturedTestItem("b", "1.49", "false"),
+	newFeaturedTestItem("c", "3.99", "false"), newFeaturedTestItem("d", "2.5", "true"),
+	newFeaturedTestItem("e", "3.99", "false"), newFeaturedTestItem("f", "1.49", "false"),
+	newFeaturedTestItem("g", "4.99", "true"),
+}
+
+var linkPattern = regexp.MustCompile(`<([^>]+)>; rel="(\w+)"`)
+
+// getPage returns the names of the items of the page at path and its links by rel
+func getPage(t *testing.T, router *misc.Router, path string) ([]string, map[string]string) {
+
+	t.Helper()
+
+	w := serve(router, http.MethodGet, path)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: got status %d, want %d: %s", path, w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var items []testItem
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, *item.Name)
+	}
+
+	links := make(map[string]string)
+	for _, match := range linkPattern.FindAllStringSubmatch(w.Header().Get("Link"), -1) {
+		links[match[2]] = match[1]
+	}
+	return names, links
+}
+
+// testPaging pages through the testPagingItems served by router, forwards
+// along the next links and back along the prev links
+func testPaging(t *testing.T, router *misc.Router) {
+
+	for _, test := range []struct {
+		query string
+		want  []string
+	}{
+		{"sort=-price&limit=2", []string{"g", "a", "c", "e", "d", "b", "f"}},
+		{"sort=price,-name&limit=3", []string{"f", "b", "d", "e", "c", "a", "g"}},
+		{"sort=createdAt&limit=2", []string{"a", "b", "c", "d", "e", "f", "g"}},
+		{"price_gte=2&sort=-price&limit=2&_=123", []string{"g", "a", "c", "e", "d"}},
+		{"limit=7", []string{"a", "b", "c", "d", "e", "f", "g"}},
+		{"sort=-price&limit=2&offset=0", []string{"g", "a", "c", "e", "d", "b", "f"}},
+	} {
+
+		var got []string
+		var pages [][]string
+		var links map[string]string
+		for path := "/items?" + test.query; path != ""; path = links["next"] {
+			var names []string
+			names, links = getPage(t, router, path)
+			got = append(got, names...)
+			pages = append(pages, names)
+			if len(pages) > len(test.want) {
+				t.Fatalf("%s: got more pages than items", test.query)
+			}
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.query, got, test.want)
+			continue
+		}
+
+		// the prev links lead back through the same pages
+		i := len(pages) - 2
+		for path := links["prev"]; path != ""; path = links["prev"] {
+			var names []string
+			names, links = getPage(t, router, path)
+			if i < 0 || !slices.Equal(names, pages[i]) {
+				t.Fatalf("%s: got %v before page %d", test.query, names, i+1)
+			}
+			i--
+		}
+		if i != -1 {
+			t.Errorf("%s: got no prev link on page %d", test.query, i+1)
+		}
+	}
+}
+
+// testFiltering filters the testPagingItems served by router
+func testFiltering(t *testing.T, router *misc.Router) {
+
+	for _, test := range []struct {
+		query string
+		want  []string
+	}{
+		{"price=3.99", []string{"a", "c", "e"}},
+		{"price=1.49&sort=-name", []string{"f", "b"}},
+		{"price_lt=2.5", []string{"b", "f"}},
+		{"price_gte=3.99&price_ne=4.99&limit=2", []string{"a", "c"}},
+		// false is a value like any other, not the absence of the filter
+		{"featured=false", []string{"b", "c", "e", "f"}},
+		{"featured=true&sort=-price", []string{"g", "a", "d"}},
+		{"featured=false&price_ne=3.99", []string{"b", "f"}},
+	} {
+
+		if got, _ := getPage(t, router, "/items?"+test.query); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestGetItemsPages(t *testing.T) {
+
+	router, _ := newTestRouter(t, testPagingItems...)
+	testPaging(t, router)
+	testFiltering(t, router)
+}
+
+func TestGetItemsQueryErrors(t *testing.T) {
+
+	router, _ := newTestRouter(t, testPagingItems...)
+	for _, query := range []string{
+		"limit=0", "limit=1001", "offset=-1", "sort=color", "sort=", "price=cheap", "featured=yes", "createdAt=2026-10-16",
+		"cursor=garbage", "cursor=WzFd", "sort=-price&cursor=eyJ2IjpbMV19", "cursor=eyJ2IjpbMV19&offset=2",
+	} {
+		if w := serve(router, http.MethodGet, "/items?"+query); w.Code != http.StatusBadRequest {
+			t.Errorf("GET /items?%s: got status %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestItemETags(t *testing.T) {
+
+	router, _ := newTestRouter(t, newTestItem("soup", "4"))
+	bearer := "Bearer " + login(t, router, "admin")
+	path := "/items/1"
+
+	// expect serves the request and checks its status, returning its ETag
+	expect := func(method, body string, headers map[string]string, status int) string {
+
+		t.Helper()
+
+		w := serveWithHeaders(router, method, path, body, headers)
+		if w.Code != status {
+			t.Fatalf("%s %s with %v: got status %d, want %d", method, path, headers, w.Code, status)
+		}
+		if status == http.StatusPreconditionFailed {
+			var response struct{ Error misc.Error }
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error.Code != misc.ErrorCodePreconditionFailed {
+				t.Errorf("%s %s with %v: got %s, want a precondition_failed error", method, path, headers, w.Body.String())
+			}
+		}
+		return w.Header().Get("ETag")
+	}
+
+	first := expect(http.MethodGet, "", nil, http.StatusOK)
+	if first == "" {
+		t.Fatalf("GET %s: got no ETag", path)
+	}
+	if got := expect(http.MethodGet, "", map[string]string{"If-None-Match": first}, http.StatusNotModified); got != first {
+		t.Errorf("GET %s, not modified: got ETag %q, want %q", path, got, first)
+	}
+
+	// a change made from a stale copy fails and changes nothing
+	expect(http.MethodPatch, `{"price":"5"}`, map[string]string{"Authorization": bearer, "If-Match": `"stale"`},
+		http.StatusPreconditionFailed)
+	expect(http.MethodPatch, `{"price":"5"}`, map[string]string{"Authorization": bearer, "If-Match": "W/" + first},
+		http.StatusPreconditionFailed)
+	if got := expect(http.MethodGet, "", nil, http.StatusOK); got != first {
+		t.Errorf("GET %s after the failed changes: got ETag %q, want %q", path, got, first)
+	}
+
+	second := expect(http.MethodPatch, `{"price":"5"}`, map[string]string{"Authorization": bearer, "If-Match": first},
+		http.StatusOK)
+	if second == "" || second == first {
+		t.Errorf("PATCH %s: got ETag %q, want a new one", path, second)
+	}
+	if got := expect(http.MethodGet, "", map[string]string{"If-None-Match": first}, http.StatusOK); got != second {
+		t.Errorf("GET %s after PATCH: got ETag %q, want %q", path, got, second)
+	}
+
+	// changes without If-Match are let through
+	third := expect(http.MethodPut, `{"featured":"true"}`, map[string]string{"Authorization": bearer}, http.StatusOK)
+
+	expect(http.MethodDelete, "", map[string]string{"Authorization": bearer, "If-Match": second}, http.StatusPreconditionFailed)
+	expect(http.MethodDelete, "", map[string]string{"Authorization": bearer, "If-Match": third}, http.StatusOK)
+	expect(http.MethodPatch, `{"price":"6"}`, map[string]string{"Authorization": bearer, "If-Match": third},
+		http.StatusPreconditionFailed)
+}
+
+func TestItemsETag(t *testing.T) {
+
+	router, store := newTestRouter(t, newTestItem("soup", "4"), newTestItem("salad", "6"))
+
+	w := serve(router, http.MethodGet, "/items")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET /items: got status %d and ETag %q, want %d and an ETag", w.Code, etag, http.StatusOK)
+	}
+
+	for _, test := range []struct {
+		path   string
+		status int
+	}{
+		{"/items", http.StatusNotModified},
+		// another page is another representation
+		{"/items?limit=1", http.StatusOK},
+		{"/items?sort=name", http.StatusOK},
+	} {
+
+		w := serveWithHeaders(router, http.MethodGet, test.path, "", map[string]string{"If-None-Match": etag})
+		if w.Code != test.status {
+			t.Errorf("GET %s with the ETag of /items: got status %d, want %d", test.path, w.Code, test.status)
+		}
+	}
+
+	if _, err := store.Create(context.Background(), newTestItem("stew", "9")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if w := serveWithHeaders(router, http.MethodGet, "/items", "", map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+		t.Errorf("GET /items after a new item: got status %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,29 @@
+package resource
+
+import (
+	"context"
+
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/misc"
+)
+
+// Store is the persistence layer used by the resource handlers.
+// NewDbStore returns the SQL implementation, NewMemoryStore an in-memory
+// one for tests and demos.
+type Store[T any] interface {
+	// Get returns the row, or nil if not found
+	Get(ctx context.Context, id int64) (*T, error)
+	// List returns the page of rows selected by params and where it lies among the rows matching its filters
+	List(ctx context.Context, params listing.Params) ([]T, listing.PageInfo, error)
+	Create(ctx context.Context, item T) (*misc.Status, error)
+	// Update sets the columns which are not nil in item and returns the updated row.
+	// Update and Delete only change the row if precondition, when not nil, accepts it.
+	Update(ctx context.Context, id int64, item T, precondition Precondition[T]) (*T, error)
+	Delete(ctx context.Context, id int64, precondition Precondition[T]) (*misc.Status, error)
+	DeleteAll(ctx context.Context) (*misc.Status, error)
+}
+
+// Precondition checks the current row, nil when it doesn't exist, right before
+// it is changed, while nothing else can change it. The change is not made when
+// it returns an error, which the change returns.
+type Precondition[T any] func(current *T) error
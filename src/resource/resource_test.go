@@ -0,0 +1,34 @@
+package resource
+
+import "testing"
+
+func TestNewPanics(t *testing.T) {
+
+	type wrongTag struct {
+		ID        int64   `json:"_id"`
+		Name      *string `json:"name" validate:"required,max=fifty"`
+		CreatedAt *string `json:"createdAt"`
+		UpdatedAt *string `json:"updatedAt"`
+	}
+	type wrongTimestamp struct {
+		ID        int64   `json:"_id"`
+		Name      *string `json:"name"`
+		CreatedAt *int64  `json:"createdAt"`
+		UpdatedAt *string `json:"updatedAt"`
+	}
+
+	for name, newResource := range map[string]func(){
+		"wrong validate tag": func() { New[wrongTag](Definition{Name: "item", Columns: []string{"name"}}) },
+		"wrong timestamp":    func() { New[wrongTimestamp](Definition{Name: "item", Columns: []string{"name"}}) },
+		"missing column":     func() { New[testItem](Definition{Name: "item", Columns: []string{"color"}}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: New: got no panic", name)
+				}
+			}()
+			newResource()
+		}()
+	}
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/database"
+)
+
+type dbStore struct {
+	db *database.Conn
+}
+
+// NewDbStore searches the full-text indexes of the 0005_search migration, of
+// MySQL or PostgreSQL
+func NewDbStore(db *database.Conn) Store {
+	return &dbStore{db: db}
+}
+
+// getQuery returns the query selecting the id, the name, the searched columns
+// and the score of the rows matching the words, and its arguments
+func (s *dbStore) getQuery(kind Kind, words string, limit int) (string, []interface{}, error) {
+
+	columns := strings.Join(kind.Columns, ", ")
+	switch s.db.Dialect.Name() {
+	case config.MySQLDriver:
+		// the natural language mode matches any of the words, ranking the rows with more of them first
+		match := `MATCH(` + columns + `) AGAINST (? IN NATURAL LANGUAGE MODE)`
+		return `SELECT id, name, ` + columns + `, ` + match + ` AS score FROM ` + kind.Table +
+				` WHERE ` + match + ` ORDER BY score DESC, id LIMIT ?`,
+			[]interface{}{words, words, limit}, nil
+
+	case config.PostgresDriver:
+		// the expression of the index, plainto_tsquery ANDs the words, turned into an OR
+		document := `to_tsvector('english', ` + strings.Join(kind.Columns, ` || ' ' || `) + `)`
+		return `SELECT id, name, ` + columns + `, ts_rank(` + document + `, q) AS score
+					FROM ` + kind.Table + `, CAST(replace(CAST(plainto_tsquery('english', ?) AS TEXT), '&', '|') AS tsquery) q
+					WHERE ` + document + ` @@ q ORDER BY score DESC, id LIMIT ?`,
+			[]interface{}{words, limit}, nil
+	}
+	return "", nil, fmt.Errorf("No full-text index for the %s driver", s.db.Dialect.Name())
+}
+
+func (s *dbStore) Search(ctx context.Context, kind Kind, query string, limit int) ([]Match, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	sqlQuery, args, err := s.getQuery(kind, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]Match, 0)
+	for rows.Next() {
+
+		var match Match
+		fields := make([]string, len(kind.Columns))
+		dest := []interface{}{&match.ID, &match.Name}
+		for i := range fields {
+			dest = append(dest, &fields[i])
+		}
+		dest = append(dest, &match.Score)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		match.Fields = make(map[string]string)
+		for i, column := range kind.Columns {
+			match.Fields[column] = fields[i]
+		}
+		matches = append(matches, match)
+	}
+
+	return matches, rows.Err()
+}
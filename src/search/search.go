@@ -0,0 +1,39 @@
+package search
+
+// Kind is a type of document searched by GET /search, with the columns of its
+// table in the full-text index
+type Kind struct {
+	// Name groups the hits of the kind in the results, like "dishes"
+	Name    string
+	Table   string
+	Columns []string
+}
+
+var Kinds = []Kind{
+	{Name: "dishes", Table: "dish", Columns: []string{"name", "description"}},
+	{Name: "promotions", Table: "promotion", Columns: []string{"description"}},
+	{Name: "leaders", Table: "leader", Columns: []string{"description"}},
+}
+
+// Match is a row matching a query, with the text of its searched columns
+type Match struct {
+	ID     int64
+	Name   string
+	Fields map[string]string
+	Score  float64
+}
+
+// Hit is a match as the client gets it: the searched fields which match are
+// in Highlights, with the matching words in <mark> tags and HTML escaped
+type Hit struct {
+	ID         int64             `json:"_id"`
+	Name       string            `json:"name"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type Results struct {
+	Query string `json:"query"`
+	// Results holds the hits of each kind by its name, best first
+	Results map[string][]Hit `json:"results"`
+}
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/leaders"
+	"confusion.com/bwoo/listing"
+	"confusion.com/bwoo/promotions"
+	"confusion.com/bwoo/resource"
+)
+
+// BM25 parameters: how fast the score of a word saturates with its count,
+// and how much a long document is penalized
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// nameWeight counts the words of a name as many times, as a name matching
+// the query tells more than a description mentioning it
+const nameWeight = 2
+
+type tokenizerStore struct {
+	// sources lists the rows of each kind by its name
+	sources map[string]func(ctx context.Context) ([]Match, error)
+}
+
+// NewTokenizerStore searches the rows of the stores with Tokenize, ranking
+// them with BM25. It reads all the rows of a kind on every search, which
+// suits a menu, not a catalog.
+func NewTokenizerStore(dishStore dishes.DishStore, promotionStore promotions.PromotionStore,
+	leaderStore leaders.LeaderStore) Store {
+
+	return &tokenizerStore{sources: map[string]func(ctx context.Context) ([]Match, error){
+		"dishes": listMatches(dishStore, func(dish *dishes.Dish) Match {
+			return Match{ID: dish.ID, Name: getString(dish.Name),
+				Fields: map[string]string{"name": getString(dish.Name), "description": getString(dish.Description)}}
+		}),
+		"promotions": listMatches(promotionStore, func(promotion *promotions.Promotion) Match {
+			return Match{ID: promotion.ID, Name: getString(promotion.Name),
+				Fields: map[string]string{"description": getString(promotion.Description)}}
+		}),
+		"leaders": listMatches(leaderStore, func(leader *leaders.Leader) Match {
+			return Match{ID: leader.ID, Name: getString(leader.Name),
+				Fields: map[string]string{"description": getString(leader.Description)}}
+		}),
+	}}
+}
+
+// listMatches returns the source listing all the rows of store as matches
+func listMatches[T any](store resource.Store[T], toMatch func(item *T) Match) func(ctx context.Context) ([]Match, error) {
+	return func(ctx context.Context) ([]Match, error) {
+
+		items, _, err := store.List(ctx, listing.Params{})
+		if err != nil {
+			return nil, err
+		}
+
+		matches := make([]Match, 0, len(items))
+		for i := range items {
+			matches = append(matches, toMatch(&items[i]))
+		}
+		return matches, nil
+	}
+}
+
+func getString(s *string) string {
+
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (s *tokenizerStore) Search(ctx context.Context, kind Kind, query string, limit int) ([]Match, error) {
+
+	source, ok := s.sources[kind.Name]
+	if !ok {
+		return nil, fmt.Errorf("No rows to search for %s", kind.Name)
+	}
+
+	rows, err := source(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := getTerms(query)
+	if len(terms) == 0 || len(rows) == 0 {
+		return []Match{}, nil
+	}
+
+	// the count of each term in each row, and the number of rows with each term
+	counts := make([]map[string]int, len(rows))
+	lengths := make([]int, len(rows))
+	rowsWithTerm := make(map[string]int)
+	totalLength := 0
+	for i, row := range rows {
+		counts[i] = make(map[string]int)
+		for _, column := range kind.Columns {
+			weight := 1
+			if column == "name" {
+				weight = nameWeight
+			}
+			for _, token := range Tokenize(row.Fields[column]) {
+				counts[i][token.Term] += weight
+				lengths[i] += weight
+			}
+		}
+		for _, term := range terms {
+			if counts[i][term] > 0 {
+				rowsWithTerm[term]++
+			}
+		}
+		totalLength += lengths[i]
+	}
+	averageLength := math.Max(float64(totalLength)/float64(len(rows)), 1)
+
+	matches := make([]Match, 0)
+	for i, row := range rows {
+		score := 0.0
+		for _, term := range terms {
+			count := float64(counts[i][term])
+			if count == 0 {
+				continue
+			}
+			n := float64(rowsWithTerm[term])
+			idf := math.Log(1 + (float64(len(rows))-n+0.5)/(n+0.5))
+			score += idf * count * (k1 + 1) / (count + k1*(1-b+b*float64(lengths[i])/averageLength))
+		}
+		if score > 0 {
+			row.Score = score
+			matches = append(matches, row)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
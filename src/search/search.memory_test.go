@@ -0,0 +1,98 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/leaders"
+	"confusion.com/bwoo/promotions"
+)
+
+// newTestStore searches memory stores holding a few dishes, a promotion and a leader
+func newTestStore(t *testing.T) Store {
+
+	t.Helper()
+	ctx := context.Background()
+
+	dishStore := dishes.NewMemoryStore()
+	for _, dish := range [][2]string{
+		{"Tomato Soup", "Slow cooked soup of ripe tomato"},
+		{"Garden Salad", "Fresh greens with a tomato dressing"},
+		{"Bread", "Baked every morning, lovely with a soup"},
+		{"Cheese Cake", "A New York style cheese cake"},
+	} {
+		name, description := dish[0], dish[1]
+		image, category, price := "images/dish.png", "mains", "4.99"
+		if _, err := dishStore.Create(ctx, dishes.Dish{Name: &name, Description: &description, Image: &image,
+			Category: &category, Price: &price}); err != nil {
+			t.Fatalf("Create %s: %v", name, err)
+		}
+	}
+
+	promotionStore := promotions.NewMemoryStore()
+	name, description, image, price := "Weekend Grand Buffet", "Soup, salad and cake for the whole family", "images/buffet.png", "19.99"
+	if _, err := promotionStore.Create(ctx, promotions.Promotion{Name: &name, Description: &description, Image: &image,
+		Price: &price}); err != nil {
+		t.Fatalf("Create %s: %v", name, err)
+	}
+
+	leaderStore := leaders.NewMemoryStore()
+	name, description, designation, abbr := "Peter Pan", "Our CEO, who bakes the bread", "Chief Epicurious Officer", "CEO"
+	if _, err := leaderStore.Create(ctx, leaders.Leader{Name: &name, Description: &description, Image: &image,
+		Designation: &designation, Abbr: &abbr}); err != nil {
+		t.Fatalf("Create %s: %v", name, err)
+	}
+
+	return NewTokenizerStore(dishStore, promotionStore, leaderStore)
+}
+
+func TestTokenizerStoreSearch(t *testing.T) {
+
+	store := newTestStore(t)
+
+	for _, test := range []struct {
+		kind  Kind
+		query string
+		limit int
+		want  []string
+	}{
+		// a name matching weighs more than a description mentioning the word
+		{Kinds[0], "tomato", 10, []string{"Tomato Soup", "Garden Salad"}},
+		{Kinds[0], "soups", 10, []string{"Tomato Soup", "Bread"}},
+		{Kinds[0], "tomato", 1, []string{"Tomato Soup"}},
+		// the more words of the query a row has, the better
+		{Kinds[0], "soup bread", 10, []string{"Bread", "Tomato Soup"}},
+		{Kinds[0], "CHEESE", 10, []string{"Cheese Cake"}},
+		{Kinds[0], "pizza", 10, []string{}},
+		{Kinds[0], "the and of", 10, []string{}},
+		// only the description of the promotions and the leaders is searched
+		{Kinds[1], "soup", 10, []string{"Weekend Grand Buffet"}},
+		{Kinds[1], "buffet", 10, []string{}},
+		{Kinds[2], "bread", 10, []string{"Peter Pan"}},
+	} {
+
+		matches, err := store.Search(context.Background(), test.kind, test.query, test.limit)
+		if err != nil {
+			t.Errorf("%s %q: %v", test.kind.Name, test.query, err)
+			continue
+		}
+
+		names := make([]string, 0, len(matches))
+		for i, match := range matches {
+			names = append(names, match.Name)
+			if match.Score <= 0 || (i > 0 && match.Score > matches[i-1].Score) {
+				t.Errorf("%s %q: got score %v after %v, want positive scores, best first",
+					test.kind.Name, test.query, match.Score, matches[max(i-1, 0)].Score)
+			}
+		}
+		if !slices.Equal(names, test.want) {
+			t.Errorf("%s %q: got %v, want %v", test.kind.Name, test.query, names, test.want)
+		}
+	}
+
+	if _, err := store.Search(context.Background(), Kind{Name: "users"}, "jane", 10); err == nil {
+		t.Errorf("users: got no error, want one")
+	}
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"confusion.com/bwoo/cors"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/ratelimit"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// the hits of each kind
+	defaultLimit = 10
+	maxLimit     = 50
+
+	maxQueryLength = 100
+)
+
+// handlers search store
+type handlers struct {
+	store Store
+}
+
+func SetupRoutes(router *misc.Router, store Store, limiters *ratelimit.Limiters) {
+
+	h := &handlers{store: store}
+
+	router.GET("/search", cors.CorsAllOrigin(limiters.RateLimit("search", nil, h.getSearch)))
+}
+
+// getQuery reads the words searched and the number of hits of each kind of the query string
+func getQuery(r *http.Request) (string, int, error) {
+
+	details := make([]misc.FieldError, 0)
+	values := r.URL.Query()
+
+	query := values.Get("q")
+	if query == "" {
+		details = append(details, misc.FieldError{Field: "q", Message: "required"})
+	} else if len(query) > maxQueryLength {
+		details = append(details, misc.FieldError{Field: "q", Message: fmt.Sprintf("at most %d characters", maxQueryLength)})
+	}
+
+	limit := defaultLimit
+	if limitStr := values.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxLimit {
+			details = append(details, misc.FieldError{Field: "limit", Message: fmt.Sprintf("expected 1 to %d", maxLimit)})
+		}
+	}
+
+	if len(details) > 0 {
+		return "", 0, misc.NewError(misc.ErrorCodeBadRequest, "Invalid query parameters", details...)
+	}
+	return query, limit, nil
+}
+
+// getHit highlights the words of the query in the searched fields of match
+func getHit(match Match, terms []string) Hit {
+
+	hit := Hit{
+		ID:         match.ID,
+		Name:       match.Name,
+		Score:      math.Round(match.Score*1000) / 1000,
+		Highlights: make(map[string]string),
+	}
+	for field, text := range match.Fields {
+		if highlighted, ok := Highlight(text, terms); ok {
+			hit.Highlights[field] = highlighted
+		}
+	}
+	return hit
+}
+
+func (h *handlers) getSearch(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	query, limit, err := getQuery(r)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	terms := getTerms(query)
+	results := Results{Query: query, Results: make(map[string][]Hit)}
+	for _, kind := range Kinds {
+
+		hits := make([]Hit, 0)
+		// a query of stop words only matches nothing
+		if len(terms) > 0 {
+			matches, err := h.store.Search(r.Context(), kind, query, limit)
+			if err != nil {
+				misc.WriteError(w, r, err)
+				return
+			}
+			for _, match := range matches {
+				hits = append(hits, getHit(match, terms))
+			}
+		}
+		results.Results[kind.Name] = hits
+	}
+
+	resultsJson, err := misc.GetJsonFromJsonObjs(results)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resultsJson)
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"confusion.com/bwoo/misc"
+)
+
+func TestGetSearch(t *testing.T) {
+
+	router := misc.NewRouter()
+	SetupRoutes(router, newTestStore(t), nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q=Soup&limit=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /search: got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var results Results
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatalf("GET /search: %v", err)
+	}
+	if results.Query != "Soup" || len(results.Results) != len(Kinds) {
+		t.Fatalf("GET /search: got %+v, want the hits of every kind for Soup", results)
+	}
+
+	for _, test := range []struct {
+		kind       string
+		name       string
+		highlights map[string]string
+	}{
+		{"dishes", "Tomato Soup", map[string]string{"name": "Tomato <mark>Soup</mark>",
+			"description": "Slow cooked <mark>soup</mark> of ripe tomato"}},
+		{"promotions", "Weekend Grand Buffet", map[string]string{"description": "<mark>Soup</mark>, salad and cake for the whole family"}},
+	} {
+
+		hits := results.Results[test.kind]
+		if len(hits) != 1 || hits[0].Name != test.name || hits[0].Score <= 0 {
+			t.Errorf("%s: got %+v, want %s only", test.kind, hits, test.name)
+			continue
+		}
+		for field, want := range test.highlights {
+			if got := hits[0].Highlights[field]; got != want {
+				t.Errorf("%s %s: got highlight %q, want %q", test.kind, field, got, want)
+			}
+		}
+	}
+	if hits := results.Results["leaders"]; hits == nil || len(hits) != 0 {
+		t.Errorf("leaders: got %v, want an empty list", hits)
+	}
+}
+
+func TestGetSearchErrors(t *testing.T) {
+
+	router := misc.NewRouter()
+	SetupRoutes(router, newTestStore(t), nil)
+
+	for _, test := range []struct {
+		query string
+		field string
+	}{
+		{"", "q"},
+		{"?q=" + strings.Repeat("a", maxQueryLength+1), "q"},
+		{"?q=soup&limit=0", "limit"},
+		{"?q=soup&limit=51", "limit"},
+		{"?q=soup&limit=ten", "limit"},
+	} {
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search"+test.query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET /search%s: got status %d, want %d", test.query, w.Code, http.StatusBadRequest)
+			continue
+		}
+
+		var response struct{ Error misc.Error }
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("GET /search%s: %v", test.query, err)
+		}
+		if len(response.Error.Details) != 1 || response.Error.Details[0].Field != test.field {
+			t.Errorf("GET /search%s: got %+v, want an error on %s", test.query, response.Error, test.field)
+		}
+	}
+}
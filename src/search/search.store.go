@@ -0,0 +1,11 @@
+package search
+
+import "context"
+
+// Store finds the rows matching a query. NewDbStore returns the implementation
+// using the full-text indexes of MySQL and PostgreSQL, NewTokenizerStore one
+// tokenizing the rows itself, for SQLite and the in-memory stores.
+type Store interface {
+	// Search returns at most limit rows of kind matching the words of query, best first
+	Search(ctx context.Context, kind Kind, query string, limit int) ([]Match, error)
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a word of a text, Term being the form it is indexed and searched by
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// the words too common to tell the documents apart
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "our": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"we": true, "with": true, "you": true, "your": true,
+}
+
+// Tokenize splits text into its words, lower cased and stemmed, leaving out the stop words
+func Tokenize(text string) []Token {
+
+	tokens := make([]Token, 0)
+	start := -1
+	for i, c := range text + " " {
+		isWordChar := unicode.IsLetter(c) || unicode.IsDigit(c)
+		if isWordChar && start < 0 {
+			start = i
+		} else if !isWordChar && start >= 0 {
+			word := strings.ToLower(text[start:i])
+			if !stopWords[word] {
+				tokens = append(tokens, Token{Term: stem(word), Start: start, End: i})
+			}
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// stem folds the plural of a word into its singular, so "dishes" finds "dish"
+func stem(word string) string {
+
+	switch {
+	case utf8.RuneCountInString(word) <= 3:
+		return word
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// getTerms returns the distinct terms of a query
+func getTerms(query string) []string {
+
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, token := range Tokenize(query) {
+		if !seen[token.Term] {
+			seen[token.Term] = true
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+// Highlight HTML escapes text and puts the words with one of terms in <mark>
+// tags. It tells if any word was marked.
+func Highlight(text string, terms []string) (string, bool) {
+
+	isTerm := make(map[string]bool)
+	for _, term := range terms {
+		isTerm[term] = true
+	}
+
+	var sb strings.Builder
+	marked := false
+	last := 0
+	for _, token := range Tokenize(text) {
+		if !isTerm[token.Term] {
+			continue
+		}
+		sb.WriteString(html.EscapeString(text[last:token.Start]))
+		sb.WriteString("<mark>" + html.EscapeString(text[token.Start:token.End]) + "</mark>")
+		last = token.End
+		marked = true
+	}
+	sb.WriteString(html.EscapeString(text[last:]))
+
+	return sb.String(), marked
+}
@@ -0,0 +1,72 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+
+	for _, test := range []struct {
+		text string
+		want []Token
+	}{
+		{"Tomato Soup", []Token{{"tomato", 0, 6}, {"soup", 7, 11}}},
+		// stop words are left out, the plurals folded
+		{"the dishes of the day", []Token{{"dish", 4, 10}, {"day", 18, 21}}},
+		{"sweet-tangy, 2 sauces!", []Token{{"sweet", 0, 5}, {"tangy", 6, 11}, {"2", 13, 14}, {"sauce", 15, 21}}},
+		// the offsets are the ones of the bytes
+		{"crème brûlée", []Token{{"crème", 0, 6}, {"brûlée", 7, 15}}},
+		{"", []Token{}},
+		{"and or the", []Token{}},
+	} {
+
+		if got := Tokenize(test.text); !slices.Equal(got, test.want) {
+			t.Errorf("Tokenize(%q): got %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+
+	for _, test := range []struct {
+		word, want string
+	}{
+		{"dishes", "dish"},
+		{"berries", "berry"},
+		{"glasses", "glass"},
+		{"peaches", "peach"},
+		{"boxes", "box"},
+		{"olives", "olive"},
+		{"glass", "glass"},
+		{"hummus", "hummus"},
+		{"gas", "gas"},
+		{"pizza", "pizza"},
+	} {
+
+		if got := stem(test.word); got != test.want {
+			t.Errorf("stem(%q): got %q, want %q", test.word, got, test.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+
+	for _, test := range []struct {
+		text       string
+		terms      []string
+		want       string
+		wantMarked bool
+	}{
+		{"Tomato Soups", []string{"soup"}, "Tomato <mark>Soups</mark>", true},
+		{"Soup & <b>Bread</b>", []string{"soup", "bread"}, "<mark>Soup</mark> &amp; &lt;b&gt;<mark>Bread</mark>&lt;/b&gt;", true},
+		{"Fresh <salad>", []string{"soup"}, "Fresh &lt;salad&gt;", false},
+		{"the soup of the day", []string{"the"}, "the soup of the day", false},
+	} {
+
+		got, marked := Highlight(test.text, test.terms)
+		if got != test.want || marked != test.wantMarked {
+			t.Errorf("Highlight(%q, %v): got %q, %v, want %q, %v", test.text, test.terms, got, marked, test.want, test.wantMarked)
+		}
+	}
+}
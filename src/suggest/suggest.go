@@ -0,0 +1,163 @@
+package suggest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/favoriteDishes"
+	"confusion.com/bwoo/listing"
+)
+
+// minFuzzyLength is the length of the shortest prefix forgiven a typo, one or
+// two runes within one edit of a key would match nearly every dish
+const minFuzzyLength = 3
+
+type Suggestion struct {
+	ID       int64  `json:"_id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	// Favorites is the number of users having the dish as a favorite
+	Favorites int `json:"favorites"`
+}
+
+// Index suggests the dishes whose name, a word of their name or their
+// category starts with what the user typed, the favorite ones first. It is
+// built from the stores by Load and kept current by the stores of
+// NewIndexedDishStore and NewIndexedFavoriteDishStore.
+type Index struct {
+	dishStore     dishes.DishStore
+	favoriteStore favoriteDishes.FavoriteDishStore
+
+	mu        sync.RWMutex
+	root      *trieNode
+	dishes    map[int64]Suggestion
+	favorites map[int64]int
+}
+
+func NewIndex(dishStore dishes.DishStore, favoriteStore favoriteDishes.FavoriteDishStore) *Index {
+	return &Index{
+		dishStore:     dishStore,
+		favoriteStore: favoriteStore,
+		root:          newTrieNode(),
+		dishes:        make(map[int64]Suggestion),
+		favorites:     make(map[int64]int),
+	}
+}
+
+// normalize lower cases s and collapses its spaces, for the keys and the prefixes alike
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// getKeys returns the keys of a name: the name and its ends starting at each
+// of its other words, so "Buffalo Paneer" is found by "buf" and by "pan"
+func getKeys(name string) []string {
+
+	name = normalize(name)
+	keys := make([]string, 0)
+	wasWordChar := false
+	for i, c := range name {
+		isWordChar := unicode.IsLetter(c) || unicode.IsDigit(c)
+		if isWordChar && !wasWordChar {
+			keys = append(keys, name[i:])
+		}
+		wasWordChar = isWordChar
+	}
+	return keys
+}
+
+// Load builds the index from the stores
+func (idx *Index) Load(ctx context.Context) error {
+
+	if err := idx.loadDishes(ctx); err != nil {
+		return err
+	}
+	return idx.loadFavorites(ctx)
+}
+
+func (idx *Index) loadDishes(ctx context.Context) error {
+
+	dishList, _, err := idx.dishStore.List(ctx, listing.Params{})
+	if err != nil {
+		return err
+	}
+
+	// built aside, so the suggestions are served from the old index meanwhile
+	root := newTrieNode()
+	suggestions := make(map[int64]Suggestion)
+	for _, dish := range dishList {
+		if dish.Name == nil {
+			continue
+		}
+		suggestion := Suggestion{ID: dish.ID, Name: *dish.Name}
+		for _, key := range getKeys(*dish.Name) {
+			root.insert(key, dish.ID)
+		}
+		if dish.Category != nil {
+			suggestion.Category = *dish.Category
+			root.insert(normalize(*dish.Category), dish.ID)
+		}
+		suggestions[dish.ID] = suggestion
+	}
+
+	idx.mu.Lock()
+	idx.root = root
+	idx.dishes = suggestions
+	idx.mu.Unlock()
+	return nil
+}
+
+func (idx *Index) loadFavorites(ctx context.Context) error {
+
+	favorites, err := idx.favoriteStore.CountByDish(ctx)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	idx.favorites = favorites
+	idx.mu.Unlock()
+	return nil
+}
+
+// Suggest returns at most limit dishes matching prefix: the exact matches
+// first, then those a typo away, each of them by the number of favorites
+func (idx *Index) Suggest(prefix string, limit int) []Suggestion {
+
+	runes := []rune(normalize(prefix))
+	maxDistance := 1
+	if len(runes) < minFuzzyLength {
+		maxDistance = 0
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	distances := idx.root.search(runes, maxDistance)
+	suggestions := make([]Suggestion, 0, len(distances))
+	for dishId := range distances {
+		suggestion := idx.dishes[dishId]
+		suggestion.Favorites = idx.favorites[dishId]
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if distances[a.ID] != distances[b.ID] {
+			return distances[a.ID] < distances[b.ID]
+		}
+		if a.Favorites != b.Favorites {
+			return a.Favorites > b.Favorites
+		}
+		return a.Name < b.Name
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
@@ -0,0 +1,82 @@
+package suggest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"confusion.com/bwoo/cors"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/resource"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+
+	// the longest dish name
+	maxPrefixLength = 50
+)
+
+// handlers suggest the dish names of index
+type handlers struct {
+	index *Index
+}
+
+// Routes returns GET /dishes/suggest?prefix=, served by dishes.SetupRoutes
+// as it is under the dish routes
+func Routes(index *Index) []resource.Route {
+
+	h := &handlers{index: index}
+
+	return []resource.Route{{Name: "suggest", Handle: cors.CorsAllOrigin(h.getSuggestions)}}
+}
+
+// getQuery reads what the user typed and the number of suggestions of the query string
+func getQuery(r *http.Request) (string, int, error) {
+
+	details := make([]misc.FieldError, 0)
+	values := r.URL.Query()
+
+	prefix := values.Get("prefix")
+	if normalize(prefix) == "" {
+		details = append(details, misc.FieldError{Field: "prefix", Message: "required"})
+	} else if utf8.RuneCountInString(prefix) > maxPrefixLength {
+		details = append(details, misc.FieldError{Field: "prefix", Message: fmt.Sprintf("at most %d characters", maxPrefixLength)})
+	}
+
+	limit := defaultLimit
+	if limitStr := values.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxLimit {
+			details = append(details, misc.FieldError{Field: "limit", Message: fmt.Sprintf("expected 1 to %d", maxLimit)})
+		}
+	}
+
+	if len(details) > 0 {
+		return "", 0, misc.NewError(misc.ErrorCodeBadRequest, "Invalid query parameters", details...)
+	}
+	return prefix, limit, nil
+}
+
+func (h *handlers) getSuggestions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+	prefix, limit, err := getQuery(r)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	suggestionsJson, err := misc.GetJsonFromJsonObjs(h.index.Suggest(prefix, limit))
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(suggestionsJson)
+}
@@ -0,0 +1,63 @@
+package suggest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/misc"
+)
+
+func TestGetSuggestions(t *testing.T) {
+
+	index, dishStore, _ := newTestIndex(t, []int{1}, [2]string{"Pizza Margherita", "mains"}, [2]string{"Paneer Tikka", "appetizer"})
+	router := misc.NewRouter()
+	dishes.SetupRoutes(router, dishStore, Routes(index)...)
+
+	for _, test := range []struct {
+		query  string
+		status int
+		want   []Suggestion
+		field  string
+	}{
+		{"?prefix=piz", http.StatusOK, []Suggestion{{ID: 1, Name: "Pizza Margherita", Category: "mains", Favorites: 1}}, ""},
+		{"?prefix=p&limit=1", http.StatusOK, []Suggestion{{ID: 1, Name: "Pizza Margherita", Category: "mains", Favorites: 1}}, ""},
+		{"?prefix=sushi", http.StatusOK, []Suggestion{}, ""},
+		{"", http.StatusBadRequest, nil, "prefix"},
+		{"?prefix=%20%20", http.StatusBadRequest, nil, "prefix"},
+		{"?prefix=" + strings.Repeat("p", maxPrefixLength+1), http.StatusBadRequest, nil, "prefix"},
+		{"?prefix=piz&limit=0", http.StatusBadRequest, nil, "limit"},
+		{"?prefix=piz&limit=many", http.StatusBadRequest, nil, "limit"},
+	} {
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dishes/suggest"+test.query, nil))
+		if w.Code != test.status {
+			t.Errorf("GET /dishes/suggest%s: got status %d, want %d", test.query, w.Code, test.status)
+			continue
+		}
+
+		if test.field != "" {
+			var response struct{ Error misc.Error }
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("GET /dishes/suggest%s: %v", test.query, err)
+			}
+			if len(response.Error.Details) != 1 || response.Error.Details[0].Field != test.field {
+				t.Errorf("GET /dishes/suggest%s: got %+v, want an error on %s", test.query, response.Error, test.field)
+			}
+			continue
+		}
+
+		var suggestions []Suggestion
+		if err := json.Unmarshal(w.Body.Bytes(), &suggestions); err != nil {
+			t.Fatalf("GET /dishes/suggest%s: %v", test.query, err)
+		}
+		if !slices.Equal(suggestions, test.want) {
+			t.Errorf("GET /dishes/suggest%s: got %+v, want %+v", test.query, suggestions, test.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package suggest
+
+import (
+	"context"
+
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/favoriteDishes"
+	"confusion.com/bwoo/logging"
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/resource"
+)
+
+// indexedDishStore reloads the dishes of the index after every change made through it
+type indexedDishStore struct {
+	dishes.DishStore
+	index *Index
+}
+
+// NewIndexedDishStore returns store, keeping index current with the changes made through it
+func NewIndexedDishStore(store dishes.DishStore, index *Index) dishes.DishStore {
+	return &indexedDishStore{DishStore: store, index: index}
+}
+
+// reload is called after a change, which it doesn't fail: the index is
+// reloaded again by the next one
+func (s *indexedDishStore) reload(ctx context.Context, err error) {
+
+	if err != nil {
+		return
+	}
+	if err := s.index.loadDishes(ctx); err != nil {
+		logging.FromContext(ctx).Error("Error reloading the dish suggestions", "error", err)
+	}
+}
+
+func (s *indexedDishStore) Create(ctx context.Context, dish dishes.Dish) (*misc.Status, error) {
+
+	status, err := s.DishStore.Create(ctx, dish)
+	s.reload(ctx, err)
+	return status, err
+}
+
+func (s *indexedDishStore) Update(ctx context.Context, id int64, dish dishes.Dish, precondition resource.Precondition[dishes.Dish]) (*dishes.Dish, error) {
+
+	updated, err := s.DishStore.Update(ctx, id, dish, precondition)
+	s.reload(ctx, err)
+	return updated, err
+}
+
+func (s *indexedDishStore) Delete(ctx context.Context, id int64, precondition resource.Precondition[dishes.Dish]) (*misc.Status, error) {
+
+	status, err := s.DishStore.Delete(ctx, id, precondition)
+	s.reload(ctx, err)
+	return status, err
+}
+
+func (s *indexedDishStore) DeleteAll(ctx context.Context) (*misc.Status, error) {
+
+	status, err := s.DishStore.DeleteAll(ctx)
+	s.reload(ctx, err)
+	return status, err
+}
+
+// indexedFavoriteDishStore reloads the favorites of the index after every change made through it
+type indexedFavoriteDishStore struct {
+	favoriteDishes.FavoriteDishStore
+	index *Index
+}
+
+// NewIndexedFavoriteDishStore returns store, keeping the favorites of index
+// current with the changes made through it
+func NewIndexedFavoriteDishStore(store favoriteDishes.FavoriteDishStore, index *Index) favoriteDishes.FavoriteDishStore {
+	return &indexedFavoriteDishStore{FavoriteDishStore: store, index: index}
+}
+
+func (s *indexedFavoriteDishStore) reload(ctx context.Context, err error) {
+
+	if err != nil {
+		return
+	}
+	if err := s.index.loadFavorites(ctx); err != nil {
+		logging.FromContext(ctx).Error("Error reloading the favorites of the dish suggestions", "error", err)
+	}
+}
+
+func (s *indexedFavoriteDishStore) Create(ctx context.Context, userId, dishId int64) (*misc.Status, error) {
+
+	status, err := s.FavoriteDishStore.Create(ctx, userId, dishId)
+	s.reload(ctx, err)
+	return status, err
+}
+
+func (s *indexedFavoriteDishStore) CreateMany(ctx context.Context, userId int64, dishIds []int64) (*misc.Status, error) {
+
+	status, err := s.FavoriteDishStore.CreateMany(ctx, userId, dishIds)
+	s.reload(ctx, err)
+	return status, err
+}
+
+func (s *indexedFavoriteDishStore) Delete(ctx context.Context, userId, dishId int64) (*misc.Status, error) {
+
+	status, err := s.FavoriteDishStore.Delete(ctx, userId, dishId)
+	s.reload(ctx, err)
+	return status, err
+}
+
+func (s *indexedFavoriteDishStore) DeleteAll(ctx context.Context, userId int64) (*misc.Status, error) {
+
+	status, err := s.FavoriteDishStore.DeleteAll(ctx, userId)
+	s.reload(ctx, err)
+	return status, err
+}
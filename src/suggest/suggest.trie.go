@@ -0,0 +1,82 @@
+package suggest
+
+import "slices"
+
+// trieNode is a node of the prefix tree of the keys of the dishes, one rune per level
+type trieNode struct {
+	children map[rune]*trieNode
+	// dishIds are the dishes with a key ending here
+	dishIds []int64
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[rune]*trieNode)}
+}
+
+func (n *trieNode) insert(key string, dishId int64) {
+
+	node := n
+	for _, c := range key {
+		child, ok := node.children[c]
+		if !ok {
+			child = newTrieNode()
+			node.children[c] = child
+		}
+		node = child
+	}
+	node.dishIds = append(node.dishIds, dishId)
+}
+
+// search returns the dishes with a key starting with prefix, give or take
+// maxDistance edits (insertions, deletions or substitutions of a rune), with
+// the fewest edits each needs
+func (n *trieNode) search(prefix []rune, maxDistance int) map[int64]int {
+
+	distances := make(map[int64]int)
+
+	// the edit distances from the prefixes of prefix to the key of the node, the root's being empty
+	row := make([]int, len(prefix)+1)
+	for i := range row {
+		row[i] = i
+	}
+	n.searchRow(prefix, row, maxDistance, distances)
+	return distances
+}
+
+func (n *trieNode) searchRow(prefix []rune, row []int, maxDistance int, distances map[int64]int) {
+
+	// the key of the node is close enough to the whole prefix, so are the keys below it
+	if distance := row[len(prefix)]; distance <= maxDistance {
+		n.collect(distance, distances)
+	}
+	// the keys below are further still
+	if slices.Min(row) > maxDistance {
+		return
+	}
+
+	for c, child := range n.children {
+		next := make([]int, len(row))
+		next[0] = row[0] + 1
+		for i := 1; i < len(row); i++ {
+			substitution := row[i-1]
+			if prefix[i-1] != c {
+				substitution++
+			}
+			next[i] = min(row[i]+1, next[i-1]+1, substitution)
+		}
+		child.searchRow(prefix, next, maxDistance, distances)
+	}
+}
+
+// collect gives the dishes of the node and below it distance, unless they have a lower one
+func (n *trieNode) collect(distance int, distances map[int64]int) {
+
+	for _, dishId := range n.dishIds {
+		if d, ok := distances[dishId]; !ok || distance < d {
+			distances[dishId] = distance
+		}
+	}
+	for _, child := range n.children {
+		child.collect(distance, distances)
+	}
+}
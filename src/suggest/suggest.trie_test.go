@@ -0,0 +1,43 @@
+package suggest
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestTrieSearch(t *testing.T) {
+
+	root := newTrieNode()
+	for dishId, key := range map[int64]string{1: "pizza", 2: "paneer", 3: "pakoda", 4: "pie", 5: "pizzetta"} {
+		root.insert(key, dishId)
+	}
+
+	for _, test := range []struct {
+		prefix      string
+		maxDistance int
+		want        map[int64]int
+	}{
+		{"pizz", 0, map[int64]int{1: 0, 5: 0}},
+		{"pizza", 0, map[int64]int{1: 0}},
+		{"p", 0, map[int64]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}},
+		{"", 0, map[int64]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}},
+		{"pizzas", 0, map[int64]int{}},
+		{"naan", 1, map[int64]int{}},
+		// a substitution, an insertion and a deletion
+		{"pizxa", 1, map[int64]int{1: 1}},
+		{"pizzza", 1, map[int64]int{1: 1}},
+		{"piza", 1, map[int64]int{1: 1, 5: 1}},
+		// pie is one edit from pi, the rest of the keys is free
+		{"pix", 1, map[int64]int{1: 1, 4: 1, 5: 1}},
+		// pakoda is one edit from pan, pizza two
+		{"pan", 1, map[int64]int{2: 0, 3: 1}},
+		{"pakx", 1, map[int64]int{3: 1}},
+		{"pakxx", 1, map[int64]int{}},
+		{"pakxx", 2, map[int64]int{3: 2}},
+	} {
+
+		if got := root.search([]rune(test.prefix), test.maxDistance); !maps.Equal(got, test.want) {
+			t.Errorf("search(%q, %d): got %v, want %v", test.prefix, test.maxDistance, got, test.want)
+		}
+	}
+}
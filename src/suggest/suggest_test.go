@@ -0,0 +1,154 @@
+package suggest
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"confusion.com/bwoo/dishes"
+	"confusion.com/bwoo/favoriteDishes"
+)
+
+func TestGetKeys(t *testing.T) {
+
+	for _, test := range []struct {
+		name string
+		want []string
+	}{
+		{"Pizza", []string{"pizza"}},
+		{"Buffalo  Paneer", []string{"buffalo paneer", "paneer"}},
+		{"ElaiCheese Cake", []string{"elaicheese cake", "cake"}},
+		{"Vada-Donut (2)", []string{"vada-donut (2)", "donut (2)", "2)"}},
+		{"", []string{}},
+	} {
+
+		if got := getKeys(test.name); !slices.Equal(got, test.want) {
+			t.Errorf("getKeys(%q): got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+// newTestIndex indexes the dishes of memory stores through the indexed
+// stores, with favorites[i] users having the dish i+1 as a favorite
+func newTestIndex(t *testing.T, favorites []int, names ...[2]string) (*Index, dishes.DishStore, favoriteDishes.FavoriteDishStore) {
+
+	t.Helper()
+	ctx := context.Background()
+
+	dishStore := dishes.NewMemoryStore()
+	favoriteStore := favoriteDishes.NewMemoryStore(dishStore)
+	index := NewIndex(dishStore, favoriteStore)
+	if err := index.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	dishStore = NewIndexedDishStore(dishStore, index)
+	favoriteStore = NewIndexedFavoriteDishStore(favoriteStore, index)
+
+	for _, dish := range names {
+		name, category := dish[0], dish[1]
+		image, price, description := "images/dish.png", "4.99", "A dish"
+		if _, err := dishStore.Create(ctx, dishes.Dish{Name: &name, Category: &category, Image: &image,
+			Price: &price, Description: &description}); err != nil {
+			t.Fatalf("Create %s: %v", name, err)
+		}
+	}
+	for i, count := range favorites {
+		for userId := int64(1); userId <= int64(count); userId++ {
+			if _, err := favoriteStore.Create(ctx, userId, int64(i+1)); err != nil {
+				t.Fatalf("Create favorite: %v", err)
+			}
+		}
+	}
+	return index, dishStore, favoriteStore
+}
+
+func TestSuggest(t *testing.T) {
+
+	index, _, _ := newTestIndex(t, []int{0, 2, 1, 0, 3},
+		[2]string{"Uthappizza", "mains"}, [2]string{"Pizza Margherita", "mains"}, [2]string{"Pizza Diavola", "mains"},
+		[2]string{"Paneer Tikka", "appetizer"}, [2]string{"Buffalo Paneer", "appetizer"})
+
+	for _, test := range []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		// the most favorite first
+		{"pizza", 10, []string{"Pizza Margherita", "Pizza Diavola"}},
+		{"Pizza ", 10, []string{"Pizza Margherita", "Pizza Diavola"}},
+		// any word of the name
+		{"pan", 10, []string{"Buffalo Paneer", "Paneer Tikka"}},
+		{"tik", 10, []string{"Paneer Tikka"}},
+		// the category
+		{"appe", 10, []string{"Buffalo Paneer", "Paneer Tikka"}},
+		// the exact matches before the ones a typo away, whatever their favorites
+		{"pizz", 10, []string{"Pizza Margherita", "Pizza Diavola"}},
+		{"pizza d", 10, []string{"Pizza Diavola", "Pizza Margherita"}},
+		{"piza", 10, []string{"Pizza Margherita", "Pizza Diavola"}},
+		{"uthapizza", 10, []string{"Uthappizza"}},
+		{"paner", 10, []string{"Buffalo Paneer", "Paneer Tikka"}},
+		{"main", 2, []string{"Pizza Margherita", "Pizza Diavola"}},
+		{"main", 1, []string{"Pizza Margherita"}},
+		// too short to forgive a typo
+		{"px", 10, []string{}},
+		{"sushi", 10, []string{}},
+	} {
+
+		names := make([]string, 0)
+		for _, suggestion := range index.Suggest(test.prefix, test.limit) {
+			names = append(names, suggestion.Name)
+		}
+		if !slices.Equal(names, test.want) {
+			t.Errorf("Suggest(%q, %d): got %q, want %q", test.prefix, test.limit, names, test.want)
+		}
+	}
+
+	suggestions := index.Suggest("buffalo", 1)
+	if len(suggestions) != 1 || suggestions[0] != (Suggestion{ID: 5, Name: "Buffalo Paneer", Category: "appetizer", Favorites: 3}) {
+		t.Errorf("Suggest(buffalo): got %+v, want Buffalo Paneer with 3 favorites", suggestions)
+	}
+}
+
+func TestSuggestFollowsTheStores(t *testing.T) {
+
+	ctx := context.Background()
+	index, dishStore, favoriteStore := newTestIndex(t, nil, [2]string{"Pizza Margherita", "mains"}, [2]string{"Pizza Diavola", "mains"})
+
+	suggest := func() []string {
+		names := make([]string, 0)
+		for _, suggestion := range index.Suggest("pizza", 10) {
+			names = append(names, suggestion.Name)
+		}
+		return names
+	}
+
+	for _, test := range []struct {
+		name   string
+		change func() error
+		want   []string
+	}{
+		{"by name", func() error { return nil }, []string{"Pizza Diavola", "Pizza Margherita"}},
+		{"a favorite", func() error { _, err := favoriteStore.Create(ctx, 1, 2); return err },
+			[]string{"Pizza Diavola", "Pizza Margherita"}},
+		{"another favorite", func() error { _, err := favoriteStore.CreateMany(ctx, 2, []int64{1}); return err },
+			[]string{"Pizza Diavola", "Pizza Margherita"}},
+		{"a third favorite", func() error { _, err := favoriteStore.Create(ctx, 3, 1); return err },
+			[]string{"Pizza Margherita", "Pizza Diavola"}},
+		{"favorites removed", func() error { _, err := favoriteStore.DeleteAll(ctx, 3); return err },
+			[]string{"Pizza Diavola", "Pizza Margherita"}},
+		{"renamed", func() error {
+			name := "Calzone"
+			_, err := dishStore.Update(ctx, 2, dishes.Dish{Name: &name}, nil)
+			return err
+		}, []string{"Pizza Margherita"}},
+		{"deleted", func() error { _, err := dishStore.Delete(ctx, 1, nil); return err }, []string{}},
+	} {
+
+		if err := test.change(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := suggest(); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
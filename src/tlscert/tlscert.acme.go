@@ -0,0 +1,111 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func newAcmeManager(config config.Config) (*autocert.Manager, error) {
+
+	client := &acme.Client{DirectoryURL: config.AcmeDirectoryUrl}
+
+	// a test ACME server such as Pebble serves its directory with its own CA
+	if config.AcmeCaCert != "" {
+		caCert, err := os.ReadFile(config.AcmeCaCert)
+		if err != nil {
+			return nil, err
+		}
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("No certificate found in acme_ca_cert %s", config.AcmeCaCert)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(config.AcmeCacheDir),
+		HostPolicy: autocert.HostWhitelist(config.GetAcmeDomains()...),
+		Client:     client,
+		Email:      config.AcmeEmail,
+	}, nil
+}
+
+func (m *Manager) isAcmeDomain(serverName string) bool {
+
+	return m.acme.HostPolicy(nil, strings.TrimSuffix(serverName, ".")) == nil
+}
+
+// AcmeCertificate tells when the ACME certificate of Domain expires, NotAfter
+// is zero until autocert obtained it, on the first handshake for Domain
+type AcmeCertificate struct {
+	Domain   string
+	NotAfter time.Time
+}
+
+// GetAcmeCertificateExpiries reads the certificates of the acme_domains from
+// the autocert cache, the ones autocert serves and renews. It returns none
+// when acme_domains is not set.
+func (m *Manager) GetAcmeCertificateExpiries(ctx context.Context) ([]AcmeCertificate, error) {
+
+	var certs []AcmeCertificate
+	for _, domain := range m.acmeDomains {
+		cert := AcmeCertificate{Domain: domain}
+		// autocert keeps an ECDSA certificate, and an RSA one for the clients
+		// without ECDSA, the first to expire is reported
+		for _, key := range []string{domain, domain + "+rsa"} {
+			notAfter, err := m.getCachedExpiry(ctx, key)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading the ACME certificate of %s: %v", domain, err)
+			}
+			if !notAfter.IsZero() && (cert.NotAfter.IsZero() || notAfter.Before(cert.NotAfter)) {
+				cert.NotAfter = notAfter
+			}
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// getCachedExpiry returns when the certificate cached under key expires, zero
+// if there is none. autocert caches the private key followed by the chain,
+// leaf first.
+func (m *Manager) getCachedExpiry(ctx context.Context, key string) (time.Time, error) {
+
+	data, err := m.acme.Cache.Get(ctx, key)
+	if errors.Is(err, autocert.ErrCacheMiss) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		leaf, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return leaf.NotAfter, nil
+	}
+	return time.Time{}, fmt.Errorf("no certificate in the cache entry %s", key)
+}
@@ -0,0 +1,174 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// how often GetCertificate looks for a rotated certificate on disk
+const reloadCheckInterval = 5 * time.Second
+
+// Manager hands out the certificate of the https server: an ACME certificate
+// for the acme_domains, the certificate at cert_path / key_path otherwise.
+// The files are reloaded when they change, so a rotated certificate is
+// picked up without a restart.
+type Manager struct {
+	certPath    string
+	keyPath     string
+	acme        *autocert.Manager
+	acmeDomains []string
+
+	mutex       sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+// NewManager loads the certificate files, generating a self-signed
+// certificate first if they do not exist and self_signed_cert is set
+func NewManager(config config.Config) (*Manager, error) {
+
+	m := &Manager{certPath: config.CertPath, keyPath: config.KeyPath}
+
+	if config.SelfSignedCert && !fileExists(m.certPath) && !fileExists(m.keyPath) {
+		hosts := append([]string{"localhost", "127.0.0.1", "::1", "www.confusion.com"}, config.GetAcmeDomains()...)
+		if err := GenerateSelfSigned(m.certPath, m.keyPath, hosts); err != nil {
+			return nil, fmt.Errorf("Error generating a self-signed certificate: %v", err)
+		}
+		slog.Info("Generated a self-signed certificate", "path", m.certPath)
+	}
+
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+
+	if len(config.GetAcmeDomains()) > 0 {
+		acmeManager, err := newAcmeManager(config)
+		if err != nil {
+			return nil, err
+		}
+		m.acme = acmeManager
+		m.acmeDomains = config.GetAcmeDomains()
+	}
+
+	return m, nil
+}
+
+func fileExists(path string) bool {
+
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// reload loads the certificate files if they changed since the last load
+func (m *Manager) reload() error {
+
+	certInfo, err := os.Stat(m.certPath)
+	if err != nil {
+		return fmt.Errorf("Error loading the certificate: %v", err)
+	}
+	keyInfo, err := os.Stat(m.keyPath)
+	if err != nil {
+		return fmt.Errorf("Error loading the certificate key: %v", err)
+	}
+
+	if m.cert != nil && certInfo.ModTime().Equal(m.certModTime) && keyInfo.ModTime().Equal(m.keyModTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(m.certPath, m.keyPath)
+	if err != nil {
+		return fmt.Errorf("Error loading the certificate %s: %v", m.certPath, err)
+	}
+
+	if m.cert != nil {
+		slog.Info("Reloaded the certificate", "path", m.certPath)
+	}
+	m.cert = &cert
+	m.certModTime = certInfo.ModTime()
+	m.keyModTime = keyInfo.ModTime()
+	return nil
+}
+
+// getFileCertificate returns the certificate from disk, reloading it if it
+// was rotated. A rotation failing half way, e.g. a new certificate next to
+// the old key, keeps the previous certificate until both files match again.
+func (m *Manager) getFileCertificate() *tls.Certificate {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if time.Since(m.lastCheck) >= reloadCheckInterval {
+		m.lastCheck = time.Now()
+		if err := m.reload(); err != nil {
+			slog.Error("Error reloading the certificate", "path", m.certPath, "error", err)
+		}
+	}
+	return m.cert
+}
+
+// GetFileCertificateExpiry returns when the certificate at cert_path, the one
+// served unless an ACME certificate is, expires
+func (m *Manager) GetFileCertificateExpiry() (time.Time, error) {
+
+	cert := m.getFileCertificate()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Error parsing the certificate %s: %v", m.certPath, err)
+	}
+	return leaf.NotAfter, nil
+}
+
+// GetCertificate is the tls.Config callback choosing the certificate of
+// every TLS handshake
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+
+	if m.acme != nil && hello.ServerName != "" {
+		cert, err := m.acme.GetCertificate(hello)
+		if err == nil {
+			return cert, nil
+		}
+		if m.isAcmeDomain(hello.ServerName) {
+			slog.Warn("No ACME certificate, using the certificate file",
+				"serverName", hello.ServerName, "path", m.certPath, "error", err)
+		}
+	}
+
+	return m.getFileCertificate(), nil
+}
+
+// TLSConfig returns the tls.Config of the https server
+func (m *Manager) TLSConfig() *tls.Config {
+
+	nextProtos := []string{"h2", "http/1.1"}
+	if m.acme != nil {
+		// answers the tls-alpn-01 challenges
+		nextProtos = append(nextProtos, acme.ALPNProto)
+	}
+
+	return &tls.Config{
+		NextProtos:     nextProtos,
+		GetCertificate: m.GetCertificate,
+	}
+}
+
+// HTTPHandler answers the ACME http-01 challenges and passes the other
+// requests to fallback
+func (m *Manager) HTTPHandler(fallback http.Handler) http.Handler {
+
+	if m.acme == nil {
+		return fallback
+	}
+	return m.acme.HTTPHandler(fallback)
+}
@@ -0,0 +1,348 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"confusion.com/bwoo/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// the ACME certificates are ECDSA ones, autocert looks up an RSA certificate
+// for the clients not offering ECDSA
+var ecdsaCipherSuites = []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+
+func newTestConfig(t *testing.T) config.Config {
+
+	t.Helper()
+
+	dir := t.TempDir()
+	return config.Config{
+		CertPath:     filepath.Join(dir, "certs", "server.crt"),
+		KeyPath:      filepath.Join(dir, "certs", "server.key"),
+		AcmeCacheDir: filepath.Join(dir, "acme"),
+	}
+}
+
+// newPemCertificate returns a certificate for host expiring at notAfter,
+// encoded after its key like autocert caches them
+func newPemCertificate(t *testing.T, host string, notAfter time.Time) (*x509.Certificate, []byte) {
+
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	cert, err := x509.ParseCertificate(certDer)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer})...)
+	return cert, data
+}
+
+func getLeaf(t *testing.T, cert *tls.Certificate) *x509.Certificate {
+
+	t.Helper()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return leaf
+}
+
+func TestNewManagerSelfSigned(t *testing.T) {
+
+	for _, test := range []struct {
+		name           string
+		selfSignedCert bool
+		acmeDomains    string
+		wantErr        bool
+		wantHosts      []string
+	}{
+		{name: "self-signed", selfSignedCert: true, wantHosts: []string{"localhost", "www.confusion.com"}},
+		{name: "self-signed for the ACME domains", selfSignedCert: true, acmeDomains: "shop.test",
+			wantHosts: []string{"localhost", "shop.test"}},
+		{name: "missing files", wantErr: true},
+	} {
+
+		config := newTestConfig(t)
+		config.SelfSignedCert = test.selfSignedCert
+		config.AcmeDomains = test.acmeDomains
+
+		m, err := NewManager(config)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: NewManager: got no error, want one", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: NewManager: %v", test.name, err)
+		}
+
+		leaf := getLeaf(t, m.getFileCertificate())
+		for _, host := range test.wantHosts {
+			if !slices.Contains(leaf.DNSNames, host) {
+				t.Errorf("%s: DNSNames: got %v, want %s in them", test.name, leaf.DNSNames, host)
+			}
+		}
+		if !slices.ContainsFunc(leaf.IPAddresses, net.IP.IsLoopback) {
+			t.Errorf("%s: IPAddresses: got %v, want the loopback addresses", test.name, leaf.IPAddresses)
+		}
+
+		notAfter, err := m.GetFileCertificateExpiry()
+		if err != nil {
+			t.Fatalf("%s: GetFileCertificateExpiry: %v", test.name, err)
+		}
+		if want := time.Now().Add(selfSignedValidity); notAfter.Before(want.Add(-2*time.Hour)) || notAfter.After(want) {
+			t.Errorf("%s: GetFileCertificateExpiry: got %v, want about %v", test.name, notAfter, want)
+		}
+	}
+}
+
+func TestNewManagerKeepsExistingFiles(t *testing.T) {
+
+	config := newTestConfig(t)
+	config.SelfSignedCert = true
+	if err := GenerateSelfSigned(config.CertPath, config.KeyPath, []string{"existing.test"}); err != nil {
+		t.Fatalf("GenerateSelfSigned: %v", err)
+	}
+
+	m, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if leaf := getLeaf(t, m.getFileCertificate()); !slices.Equal(leaf.DNSNames, []string{"existing.test"}) {
+		t.Errorf("DNSNames: got %v, want the certificate on disk", leaf.DNSNames)
+	}
+}
+
+func TestGetCertificateReloads(t *testing.T) {
+
+	config := newTestConfig(t)
+	config.SelfSignedCert = true
+	m, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	hello := &tls.ClientHelloInfo{ServerName: "localhost"}
+
+	getSerial := func() *big.Int {
+		cert, err := m.GetCertificate(hello)
+		if err != nil {
+			t.Fatalf("GetCertificate: %v", err)
+		}
+		return getLeaf(t, cert).SerialNumber
+	}
+	// makes the files look changed and the next GetCertificate look for them
+	touch := func(paths ...string) {
+		modTime := time.Now().Add(time.Minute)
+		for _, path := range paths {
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatalf("Chtimes: %v", err)
+			}
+		}
+		m.lastCheck = time.Time{}
+	}
+
+	first := getSerial()
+
+	// a rotation within reloadCheckInterval of the last check is not seen yet
+	if err := GenerateSelfSigned(config.CertPath, config.KeyPath, []string{"localhost"}); err != nil {
+		t.Fatalf("GenerateSelfSigned: %v", err)
+	}
+	if got := getSerial(); got.Cmp(first) != 0 {
+		t.Errorf("before reloadCheckInterval: got serial %v, want the previous %v", got, first)
+	}
+
+	touch(config.CertPath, config.KeyPath)
+	rotated := getSerial()
+	if rotated.Cmp(first) == 0 {
+		t.Errorf("after the rotation: got the previous serial %v, want the new certificate", first)
+	}
+
+	// a new certificate next to the old key keeps the previous certificate
+	otherDir := t.TempDir()
+	otherCert, otherKey := filepath.Join(otherDir, "other.crt"), filepath.Join(otherDir, "other.key")
+	if err := GenerateSelfSigned(otherCert, otherKey, []string{"localhost"}); err != nil {
+		t.Fatalf("GenerateSelfSigned: %v", err)
+	}
+	data, err := os.ReadFile(otherCert)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	other, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	if err := os.WriteFile(config.CertPath, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	touch(config.CertPath)
+	if got := getSerial(); got.Cmp(rotated) != 0 {
+		t.Errorf("half rotated: got serial %v, want the previous %v", got, rotated)
+	}
+
+	// until the key follows
+	if err := os.Rename(otherKey, config.KeyPath); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	touch(config.CertPath, config.KeyPath)
+	if got := getSerial(); got.Cmp(other.SerialNumber) != 0 {
+		t.Errorf("rotation completed: got serial %v, want %v", got, other.SerialNumber)
+	}
+}
+
+func TestGetCertificateAcme(t *testing.T) {
+
+	// an ACME server without a directory, so no certificate can be obtained
+	acmeServer := httptest.NewServer(http.NotFoundHandler())
+	defer acmeServer.Close()
+
+	config := newTestConfig(t)
+	config.SelfSignedCert = true
+	config.AcmeDomains = "cached.test,missing.test"
+	config.AcmeDirectoryUrl = acmeServer.URL
+
+	cached, data := newPemCertificate(t, "cached.test", time.Now().Add(30*24*time.Hour).Truncate(time.Second))
+	if err := autocert.DirCache(config.AcmeCacheDir).Put(context.Background(), "cached.test", data); err != nil {
+		t.Fatalf("DirCache.Put: %v", err)
+	}
+
+	m, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	fileSerial := getLeaf(t, m.getFileCertificate()).SerialNumber
+
+	for _, test := range []struct {
+		serverName string
+		wantSerial *big.Int
+	}{
+		{"cached.test", cached.SerialNumber},
+		// the ACME server fails, the certificate file is served
+		{"missing.test", fileSerial},
+		{"localhost", fileSerial},
+		{"", fileSerial},
+	} {
+
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: test.serverName, CipherSuites: ecdsaCipherSuites})
+		if err != nil {
+			t.Errorf("GetCertificate %q: %v", test.serverName, err)
+			continue
+		}
+		if got := getLeaf(t, cert).SerialNumber; got.Cmp(test.wantSerial) != 0 {
+			t.Errorf("GetCertificate %q: got serial %v, want %v", test.serverName, got, test.wantSerial)
+		}
+	}
+
+	expiries, err := m.GetAcmeCertificateExpiries(context.Background())
+	if err != nil {
+		t.Fatalf("GetAcmeCertificateExpiries: %v", err)
+	}
+	want := []AcmeCertificate{{Domain: "cached.test", NotAfter: cached.NotAfter}, {Domain: "missing.test"}}
+	if !slices.EqualFunc(expiries, want, func(a, b AcmeCertificate) bool { return a.Domain == b.Domain && a.NotAfter.Equal(b.NotAfter) }) {
+		t.Errorf("GetAcmeCertificateExpiries: got %v, want %v", expiries, want)
+	}
+}
+
+func TestGetAcmeCertificateExpiries(t *testing.T) {
+
+	soon := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	later := time.Now().Add(60 * 24 * time.Hour).Truncate(time.Second)
+
+	for _, test := range []struct {
+		name     string
+		cache    map[string]time.Time
+		garbage  bool
+		want     time.Time
+		wantErr  bool
+		disabled bool
+	}{
+		{name: "not obtained"},
+		{name: "ECDSA", cache: map[string]time.Time{"shop.test": later}, want: later},
+		{name: "RSA only", cache: map[string]time.Time{"shop.test+rsa": later}, want: later},
+		{name: "the first to expire", cache: map[string]time.Time{"shop.test": later, "shop.test+rsa": soon}, want: soon},
+		{name: "no certificate in the entry", garbage: true, wantErr: true},
+		{name: "acme_domains not set", disabled: true},
+	} {
+
+		config := newTestConfig(t)
+		config.SelfSignedCert = true
+		if !test.disabled {
+			config.AcmeDomains = "shop.test"
+		}
+		cache := autocert.DirCache(config.AcmeCacheDir)
+		for key, notAfter := range test.cache {
+			_, data := newPemCertificate(t, "shop.test", notAfter)
+			if err := cache.Put(context.Background(), key, data); err != nil {
+				t.Fatalf("%s: DirCache.Put: %v", test.name, err)
+			}
+		}
+		if test.garbage {
+			if err := cache.Put(context.Background(), "shop.test", []byte("garbage")); err != nil {
+				t.Fatalf("%s: DirCache.Put: %v", test.name, err)
+			}
+		}
+
+		m, err := NewManager(config)
+		if err != nil {
+			t.Fatalf("%s: NewManager: %v", test.name, err)
+		}
+
+		expiries, err := m.GetAcmeCertificateExpiries(context.Background())
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: got no error, want one", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if test.disabled {
+			if len(expiries) != 0 {
+				t.Errorf("%s: got %v, want none", test.name, expiries)
+			}
+			continue
+		}
+		if len(expiries) != 1 || expiries[0].Domain != "shop.test" || !expiries[0].NotAfter.Equal(test.want) {
+			t.Errorf("%s: got %v, want shop.test expiring at %v", test.name, expiries, test.want)
+		}
+	}
+}
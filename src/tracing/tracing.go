@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+
+	"confusion.com/bwoo/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "confusion"
+
+// instrumentationName names the tracer of the spans of this module
+const instrumentationName = "confusion.com/bwoo"
+
+// Setup sends the spans to the OTLP/HTTP collector at otlp_endpoint. With an
+// empty otlp_endpoint no span is recorded, but the W3C trace context of the
+// requests is still passed on. The returned function flushes the spans not
+// sent yet, it is called once the servers stopped.
+func Setup(config config.Config) (func(ctx context.Context) error, error) {
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if config.OtlpEndpoint == "" {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	// the OTEL_EXPORTER_OTLP_* environment variables, e.g. for headers, are honored as well
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(config.OtlpEndpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name, child of the span of ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"net/http"
+
+	"confusion.com/bwoo/misc"
+	"confusion.com/bwoo/recorder"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace starts a server span for every request, continuing the trace of the
+// traceparent header if the client sent one, and serves it with next. The span
+// is named after the route of the misc.Router the request matches once it is
+// served, e.g. GET /dishes/:dishId.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		r, getRoute := misc.GetRoutePattern(r)
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			))
+		defer span.End()
+
+		rec := recorder.New(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		route := getRoute()
+		span.SetName(r.Method + " " + route)
+		status := rec.Status()
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
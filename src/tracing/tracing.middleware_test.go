@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"confusion.com/bwoo/config"
+	"confusion.com/bwoo/misc"
+
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceId     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentId    = "00f067aa0ba902b7"
+	testTraceparent = "00-" + testTraceId + "-" + testParentId + "-01"
+)
+
+// recordSpans records the spans ended during the test in memory
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+
+	t.Helper()
+
+	if _, err := Setup(config.Config{}); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func getAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTrace(t *testing.T) {
+
+	exporter := recordSpans(t)
+	router := misc.NewRouter()
+	router.GET("/dishes/:dishId", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+
+		_, span := Start(r.Context(), "dishes.get")
+		span.End()
+		if ps.ByName("dishId") == "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	handler := Trace(router)
+
+	for _, test := range []struct {
+		path        string
+		traceparent string
+		wantName    string
+		wantStatus  int64
+		wantCode    codes.Code
+	}{
+		{"/dishes/1", "", "GET /dishes/:dishId", http.StatusOK, codes.Unset},
+		{"/dishes/1", testTraceparent, "GET /dishes/:dishId", http.StatusOK, codes.Unset},
+		{"/dishes/0", "", "GET /dishes/:dishId", http.StatusInternalServerError, codes.Error},
+		{"/unknown", "", "GET " + misc.UnmatchedRoute, http.StatusNotFound, codes.Unset},
+	} {
+
+		exporter.Reset()
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.traceparent != "" {
+			r.Header.Set("traceparent", test.traceparent)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		spans := exporter.GetSpans()
+		server := spans[len(spans)-1]
+		if server.Name != test.wantName || server.SpanKind != trace.SpanKindServer {
+			t.Errorf("%s: got span %s of kind %s, want the server span %s", test.path, server.Name, server.SpanKind, test.wantName)
+		}
+		if status := getAttribute(server, "http.response.status_code").AsInt64(); status != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d", test.path, status, test.wantStatus)
+		}
+		if server.Status.Code != test.wantCode {
+			t.Errorf("%s: got span status %s, want %s", test.path, server.Status.Code, test.wantCode)
+		}
+
+		// the trace of the client is continued
+		if test.traceparent != "" {
+			if server.SpanContext.TraceID().String() != testTraceId || server.Parent.SpanID().String() != testParentId ||
+				!server.Parent.IsRemote() {
+				t.Errorf("%s: got trace %s and parent %s, want the traceparent of the client",
+					test.path, server.SpanContext.TraceID(), server.Parent.SpanID())
+			}
+		} else if server.Parent.IsValid() {
+			t.Errorf("%s: got parent %s, want a new trace", test.path, server.Parent.SpanID())
+		}
+
+		// the spans of the handler are children of the server span
+		if len(spans) == 2 && spans[0].Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Errorf("%s: got span %s child of %s, want of the server span", test.path, spans[0].Name, spans[0].Parent.SpanID())
+		}
+	}
+}
+
+// without otlp_endpoint no span is recorded, but the traceparent is still passed on
+func TestSetupWithoutEndpoint(t *testing.T) {
+
+	shutdown, err := Setup(config.Config{})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	var spanContext trace.SpanContext
+	handler := Trace(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spanContext = trace.SpanContextFromContext(r.Context())
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", testTraceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if spanContext.TraceID().String() != testTraceId {
+		t.Errorf("Trace: got trace %s, want %s", spanContext.TraceID(), testTraceId)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"confusion.com/bwoo/auth"
 	"confusion.com/bwoo/config"
 	"confusion.com/bwoo/cors"
+	"confusion.com/bwoo/metrics"
 	"confusion.com/bwoo/misc"
 
 	"github.com/julienschmidt/httprouter"
@@ -24,7 +25,7 @@ var imageDirectory string
 // use original name from client
 // only allow post.  Get PUT and DELETE not allowed
 
-func SetupRoutes(router *httprouter.Router, config config.Config) {
+func SetupRoutes(router *misc.Router, config config.Config) {
 
 	imageDirectory = config.PublicImagesDir
 	//imageDirectoryFull = config.GetPublicImagesDir()
@@ -40,9 +41,7 @@ func SetupRoutes(router *httprouter.Router, config config.Config) {
 
 func methodNotSupported(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
-	httpMethod := r.Method
-	w.WriteHeader(http.StatusForbidden)
-	w.Write([]byte(httpMethod + " operation not supported on /imageUpload"))
+	misc.WriteError(w, r, misc.NewMethodNotAllowedError(r))
 }
 
 func getImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -50,7 +49,7 @@ func getImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	fileName := ps.ByName("imageName")
 	file, err := os.Open(filepath.Join(imageDirectory, fileName))
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		misc.WriteError(w, r, misc.NewNotFoundError("Image "+fileName+" not found"))
 		return
 	}
 	defer file.Close()
@@ -63,7 +62,7 @@ func getImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// get file size
 	stat, err := file.Stat()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		misc.WriteError(w, r, err)
 		return
 	}
 	fSize := strconv.FormatInt(stat.Size(), 10)
@@ -81,17 +80,26 @@ func postImageUpload(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 	r.ParseMultipartForm(5 * 1024 * 1024) // 5MB
 	file, handler, err := r.FormFile(fileUploadFormFileKey)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		misc.WriteError(w, r, misc.NewBadRequestError("Missing form file "+fileUploadFormFileKey+": "+err.Error()))
 		return
 	}
 	defer file.Close()
 
 	// Create an empty file on filesystem
 	f, err := os.OpenFile(filepath.Join(imageDirectory, handler.Filename), os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
 	defer f.Close()
 
 	// Copy the file to the images directory
-	io.Copy(f, file)
+	bytesWritten, err := io.Copy(f, file)
+	metrics.UploadedBytes.Add(float64(bytesWritten))
+	if err != nil {
+		misc.WriteError(w, r, err)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 
@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"confusion.com/bwoo/misc"
+)
+
+// maximum size of a JSON request body
+const maxBodyBytes = 1024 * 1024 // 1MB
+
+// DecodeJson decodes the JSON body of r into v. The body has to be a single
+// JSON value of at most 1MB without fields v doesn't have, otherwise the
+// returned error tells what is wrong with it, field by field where it can.
+func DecodeJson(w http.ResponseWriter, r *http.Request, v interface{}) error {
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return getDecodeError(err)
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after the JSON value")
+		}
+		return getDecodeError(err)
+	}
+
+	return nil
+}
+
+// DecodeAndValidate decodes the JSON body of r into v and validates it, see Validate
+func DecodeAndValidate(w http.ResponseWriter, r *http.Request, v interface{}, partial bool) error {
+
+	if err := DecodeJson(w, r, v); err != nil {
+		return err
+	}
+	return Validate(v, partial)
+}
+
+func getDecodeError(err error) error {
+
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return misc.NewTooLargeError(fmt.Sprintf("Request body larger than %d bytes", maxBodyBytes))
+
+	case errors.Is(err, io.EOF):
+		return misc.NewBadRequestError("Invalid JSON body: empty body")
+
+	case errors.As(err, &typeErr):
+		message := "expected " + getJsonType(typeErr.Type)
+		if typeErr.Field == "" {
+			return misc.NewBadRequestError("Invalid JSON body: " + message)
+		}
+		return misc.NewValidationError(misc.FieldError{Field: typeErr.Field, Message: message})
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return misc.NewValidationError(misc.FieldError{Field: field, Message: "unknown field"})
+	}
+
+	return misc.NewBadRequestError("Invalid JSON body: " + err.Error())
+}
+
+// getJsonType names the JSON type decoded into a value of type t
+func getJsonType(t reflect.Type) string {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
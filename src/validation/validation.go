@@ -0,0 +1,346 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"confusion.com/bwoo/misc"
+)
+
+// The models declare the rules of their fields in validate tags, comma
+// separated and checked in order:
+//
+//	required    the field must be set, and a string must not be empty
+//	min=N       a string must have at least N characters, a number must be at least N
+//	max=N       a string must have at most N characters, a number must be at most N
+//	oneof=a b   the value must be one of the space separated values
+//	number      a string must be a decimal number which is not negative, like a price
+//
+// e.g. Name *string `json:"name" validate:"required,max=50"`
+const tagName = "validate"
+
+// a rule of the validate tags: parse checks its argument and the kind of the
+// fields it is declared on, check returns what is wrong with value, or "" if
+// nothing is
+type ruleType struct {
+	parse func(r *rule, kind reflect.Kind) error
+	check func(value reflect.Value, r rule) string
+}
+
+// rules by name
+var rules = map[string]ruleType{
+	"required": {parse: parseNoArg, check: checkRequired},
+	"min":      {parse: parseLimit, check: checkMin},
+	"max":      {parse: parseLimit, check: checkMax},
+	"oneof":    {parse: parseOneOf, check: checkOneOf},
+	"number":   {parse: parseNumber, check: checkNumber},
+}
+
+// rule is a rule of a validate tag, parsed once per type
+type rule struct {
+	name string
+	arg  string
+	// limit is the argument of min and max
+	limit float64
+	// allowed are the values of oneof
+	allowed []string
+	check   func(value reflect.Value, r rule) string
+}
+
+// fieldRules are the rules of a field, found by its index in the struct
+type fieldRules struct {
+	index    []int
+	name     string
+	required bool
+	rules    []rule
+}
+
+// the fieldRules of the registered types, by type
+var registered sync.Map
+
+// Register parses the validate tags of the struct v, or the struct v points
+// to, and checks their rules fit the fields, so a wrong tag is found when the
+// routes are set up instead of by a request. Validate registers the types it
+// has not seen yet itself.
+func Register(v interface{}) error {
+
+	_, err := getFieldRules(reflect.TypeOf(v))
+	return err
+}
+
+// MustRegister is like Register but panics on a wrong tag, as the tags are
+// declared in the code
+func MustRegister(v interface{}) {
+
+	if err := Register(v); err != nil {
+		panic(err.Error())
+	}
+}
+
+func getFieldRules(t reflect.Type) ([]fieldRules, error) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if parsed, ok := registered.Load(t); ok {
+		return parsed.([]fieldRules), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Cannot validate a %s", t)
+	}
+
+	parsed, err := parseStruct(t, nil, make([]fieldRules, 0))
+	if err != nil {
+		return nil, err
+	}
+	registered.Store(t, parsed)
+	return parsed, nil
+}
+
+func parseStruct(t reflect.Type, index []int, parsed []fieldRules) ([]fieldRules, error) {
+
+	for i := 0; i < t.NumField(); i++ {
+
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		// embedded structs, like the credentials of auth.UserInfo, are part of the JSON object
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			var err error
+			if parsed, err = parseStruct(field.Type, fieldIndex, parsed); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+
+		fieldRules, err := parseField(field, tag)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid validate tag of %s.%s: %v", t, field.Name, err)
+		}
+		fieldRules.index = fieldIndex
+		parsed = append(parsed, fieldRules)
+	}
+
+	return parsed, nil
+}
+
+func parseField(field reflect.StructField, tag string) (fieldRules, error) {
+
+	parsed := fieldRules{name: getJsonName(field)}
+	kind := field.Type.Kind()
+	if kind == reflect.Ptr {
+		kind = field.Type.Elem().Kind()
+	}
+
+	for _, ruleStr := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(ruleStr, "=")
+		ruleType, ok := rules[name]
+		if !ok {
+			return fieldRules{}, fmt.Errorf("unknown rule %q", name)
+		}
+
+		r := rule{name: name, arg: arg, check: ruleType.check}
+		if err := ruleType.parse(&r, kind); err != nil {
+			return fieldRules{}, fmt.Errorf("rule %q: %v", ruleStr, err)
+		}
+		parsed.required = parsed.required || name == "required"
+		parsed.rules = append(parsed.rules, r)
+	}
+	return parsed, nil
+}
+
+// Validate checks the fields of the struct v against the rules of their
+// validate tags and returns a validation error listing every invalid field.
+// A partial v, the body of an update, only sets the fields to change, so
+// the fields it leaves out are not required.
+func Validate(v interface{}, partial bool) error {
+
+	value := reflect.Indirect(reflect.ValueOf(v))
+	parsed, err := getFieldRules(value.Type())
+	if err != nil {
+		return err
+	}
+
+	details := make([]misc.FieldError, 0)
+	for _, field := range parsed {
+		if message := validateField(value.FieldByIndex(field.index), field, partial); message != "" {
+			details = append(details, misc.FieldError{Field: field.name, Message: message})
+		}
+	}
+	if len(details) > 0 {
+		return misc.NewValidationError(details...)
+	}
+	return nil
+}
+
+func getJsonName(field reflect.StructField) string {
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func validateField(value reflect.Value, field fieldRules, partial bool) string {
+
+	// a nil pointer, or the zero value of another type, is a field left out
+	isMissing := value.IsZero()
+	if value.Kind() == reflect.Ptr && !isMissing {
+		value = value.Elem()
+	}
+	if isMissing {
+		if !partial && field.required {
+			return "is required"
+		}
+		return ""
+	}
+
+	for _, r := range field.rules {
+		if message := r.check(value, r); message != "" {
+			return message
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+/****************************
+* Rule arguments
+****************************/
+func parseNoArg(r *rule, kind reflect.Kind) error {
+
+	if r.arg != "" {
+		return fmt.Errorf("takes no argument")
+	}
+	return nil
+}
+
+func parseLimit(r *rule, kind reflect.Kind) error {
+
+	limit, err := strconv.ParseFloat(r.arg, 64)
+	if err != nil || math.IsNaN(limit) || math.IsInf(limit, 0) {
+		return fmt.Errorf("expected a number, got %q", r.arg)
+	}
+	if kind != reflect.String && !isNumberKind(kind) {
+		return fmt.Errorf("cannot compare a %s", kind)
+	}
+	r.limit = limit
+	return nil
+}
+
+func parseOneOf(r *rule, kind reflect.Kind) error {
+
+	r.allowed = strings.Fields(r.arg)
+	if len(r.allowed) == 0 {
+		return fmt.Errorf("expected the allowed values")
+	}
+	if kind != reflect.String && kind != reflect.Bool && !isNumberKind(kind) {
+		return fmt.Errorf("cannot compare a %s", kind)
+	}
+	return nil
+}
+
+func parseNumber(r *rule, kind reflect.Kind) error {
+
+	if kind != reflect.String {
+		return fmt.Errorf("applies to strings, not to a %s", kind)
+	}
+	return parseNoArg(r, kind)
+}
+
+/****************************
+* Rules
+****************************/
+func checkRequired(value reflect.Value, r rule) string {
+
+	if value.Kind() == reflect.String && value.String() == "" {
+		return "must not be empty"
+	}
+	return ""
+}
+
+// compare returns how value compares to the limit of r: the length of a
+// string, the value of a number
+func compare(value reflect.Value, r rule) (int, string) {
+
+	var n float64
+	unit := ""
+	switch value.Kind() {
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(value.String()))
+		unit = " characters long"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	default:
+		n = value.Float()
+	}
+
+	switch {
+	case n < r.limit:
+		return -1, unit
+	case n > r.limit:
+		return 1, unit
+	}
+	return 0, unit
+}
+
+func checkMin(value reflect.Value, r rule) string {
+
+	if cmp, unit := compare(value, r); cmp < 0 {
+		return "must be at least " + r.arg + unit
+	}
+	return ""
+}
+
+func checkMax(value reflect.Value, r rule) string {
+
+	if cmp, unit := compare(value, r); cmp > 0 {
+		return "must be at most " + r.arg + unit
+	}
+	return ""
+}
+
+func checkOneOf(value reflect.Value, r rule) string {
+
+	if !contains(r.allowed, fmt.Sprint(value.Interface())) {
+		return "must be one of " + strings.Join(r.allowed, ", ")
+	}
+	return ""
+}
+
+func checkNumber(value reflect.Value, r rule) string {
+
+	n, err := strconv.ParseFloat(value.String(), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n < 0 {
+		return "must be a number which is not negative"
+	}
+	return ""
+}
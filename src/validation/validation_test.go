@@ -0,0 +1,138 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"confusion.com/bwoo/misc"
+)
+
+type testCredentials struct {
+	Username string `json:"username" validate:"required,max=5"`
+}
+
+type testModel struct {
+	testCredentials
+	Name     *string `json:"name" validate:"required,min=2,max=4"`
+	Price    *string `json:"price" validate:"number"`
+	Featured *string `json:"featured" validate:"oneof=true false"`
+	Rating   *int    `json:"rating" validate:"min=1,max=5"`
+	Untagged *string `json:"untagged"`
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestValidate(t *testing.T) {
+
+	valid := testModel{testCredentials{"jane"}, ptr("soup"), ptr("4.5"), ptr("true"), ptr(5), nil}
+
+	for _, test := range []struct {
+		name    string
+		model   testModel
+		partial bool
+		want    map[string]string
+	}{
+		{"valid", valid, false, nil},
+		{"missing", testModel{}, false, map[string]string{"username": "is required", "name": "is required"}},
+		{"partial", testModel{}, true, nil},
+		{"empty", testModel{Name: ptr("")}, true, map[string]string{"name": "must not be empty"}},
+		{"invalid", testModel{testCredentials{"johnny"}, ptr("süße"), ptr("-1"), ptr("yes"), ptr(0), ptr("")}, false,
+			map[string]string{
+				"username": "must be at most 5 characters long",
+				"price":    "must be a number which is not negative",
+				"featured": "must be one of true, false",
+				"rating":   "must be at least 1",
+			}},
+		{"too short", testModel{Name: ptr("é"), Price: ptr("NaN"), Rating: ptr(6)}, true, map[string]string{
+			"name":   "must be at least 2 characters long",
+			"price":  "must be a number which is not negative",
+			"rating": "must be at most 5",
+		}},
+	} {
+
+		got := make(map[string]string)
+		err := Validate(&test.model, test.partial)
+		if err != nil {
+			merr, ok := err.(*misc.Error)
+			if !ok || merr.Code != misc.ErrorCodeValidation {
+				t.Errorf("%s: got %v, want a validation error", test.name, err)
+				continue
+			}
+			for _, detail := range merr.Details {
+				got[detail.Field] = detail.Message
+			}
+		}
+		if len(got) != len(test.want) || (len(got) > 0 && !reflect.DeepEqual(got, test.want)) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+
+	for _, test := range []struct {
+		name  string
+		model interface{}
+		ok    bool
+	}{
+		{"valid", testModel{}, true},
+		{"pointer", &testModel{}, true},
+		{"unknown rule", struct {
+			Name string `validate:"required,email"`
+		}{}, false},
+		{"invalid argument", struct {
+			Name string `validate:"max=ten"`
+		}{}, false},
+		{"missing argument", struct {
+			Name string `validate:"min"`
+		}{}, false},
+		{"unexpected argument", struct {
+			Name string `validate:"required=yes"`
+		}{}, false},
+		{"uncomparable kind", struct {
+			Admin *bool `validate:"max=1"`
+		}{}, false},
+		{"number of a number", struct {
+			Price float64 `validate:"number"`
+		}{}, false},
+		{"empty oneof", struct {
+			Featured string `validate:"oneof="`
+		}{}, false},
+		{"embedded", struct {
+			testCredentials
+			Tags []string `validate:"min=1"`
+		}{}, false},
+		{"not a struct", "soup", false},
+	} {
+
+		if err := Register(test.model); (err == nil) != test.ok {
+			t.Errorf("%s: Register: got %v, want ok %v", test.name, err, test.ok)
+		}
+	}
+}
+
+func TestValidateUnregistered(t *testing.T) {
+
+	// a wrong tag met by a request is an internal error, not a panic
+	model := struct {
+		Name *string `validate:"length=5"`
+	}{Name: ptr("soup")}
+	if err := Validate(&model, false); err == nil || misc.HasErrorCode(err, misc.ErrorCodeValidation) {
+		t.Errorf("Validate: got %v, want an invalid tag error", err)
+	}
+}
+
+func TestMustRegister(t *testing.T) {
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustRegister: got no panic")
+		}
+	}()
+
+	MustRegister(struct {
+		Name string `validate:"max=ten"`
+	}{})
+}